@@ -2,17 +2,22 @@ package main
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
-	"unicode/utf8"
 
 	"github.com/Sirupsen/logrus"
+	runewidth "github.com/mattn/go-runewidth"
 	termbox "github.com/nsf/termbox-go"
 	"github.com/zerok/clocked"
 	"github.com/zerok/clocked/internal/backup"
+	"github.com/zerok/clocked/internal/config"
 	"github.com/zerok/clocked/internal/database"
 	"github.com/zerok/clocked/internal/form"
 	"github.com/zerok/clocked/internal/jira"
+	"github.com/zerok/clocked/internal/submissions"
+	"github.com/zerok/clocked/internal/tags"
+	"github.com/zerok/clocked/internal/templates"
 )
 
 const (
@@ -23,6 +28,13 @@ const (
 	syncMode      = iota
 	editTaskMode  = iota
 	snapshotsMode = iota
+	balanceMode   = iota
+	templateMode  = iota
+	tagMode       = iota
+	timelineMode  = iota
+	calendarMode  = iota
+	statsMode     = iota
+	backupLogMode = iota
 )
 
 type application struct {
@@ -31,28 +43,64 @@ type application struct {
 	log             *logrus.Logger
 	form            *form.Form
 	backup          *backup.Backup
+	backups         *backup.Worker
+	cfg             *config.Config
 	err             error
+	notice          string
 	mode            int
 	area            Area
 	db              database.Database
 	jiraClient      *jira.Client
 	views           map[int]View
 	activeView      View
+	keymap          Keymap
+	theme           *Theme
+	tagColors       tags.Colors
+	tagColorsPath   string
+	submissions     submissions.Log
+	submissionsPath string
+	recurring       templates.History
+	recurringPath   string
+	quit            bool
+	// hints are the clickable entries of the infoline.
+	hints     []keyHint
+	lastClick clickPosition
 }
 
+// keyHint is the position of an infoline entry on the screen.
+type keyHint struct {
+	area   Area
+	action string
+}
+
+type clickPosition struct {
+	x, y int
+	time time.Time
+}
+
+// doubleClickInterval is the maximum time between two clicks on the same
+// cell that are treated as double-click.
+const doubleClickInterval = 400 * time.Millisecond
+
 func selectByCode(code string) ItemMatcherFunc {
 	return func(i ScrollableListItem) bool {
-		task, ok := i.(clocked.Task)
+		item, ok := i.(taskItem)
 		if !ok {
 			return false
 		}
-		return task.Code == code
+		return item.Code == code
 	}
 }
 
 func newApplication() *application {
 	a := &application{
-		termLog: logrus.New(),
+		termLog:     logrus.New(),
+		cfg:         &config.Config{},
+		keymap:      Keymap{},
+		theme:       builtinThemes["dark"].copy(),
+		tagColors:   tags.Colors{},
+		submissions: submissions.Log{},
+		recurring:   templates.History{},
 	}
 	a.views = map[int]View{
 		summaryMode: &summaryView{
@@ -63,6 +111,13 @@ func newApplication() *application {
 		syncMode:      newSyncView(a),
 		editTaskMode:  newEditTaskView(a),
 		snapshotsMode: newSnapshotView(a),
+		balanceMode:   newBalanceView(a),
+		templateMode:  newTemplateView(a),
+		tagMode:       newTagView(a),
+		timelineMode:  newTimelineView(a),
+		calendarMode:  newCalendarView(a),
+		statsMode:     newStatsView(a),
+		backupLogMode: newBackupLogView(a),
 	}
 	return a
 }
@@ -70,6 +125,11 @@ func newApplication() *application {
 func (a *application) start() {
 	a.reset()
 	a.switchMode(selectionMode)
+	// Changes that haven't been backed up yet are included in a final
+	// snapshot.
+	if a.backups != nil {
+		defer a.backups.Close()
+	}
 
 	a.redrawAll()
 	for {
@@ -81,6 +141,12 @@ func (a *application) start() {
 			if !a.handleKey(evt) {
 				return
 			}
+		case termbox.EventMouse:
+			if !a.handleMouse(evt, time.Now()) {
+				return
+			}
+		case termbox.EventInterrupt:
+			// The status of the backup worker has changed.
 		}
 
 		a.redrawAll()
@@ -93,30 +159,238 @@ func (a *application) handleResize() {
 	a.area.Height = h
 }
 
+// globalActions are available in every view.
+func (a *application) globalActions() []Action {
+	return []Action{
+		{Name: "global.quit", Label: "Quit", Keys: []string{"^c"}, Run: func() error {
+			a.quit = true
+			return nil
+		}},
+		{Name: "global.summary", Label: "Daily summary", Keys: []string{"^s"}, Hidden: a.mode != selectionMode, Run: func() error {
+			a.switchMode(summaryMode)
+			return nil
+		}},
+	}
+}
+
+// actions returns the actions of the active view surrounded by the global
+// ones. Quit is always listed first.
+func (a *application) actions() []Action {
+	global := a.globalActions()
+	actions := []Action{global[0]}
+	if p, ok := a.activeView.(ActionProvider); ok {
+		actions = append(actions, p.Actions()...)
+	}
+	return append(actions, global[1:]...)
+}
+
 func (a *application) handleKey(evt termbox.Event) bool {
-	if evt.Key == termbox.KeyCtrlC {
-		return false
+	a.notice = ""
+	if a.activeView == nil {
+		return true
+	}
+	var err error
+	if action, ok := a.lookupAction(evt); ok {
+		err = action.Run()
+	} else {
+		err = a.activeView.HandleKeyEvent(evt)
 	}
-	if evt.Key == termbox.KeyCtrlS {
-		a.switchMode(summaryMode)
+	if err == ErrCloseView {
+		a.switchMode(selectionMode)
+	}
+	return !a.quit
+}
+
+// lookupAction returns the action bound to the given key event. Printable
+// characters are left to a focused text field, even if the keymap binds
+// them to an action.
+func (a *application) lookupAction(evt termbox.Event) (Action, bool) {
+	if ti, ok := a.activeView.(TextInput); ok && evt.Ch != 0 && ti.TextInputFocused() {
+		return Action{}, false
+	}
+	return a.keymap.Lookup(a.actions(), evt)
+}
+
+func (a *application) handleMouse(evt termbox.Event, now time.Time) bool {
+	if a.activeView == nil || evt.Key == termbox.MouseRelease {
 		return true
 	}
-	if a.activeView != nil {
-		if err := a.activeView.HandleKeyEvent(evt); err != nil {
+	a.notice = ""
+	var err error
+	if evt.Key == termbox.MouseLeft {
+		for _, h := range a.hints {
+			if !h.area.Contains(evt.MouseX, evt.MouseY) {
+				continue
+			}
+			for _, action := range a.actions() {
+				if action.Name == h.action && action.enabled() {
+					err = action.Run()
+					break
+				}
+			}
 			if err == ErrCloseView {
 				a.switchMode(selectionMode)
 			}
+			return !a.quit
+		}
+	}
+	double := false
+	if evt.Key == termbox.MouseLeft {
+		last := a.lastClick
+		double = last.x == evt.MouseX && last.y == evt.MouseY && now.Sub(last.time) <= doubleClickInterval
+		a.lastClick = clickPosition{x: evt.MouseX, y: evt.MouseY, time: now}
+		if double {
+			// A third click starts a new double-click.
+			a.lastClick = clickPosition{}
+		}
+	}
+	if h, ok := a.activeView.(MouseHandler); ok {
+		err = h.HandleMouseEvent(evt, double)
+	}
+	if err == ErrCloseView {
+		a.switchMode(selectionMode)
+	}
+	return !a.quit
+}
+
+// generateRecurringTasks creates all recurring tasks that are due right
+// now.
+func (a *application) generateRecurringTasks() {
+	created, changed, err := templates.Generate(a.db, a.cfg, a.recurring, time.Now())
+	if err != nil {
+		a.err = err
+	}
+	if changed && a.recurringPath != "" {
+		if err := a.recurring.Save(a.recurringPath); err != nil {
+			a.err = err
+		}
+	}
+	if len(created) == 0 {
+		return
+	}
+	a.log.Infof("%d recurring tasks created", len(created))
+	a.snapshot()
+}
+
+// undo reverts the last change of the database if it supports that.
+func (a *application) undo() {
+	undoer, ok := a.db.(database.Undoer)
+	if !ok {
+		a.err = fmt.Errorf("undo is not available for this storage format")
+		return
+	}
+	desc, err := undoer.Undo()
+	if err != nil {
+		a.err = err
+		return
+	}
+	a.notice = fmt.Sprintf("Undone: %s", desc)
+	a.snapshot()
+}
+
+// redo applies the last undone change again.
+func (a *application) redo() {
+	undoer, ok := a.db.(database.Undoer)
+	if !ok {
+		a.err = fmt.Errorf("redo is not available for this storage format")
+		return
+	}
+	desc, err := undoer.Redo()
+	if err != nil {
+		a.err = err
+		return
+	}
+	a.notice = fmt.Sprintf("Redone: %s", desc)
+	a.snapshot()
+}
+
+// snapshot requests a backup after the store has been changed. The
+// snapshot is taken in the background, right away or later depending on
+// the configured interval.
+func (a *application) snapshot() {
+	if a.backups == nil {
+		return
+	}
+	a.backups.Request()
+}
+
+// backupStatus describes the state of the backup worker for the status
+// line and returns the style it should be rendered in. Nothing is returned
+// if backups aren't configured.
+func (a *application) backupStatus() (string, string) {
+	if a.backups == nil || a.backup == nil || !a.backup.Available() {
+		return "", ""
+	}
+	status := a.backups.Status()
+	switch {
+	case status.Running:
+		return "Backing up\u2026", styleLabel
+	case status.Last != nil && status.Last.Err != nil:
+		return fmt.Sprintf("Backup failed: %s", status.Last.Err), styleError
+	case status.Pending:
+		return "Backup pending", styleLabel
+	case status.Last != nil && status.Last.PruneErr != nil:
+		return fmt.Sprintf("Backed up %s, pruning failed: %s", status.Last.Time.Format("15:04"), status.Last.PruneErr), styleError
+	case status.Last != nil:
+		return fmt.Sprintf("Backed up %s", status.Last.Time.Format("15:04")), styleLabel
+	}
+	return "", ""
+}
+
+// drawBackupStatus renders the status of the backup worker right-aligned
+// into the given line.
+func (a *application) drawBackupStatus(yOffset int) {
+	text, style := a.backupStatus()
+	if text == "" {
+		return
+	}
+	text = " " + text + " "
+	if max := a.area.Width / 2; runewidth.StringWidth(text) > max {
+		text = runewidth.Truncate(text, max, "\u2026 ")
+	}
+	a.drawStyled(a.area.XMax()-runewidth.StringWidth(text), yOffset, text, style)
+}
+
+// codeValidators make sure that codes match the configured pattern and
+// aren't used by any task other than the one with the given code.
+func (a *application) codeValidators(except string) []form.Validator {
+	cfg := a.cfg
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	pattern := cfg.CodeRegexp()
+	return []form.Validator{
+		form.Pattern(pattern, fmt.Sprintf("The code has to match %s.", pattern)),
+		form.Unique(func(code string) bool {
+			_, found := a.db.TaskByCode(code)
+			return found
+		}, except, "A task with this code already exists."),
+	}
+}
+
+// knownTags returns all tags used by any task.
+func knownTags(db database.Database) []string {
+	tasks, _ := db.AllTasks()
+	seen := make(map[string]struct{})
+	var result []string
+	for _, t := range tasks {
+		for _, tag := range t.Tags {
+			if _, found := seen[tag]; found || tag == "" {
+				continue
+			}
+			seen[tag] = struct{}{}
+			result = append(result, tag)
 		}
-		return true
 	}
-	return true
+	sort.Strings(result)
+	return result
 }
 
 func convertToTask(f *form.Form) clocked.Task {
 	return clocked.Task{
 		Code:  f.Value("code"),
 		Title: f.Value("title"),
-		Tags:  strings.Split(f.Value("tags"), " "),
+		Tags:  f.Tags("tags"),
 	}
 }
 
@@ -126,25 +400,23 @@ func (a *application) fatalError(err error, msg string, args ...interface{}) {
 }
 
 func (a *application) drawHeadline(x, y int, text string) {
-	a.drawText(x, y, text, termbox.ColorBlue|termbox.AttrBold, termbox.ColorDefault)
+	a.drawStyled(x, y, text, styleHeadline)
 }
 
-func (a *application) handleFieldInput(frm *form.Form, evt termbox.Event) {
-	focusedField := frm.FocusedField()
-	if focusedField == "" {
-		return
-	}
-	v := frm.Value(focusedField)
+// setTheme replaces the theme used by the application and all of its
+// lists.
+func (a *application) setTheme(t *Theme) {
+	*a.theme = *t.copy()
+}
 
-	if evt.Key == termbox.KeyBackspace || evt.Key == termbox.KeyBackspace2 {
-		if len(v) == 0 {
-			return
-		}
-		v = v[0 : len(v)-1]
-	} else {
-		v = appendRune(v, evt.Ch)
-	}
-	frm.SetValue(focusedField, v)
+// drawStyled works like drawText but uses the given style of the theme.
+func (a *application) drawStyled(xOffset, yOffset int, text string, style string) int {
+	s := a.theme.Style(style)
+	return a.drawText(xOffset, yOffset, text, s.Fg, s.Bg)
+}
+
+func (a *application) handleFieldInput(frm *form.Form, evt termbox.Event) {
+	frm.HandleEvent(evt)
 }
 
 func (a *application) selectTask(task clocked.Task) {
@@ -157,28 +429,29 @@ func (a *application) selectTask(task clocked.Task) {
 }
 
 func (a *application) reset() {
-	termbox.Clear(termbox.ColorWhite, termbox.ColorDefault)
+	s := a.theme.Style(styleText)
+	termbox.Clear(s.Fg, s.Bg)
 	termbox.HideCursor()
 }
 
 func (a *application) drawLabel(xOffset, yOffset int, text string, focused bool) int {
-	fg := termbox.ColorWhite
-	bg := termbox.ColorDefault
+	s := a.theme.Style(styleLabel)
 	if focused {
-		fg |= termbox.AttrBold
+		s.Fg |= termbox.AttrBold
 	}
-	return a.drawText(xOffset, yOffset, text, fg, bg)
+	return a.drawText(xOffset, yOffset, text, s.Fg, s.Bg)
 }
 
 func (a *application) drawText(xOffset, yOffset int, text string, fg, bg termbox.Attribute) int {
 	var drawnChars int
-	for idx, c := range text {
+	for _, c := range text {
+		x := xOffset + drawnChars
 		drawnChars++
-		if xOffset+idx == a.area.XMax() {
-			termbox.SetCell(xOffset+idx, yOffset, '\u2026', fg, bg)
+		if x == a.area.XMax() {
+			termbox.SetCell(x, yOffset, '\u2026', fg, bg)
 			break
 		} else {
-			termbox.SetCell(xOffset+idx, yOffset, c, fg, bg)
+			termbox.SetCell(x, yOffset, c, fg, bg)
 		}
 	}
 	return xOffset + drawnChars
@@ -221,6 +494,7 @@ func (a *application) drawKeyMapping(area Area, mapping []KeyMap) int {
 	}
 
 	a.drawLine(area.YMax() - len(lines))
+	a.drawBackupStatus(area.YMax() - len(lines))
 	for idx, l := range lines {
 		xOffset := area.XMin()
 		yOffset := area.YMax() - len(lines) + idx + 1
@@ -229,8 +503,13 @@ func (a *application) drawKeyMapping(area Area, mapping []KeyMap) int {
 				xOffset += margin
 			}
 			padding := max + 2 - len(m.Key) - len(m.Label)
-			xOffset = a.drawText(xOffset, yOffset, fmt.Sprintf("[%s]%s", m.Key, strings.Repeat(" ", padding)), termbox.AttrBold|termbox.ColorWhite, termbox.ColorBlack)
-			xOffset = a.drawText(xOffset, yOffset, m.Label, termbox.ColorWhite, termbox.ColorBlack)
+			start := xOffset
+			xOffset = a.drawStyled(xOffset, yOffset, fmt.Sprintf("[%s]%s", m.Key, strings.Repeat(" ", padding)), styleKeyHint)
+			xOffset = a.drawStyled(xOffset, yOffset, m.Label, styleKeyLabel)
+			a.hints = append(a.hints, keyHint{
+				area:   Area{X: start, Y: yOffset, Width: xOffset - start, Height: 1},
+				action: m.Action,
+			})
 		}
 	}
 
@@ -239,6 +518,7 @@ func (a *application) drawKeyMapping(area Area, mapping []KeyMap) int {
 
 func (a *application) redrawAll() {
 	a.reset()
+	a.hints = nil
 	yOffset := a.redrawError()
 
 	if a.activeView != nil {
@@ -246,9 +526,7 @@ func (a *application) redrawAll() {
 		contentArea.Y = yOffset
 		contentArea.Height -= yOffset
 
-		if km, ok := a.activeView.(Keymapper); ok {
-			contentArea.Height -= a.drawKeyMapping(contentArea, km.KeyMapping())
-		}
+		contentArea.Height -= a.drawKeyMapping(contentArea, a.keymap.Mapping(a.actions()))
 
 		a.activeView.Render(contentArea)
 	}
@@ -262,11 +540,23 @@ func formatTime(t *time.Time) string {
 	return t.Format("15:04:05")
 }
 
+// formatBalance works like database.FormatHours but always includes the sign.
+func formatBalance(d time.Duration) string {
+	if d < 0 {
+		return "-" + database.FormatHours(-d)
+	}
+	return "+" + database.FormatHours(d)
+}
+
 func (a *application) redrawError() int {
 	if a.err != nil {
 		a.drawError(a.area.XMin(), a.area.YMin(), a.err.Error())
 		return 1
 	}
+	if a.notice != "" {
+		a.drawStyled(a.area.XMin(), a.area.YMin(), a.notice, styleNotice)
+		return 1
+	}
 	return 0
 }
 
@@ -286,9 +576,9 @@ func (a *application) redrawForm(area Area, frm *form.Form) {
 	yOffset = area.YMin() + 1
 	for _, fld := range frm.Fields() {
 		isFocused := frm.IsFocused(fld.Code)
-		a.drawFieldValue(inputStartOffset, area.Width-2, yOffset, fld.Value, isFocused)
+		cursor := a.drawEditor(inputStartOffset, area.Width-2, yOffset, frm.Editor(fld.Code), isFocused)
 		if isFocused {
-			termbox.SetCursor(inputStartOffset+len(fld.Value)+1, yOffset)
+			a.drawFieldHint(cursor, area.Width-2, yOffset, frm, fld)
 		}
 		a.drawError(inputStartOffset, yOffset+1, fld.Error)
 		yOffset += 3
@@ -296,37 +586,59 @@ func (a *application) redrawForm(area Area, frm *form.Form) {
 }
 
 func (a *application) drawError(xOffset, yOffset int, msg string) int {
+	s := a.theme.Style(styleError)
 	for idx, c := range msg {
-		termbox.SetCell(xOffset+idx, yOffset, c, termbox.ColorRed, termbox.ColorDefault)
+		termbox.SetCell(xOffset+idx, yOffset, c, s.Fg, s.Bg)
 	}
 	return xOffset + len(msg)
 }
 
-func (a *application) drawFieldValue(xOffset int, xOffsetEnd int, yOffset int, value string, focused bool) int {
-	bg := termbox.ColorBlack
-	fg := termbox.ColorWhite
-	valueIndex := -1
-	for xOffset < xOffsetEnd {
-		c := ' '
-		if valueIndex >= 0 && valueIndex < len(value) {
-			c = rune(value[valueIndex])
+// drawFieldHint shows the suggestion of a focused tags field behind the
+// cursor and indicates that choice fields are changed with the arrow keys.
+func (a *application) drawFieldHint(cursor int, xOffsetEnd int, yOffset int, frm *form.Form, fld form.Field) {
+	var hint string
+	switch fld.Type {
+	case form.TagsField:
+		hint = frm.Suggestion()
+	case form.ChoiceField:
+		termbox.HideCursor()
+		cursor = xOffsetEnd - 4
+		hint = "\u2190/\u2192"
+	}
+	s := a.theme.Style(styleSuggestion)
+	for _, c := range hint {
+		if cursor >= xOffsetEnd {
+			break
 		}
-		termbox.SetCell(xOffset, yOffset, c, fg, bg)
-		xOffset++
-		valueIndex++
+		termbox.SetCell(cursor, yOffset, c, s.Fg, s.Bg)
+		cursor += runewidth.RuneWidth(c)
 	}
-	return xOffsetEnd
 }
 
-func appendRune(s string, r rune) string {
-	b := make([]byte, 3)
-	l := utf8.EncodeRune(b, r)
-	return s + string(b[0:l])
+// drawEditor renders the visible part of the given editor between xOffset
+// and xOffsetEnd and places the cursor inside of it if it is focused. The
+// column of the cursor is returned.
+func (a *application) drawEditor(xOffset int, xOffsetEnd int, yOffset int, ed *form.LineEditor, focused bool) int {
+	s := a.theme.Style(styleInput)
+	for x := xOffset; x < xOffsetEnd; x++ {
+		termbox.SetCell(x, yOffset, ' ', s.Fg, s.Bg)
+	}
+	text, cursor := ed.View(xOffsetEnd - xOffset - 1)
+	x := xOffset + 1
+	for _, c := range text {
+		termbox.SetCell(x, yOffset, c, s.Fg, s.Bg)
+		x += runewidth.RuneWidth(c)
+	}
+	if focused {
+		termbox.SetCursor(xOffset+1+cursor, yOffset)
+	}
+	return xOffset + 1 + cursor
 }
 
 func (a *application) drawLine(yOffset int) {
+	s := a.theme.Style(styleSeparator)
 	for i := a.area.XMin(); i <= a.area.XMax(); i++ {
-		termbox.SetCell(i, yOffset, '\u2500', termbox.ColorBlue, termbox.ColorDefault)
+		termbox.SetCell(i, yOffset, '\u2500', s.Fg, s.Bg)
 	}
 }
 
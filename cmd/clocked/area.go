@@ -22,3 +22,8 @@ func (a *Area) XMax() int {
 func (a *Area) YMax() int {
 	return a.Y + a.Height - 1
 }
+
+// Contains checks if the given cell lies inside of the area.
+func (a *Area) Contains(x, y int) bool {
+	return x >= a.XMin() && x <= a.XMax() && y >= a.YMin() && y <= a.YMax()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+)
+
+func runBackupCommand(env *commandEnv, args []string) error {
+	action := "snapshot"
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+	if env.backup == nil || !env.backup.Available() {
+		return fmt.Errorf("backups are not available. Most likely restic is not installed")
+	}
+	switch action {
+	case "snapshot":
+		if len(args) != 0 {
+			return fmt.Errorf("usage: clocked backup snapshot")
+		}
+		return env.snapshot()
+	case "list":
+		snapshots, err := env.backup.Snapshots()
+		if err != nil {
+			return err
+		}
+		for _, s := range snapshots {
+			id := s.ID
+			if len(id) > 8 {
+				id = id[:8]
+			}
+			fmt.Fprintf(env.out, "%s  %s  %s\n", id, s.Label(), s.Hostname)
+		}
+		return nil
+	case "forget":
+		var prune bool
+		flags := pflag.NewFlagSet("backup forget", pflag.ContinueOnError)
+		flags.BoolVar(&prune, "prune", false, "Also remove the data of forgotten snapshots from the repository")
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		return env.backup.Forget(prune)
+	case "restore":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: clocked backup restore <id>")
+		}
+		if err := env.backup.Restore(args[0]); err != nil {
+			return err
+		}
+		fmt.Fprintf(env.out, "Restored %s into %s\n", args[0], env.storageFolder)
+		return nil
+	default:
+		return fmt.Errorf("unknown backup action %s. Use snapshot, list, forget or restore", action)
+	}
+}
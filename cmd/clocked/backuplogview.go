@@ -0,0 +1,118 @@
+package main
+
+import (
+	"time"
+
+	"github.com/nsf/termbox-go"
+	"github.com/zerok/clocked/internal/backup"
+)
+
+// backupResultItem is a single attempt to take a snapshot in the backup
+// log.
+type backupResultItem struct {
+	backup.Result
+	style Style
+}
+
+func (i backupResultItem) Label() string {
+	label := i.Time.Format("2 Jan 15:04:05") + "  "
+	if i.Err != nil {
+		return label + i.Err.Error()
+	}
+	if i.PruneErr != nil {
+		return label + "Snapshot taken, pruning failed: " + i.PruneErr.Error()
+	}
+	return label + "Snapshot taken"
+}
+
+func (i backupResultItem) Columns() []string {
+	return []string{i.Duration.Round(10 * time.Millisecond).String()}
+}
+
+func (i backupResultItem) Badges() []Badge {
+	if i.Err != nil {
+		return []Badge{{Text: "failed", Style: i.style}}
+	}
+	if i.PruneErr != nil {
+		return []Badge{{Text: "prune failed", Style: i.style}}
+	}
+	return nil
+}
+
+// backupLogView lists the snapshots the backup worker has tried to take
+// since the application was started, the latest first.
+type backupLogView struct {
+	app  *application
+	list *ScrollableList
+}
+
+func newBackupLogView(app *application) *backupLogView {
+	return &backupLogView{
+		app:  app,
+		list: newThemedList(app),
+	}
+}
+
+func (v *backupLogView) BeforeFocus() error {
+	v.update()
+	return nil
+}
+
+func (v *backupLogView) update() {
+	var results []backup.Result
+	if v.app.backups != nil {
+		results = v.app.backups.Results()
+	}
+	items := make([]ScrollableListItem, 0, len(results))
+	for _, r := range results {
+		items = append(items, backupResultItem{Result: r, style: v.app.theme.Style(styleError)})
+	}
+	v.list.UpdateItems(items)
+}
+
+func (v *backupLogView) Render(area Area) error {
+	// New results may have arrived in the background.
+	if v.app.backups != nil && len(v.app.backups.Results()) != len(v.list.items) {
+		v.update()
+	}
+	if len(v.list.items) == 0 {
+		v.app.drawStyled(area.XMin(), area.YMin(), "No snapshots have been taken yet.", styleLabel)
+		return nil
+	}
+	v.list.UpdateArea(area)
+	v.list.Render()
+	return nil
+}
+
+func (v *backupLogView) Actions() []Action {
+	a := v.app
+	return []Action{
+		{Name: "backuplog.close", Label: "Back", Keys: []string{"q", "ESC"}, Run: func() error {
+			a.switchMode(snapshotsMode)
+			return nil
+		}},
+		{Name: "backuplog.retry", Label: "Back up now", Keys: []string{"r"}, Enabled: func() bool {
+			return a.backups != nil && a.backups.Status().Pending
+		}, Run: func() error {
+			a.backups.Flush()
+			return nil
+		}},
+		{Name: "backuplog.next", Label: "Next", Keys: []string{"j", "DOWN"}, Run: func() error {
+			v.list.Next()
+			return nil
+		}},
+		{Name: "backuplog.previous", Label: "Previous", Keys: []string{"k", "UP"}, Run: func() error {
+			v.list.Previous()
+			return nil
+		}},
+	}
+}
+
+func (v *backupLogView) HandleMouseEvent(evt termbox.Event, double bool) error {
+	v.list.HandleMouseEvent(evt)
+	return nil
+}
+
+func (v *backupLogView) HandleKeyEvent(evt termbox.Event) error {
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nsf/termbox-go"
+	"github.com/zerok/clocked/internal/balance"
+	"github.com/zerok/clocked/internal/database"
+)
+
+// balanceView accumulates the overtime of the week, month and year the
+// selected date is in.
+type balanceView struct {
+	app  *application
+	date time.Time
+}
+
+func newBalanceView(app *application) *balanceView {
+	return &balanceView{
+		app:  app,
+		date: time.Now(),
+	}
+}
+
+func (v *balanceView) SetDate(date time.Time) {
+	v.date = date
+}
+
+func (v *balanceView) Actions() []Action {
+	return []Action{
+		{Name: "balance.close", Label: "Summary", Keys: []string{"q", "ESC"}, Run: func() error {
+			v.app.switchMode(summaryMode)
+			if view, ok := v.app.views[summaryMode].(*summaryView); ok {
+				date := v.date
+				view.date = &date
+			}
+			return nil
+		}},
+	}
+}
+
+func (v *balanceView) Render(area Area) error {
+	now := time.Now()
+	if v.date.Before(now) && !database.SameDay(v.date, now) {
+		// For past dates the whole period is relevant.
+		now = database.StartOfDay(v.date).AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	periods := []struct {
+		label string
+		from  time.Time
+		to    time.Time
+	}{
+		{"Day", database.StartOfDay(v.date), database.StartOfDay(v.date).AddDate(0, 0, 1)},
+		{"Week", database.StartOfWeek(v.date), database.StartOfWeek(v.date).AddDate(0, 0, 7)},
+		{"Month", database.StartOfMonth(v.date), database.StartOfMonth(v.date).AddDate(0, 1, 0)},
+		{"Year", database.StartOfYear(v.date), database.StartOfYear(v.date).AddDate(1, 0, 0)},
+	}
+
+	v.app.drawHeadline(area.XMin(), area.YMin(), fmt.Sprintf("Balance until %s", now.Format("Mon, 2 Jan 2006")))
+	v.app.drawStyled(area.XMin(), area.YMin()+2, fmt.Sprintf("%-8s %10s %10s %10s", "Period", "Worked", "Target", "Balance"), styleEmphasis)
+	for idx, p := range periods {
+		b := balance.ForPeriod(v.app.db, v.app.cfg, p.from, p.to, now)
+		style := styleSuccess
+		if b.Overtime() < 0 {
+			style = styleWarning
+		}
+		yOffset := area.YMin() + 3 + idx
+		xOffset := v.app.drawStyled(area.XMin(), yOffset, fmt.Sprintf("%-8s %10s %10s ", p.label, database.FormatHours(b.Actual), database.FormatHours(b.Target)), styleText)
+		v.app.drawStyled(xOffset, yOffset, fmt.Sprintf("%10s", formatBalance(b.Overtime())), style)
+	}
+	return nil
+}
+
+func (v *balanceView) HandleKeyEvent(evt termbox.Event) error {
+	return nil
+}
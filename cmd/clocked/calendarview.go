@@ -0,0 +1,324 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nsf/termbox-go"
+	"github.com/zerok/clocked/internal/balance"
+	"github.com/zerok/clocked/internal/config"
+	"github.com/zerok/clocked/internal/database"
+	"github.com/zerok/clocked/internal/submissions"
+)
+
+// calendarCellWidth is the maximum width of a day in the calendar.
+const calendarCellWidth = 12
+
+// calendarDay is a single day shown in the calendar.
+type calendarDay struct {
+	Date time.Time
+	// Total includes the running booking up until now.
+	Total time.Duration
+	// Finished only includes the finished bookings like submissions do.
+	Finished time.Duration
+	Target   time.Duration
+	DayOff   bool
+	// Submission is the latest submission of the day to JIRA, if any.
+	Submission *submissions.Submission
+}
+
+// style returns the style of the day depending on whether its target has
+// been reached. Days that are not over yet are only highlighted once their
+// target is reached.
+func (d calendarDay) style(now time.Time) string {
+	switch {
+	case d.DayOff || (d.Target == 0 && d.Total == 0):
+		return styleSeparator
+	case d.Total >= d.Target:
+		return styleSuccess
+	case !d.Date.Before(database.StartOfDay(now)):
+		return styleText
+	case d.Total == 0:
+		return styleError
+	default:
+		return styleWarning
+	}
+}
+
+// syncMarker returns a character indicating the JIRA submission status of
+// the day and the style it should be rendered in.
+func (d calendarDay) syncMarker() (string, string) {
+	switch {
+	case d.Submission == nil:
+		return " ", styleText
+	case d.Submission.Status != submissions.StatusOK:
+		return "✗", styleError
+	case d.Submission.Outdated(d.Finished):
+		return "~", styleWarning
+	default:
+		return "✓", styleSuccess
+	}
+}
+
+// buildCalendar collects the totals, targets and submissions of all days of
+// the month month is in.
+func buildCalendar(db database.Database, cfg *config.Config, log submissions.Log, month time.Time, now time.Time) []calendarDay {
+	first := database.StartOfMonth(month)
+	var days []calendarDay
+	for day := first; day.Month() == first.Month(); day = day.AddDate(0, 0, 1) {
+		summary := db.GenerateDailySummary(day)
+		d := calendarDay{
+			Date:     day,
+			Total:    summary.Total,
+			Finished: summary.Total,
+			Target:   cfg.TargetFor(day),
+			DayOff:   cfg.IsDayOff(day),
+		}
+		if !day.After(now) {
+			d.Total = balance.Calculate(cfg, summary, day, day.AddDate(0, 0, 1), now).Actual
+		}
+		if s, ok := log.For(day); ok {
+			d.Submission = &s
+		}
+		days = append(days, d)
+	}
+	return days
+}
+
+// addMonths moves day by the given number of months. Days that don't exist
+// in the resulting month are moved to its last day.
+func addMonths(day time.Time, months int) time.Time {
+	first := database.StartOfMonth(day).AddDate(0, months, 0)
+	last := first.AddDate(0, 1, -1)
+	if day.Day() > last.Day() {
+		return last
+	}
+	return first.AddDate(0, 0, day.Day()-1)
+}
+
+// calendarView shows a month with the time booked on every day. Days are
+// colored by whether their target has been reached and marked with their
+// JIRA submission status.
+type calendarView struct {
+	app      *application
+	selected time.Time
+	// origin is the day the summary showed when the calendar was opened.
+	origin time.Time
+	days   []calendarDay
+
+	// Set while rendering so that mouse events can be mapped to days.
+	gridX     int
+	gridY     int
+	cellWidth int
+}
+
+func newCalendarView(app *application) *calendarView {
+	now := database.StartOfDay(time.Now())
+	return &calendarView{
+		app:      app,
+		selected: now,
+		origin:   now,
+	}
+}
+
+// SetDate selects the given day and shows its month.
+func (v *calendarView) SetDate(date time.Time) {
+	v.origin = date
+	v.selectDay(date)
+}
+
+func (v *calendarView) BeforeFocus() error {
+	v.update()
+	return nil
+}
+
+func (v *calendarView) selectDay(date time.Time) {
+	date = database.StartOfDay(date)
+	changed := date.Year() != v.selected.Year() || date.Month() != v.selected.Month()
+	v.selected = date
+	if changed || len(v.days) == 0 {
+		v.update()
+	}
+}
+
+func (v *calendarView) update() {
+	if v.app.db == nil {
+		return
+	}
+	v.days = buildCalendar(v.app.db, v.app.cfg, v.app.submissions, v.selected, time.Now())
+}
+
+func (v *calendarView) Actions() []Action {
+	a := v.app
+	return []Action{
+		{Name: "calendar.open", Label: "Summary", Keys: []string{"ENTER"}, Run: v.openSummary},
+		{Name: "calendar.next", Label: "Next day", Keys: []string{"l", "RIGHT"}, Run: func() error {
+			v.selectDay(v.selected.AddDate(0, 0, 1))
+			return nil
+		}},
+		{Name: "calendar.previous", Label: "Previous day", Keys: []string{"h", "LEFT"}, Run: func() error {
+			v.selectDay(v.selected.AddDate(0, 0, -1))
+			return nil
+		}},
+		{Name: "calendar.down", Label: "Next week", Keys: []string{"j", "DOWN"}, Run: func() error {
+			v.selectDay(v.selected.AddDate(0, 0, 7))
+			return nil
+		}},
+		{Name: "calendar.up", Label: "Previous week", Keys: []string{"k", "UP"}, Run: func() error {
+			v.selectDay(v.selected.AddDate(0, 0, -7))
+			return nil
+		}},
+		{Name: "calendar.later", Label: "Next month", Keys: []string{"]", "PGDN"}, Run: func() error {
+			v.selectDay(addMonths(v.selected, 1))
+			return nil
+		}},
+		{Name: "calendar.earlier", Label: "Previous month", Keys: []string{"[", "PGUP"}, Run: func() error {
+			v.selectDay(addMonths(v.selected, -1))
+			return nil
+		}},
+		{Name: "calendar.today", Label: "Today", Keys: []string{"."}, Run: func() error {
+			v.selectDay(time.Now())
+			return nil
+		}},
+		{Name: "calendar.close", Label: "Back", Keys: []string{"q", "ESC"}, Run: func() error {
+			a.switchMode(summaryMode)
+			if view, ok := a.views[summaryMode].(*summaryView); ok {
+				date := v.origin
+				view.date = &date
+			}
+			return nil
+		}},
+	}
+}
+
+// openSummary shows the summary of the selected day.
+func (v *calendarView) openSummary() error {
+	a := v.app
+	a.switchMode(summaryMode)
+	if view, ok := a.views[summaryMode].(*summaryView); ok {
+		date := v.selected
+		view.date = &date
+	}
+	return nil
+}
+
+// position returns the column and row of the given day within the grid.
+func (v *calendarView) position(day time.Time) (int, int) {
+	first := database.StartOfMonth(day)
+	offset := (int(first.Weekday()) + 6) % 7
+	idx := offset + day.Day() - 1
+	return idx % 7, idx / 7
+}
+
+func (v *calendarView) Render(area Area) error {
+	a := v.app
+	now := time.Now()
+	a.drawHeadline(area.XMin(), area.YMin(), fmt.Sprintf("Calendar for %s", v.selected.Format("January 2006")))
+	v.cellWidth = area.Width / 8
+	if v.cellWidth > calendarCellWidth {
+		v.cellWidth = calendarCellWidth
+	}
+	v.gridX = area.XMin()
+	v.gridY = area.YMin() + 2
+	for idx, name := range []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun", "Week"} {
+		a.drawStyled(v.gridX+idx*v.cellWidth, v.gridY-1, name, styleLabel)
+	}
+
+	var month, target time.Duration
+	weeks := make(map[int]time.Duration)
+	for _, d := range v.days {
+		col, row := v.position(d.Date)
+		x := v.gridX + col*v.cellWidth
+		y := v.gridY + row
+		weeks[row] += d.Total
+		month += d.Total
+		target += d.Target
+
+		style := d.style(now)
+		if d.Date.Equal(v.selected) {
+			style = styleHighlight
+		}
+		x = a.drawStyled(x, y, fmt.Sprintf("%2d ", d.Date.Day()), style)
+		x = a.drawStyled(x, y, fmt.Sprintf("%-6s", formatOptionalHours(d.Total)), d.style(now))
+		marker, markerStyle := d.syncMarker()
+		a.drawStyled(x, y, marker, markerStyle)
+	}
+	for row, total := range weeks {
+		a.drawStyled(v.gridX+7*v.cellWidth, v.gridY+row, formatOptionalHours(total), styleEmphasis)
+	}
+
+	y := v.gridY + 7
+	x := a.drawStyled(area.XMin(), y, "Month: ", styleEmphasis)
+	x = a.drawStyled(x, y, database.FormatHours(month), styleText)
+	if target > 0 {
+		x = a.drawStyled(x+2, y, "Target: ", styleEmphasis)
+		a.drawStyled(x, y, database.FormatHours(target), styleText)
+	}
+	y++
+	if d, ok := v.selectedDay(); ok {
+		x := a.drawStyled(area.XMin(), y, d.Date.Format("Mon, 2 Jan 2006")+": ", styleEmphasis)
+		text := database.FormatHours(d.Total)
+		if d.Target > 0 {
+			text += " of " + database.FormatHours(d.Target)
+		}
+		switch {
+		case d.Submission == nil:
+		case d.Submission.Status != submissions.StatusOK:
+			text += fmt.Sprintf(", submission failed at %s", d.Submission.Submitted.Format("2 Jan 15:04"))
+		case d.Submission.Outdated(d.Finished):
+			text += fmt.Sprintf(", changed since submission at %s", d.Submission.Submitted.Format("2 Jan 15:04"))
+		default:
+			text += fmt.Sprintf(", submitted at %s", d.Submission.Submitted.Format("2 Jan 15:04"))
+		}
+		a.drawStyled(x, y, text, styleText)
+	}
+	return nil
+}
+
+func (v *calendarView) selectedDay() (calendarDay, bool) {
+	for _, d := range v.days {
+		if d.Date.Equal(v.selected) {
+			return d, true
+		}
+	}
+	return calendarDay{}, false
+}
+
+// dayAt returns the day shown at the given position.
+func (v *calendarView) dayAt(x, y int) (time.Time, bool) {
+	if v.cellWidth == 0 || x < v.gridX || y < v.gridY {
+		return time.Time{}, false
+	}
+	col, row := (x-v.gridX)/v.cellWidth, y-v.gridY
+	for _, d := range v.days {
+		if c, r := v.position(d.Date); c == col && r == row {
+			return d.Date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (v *calendarView) HandleKeyEvent(evt termbox.Event) error {
+	return nil
+}
+
+// HandleMouseEvent selects clicked days and opens their summary on
+// double-click. The mouse wheel moves between months.
+func (v *calendarView) HandleMouseEvent(evt termbox.Event, double bool) error {
+	switch evt.Key {
+	case termbox.MouseWheelUp:
+		v.selectDay(addMonths(v.selected, -1))
+	case termbox.MouseWheelDown:
+		v.selectDay(addMonths(v.selected, 1))
+	case termbox.MouseLeft:
+		day, ok := v.dayAt(evt.MouseX, evt.MouseY)
+		if !ok {
+			return nil
+		}
+		v.selectDay(day)
+		if double {
+			return v.openSummary()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/config"
+	"github.com/zerok/clocked/internal/database"
+	"github.com/zerok/clocked/internal/submissions"
+)
+
+func TestBuildCalendar(t *testing.T) {
+	day := func(d, h int) time.Time {
+		return time.Date(2018, 3, d, h, 0, 0, 0, time.Local)
+	}
+	db := database.NewInMemory()
+	db.AddTask(clocked.Task{Code: "a", Bookings: []clocked.Booking{
+		booking(day(1, 9), day(1, 17)),
+		booking(day(2, 9), day(2, 12)),
+		booking(day(6, 7), day(6, 8)),
+		booking(day(6, 9), time.Time{}),
+	}})
+	cfg := &config.Config{
+		Targets:  config.Targets{Monday: 8 * time.Hour, Tuesday: 8 * time.Hour, Thursday: 8 * time.Hour, Friday: 6 * time.Hour},
+		Holidays: []string{"2018-03-05"},
+	}
+	log := submissions.Log{}
+	log.Record(day(1, 0), submissions.Submission{Status: submissions.StatusOK, Total: 8 * time.Hour})
+	log.Record(day(2, 0), submissions.Submission{Status: submissions.StatusOK, Total: 2 * time.Hour})
+	log.Record(day(6, 0), submissions.Submission{Status: submissions.StatusOK, Total: time.Hour})
+	log.Record(day(7, 0), submissions.Submission{Status: submissions.StatusFailed})
+
+	now := day(6, 11)
+	days := buildCalendar(db, cfg, log, day(15, 0), now)
+	require.Len(t, days, 31)
+
+	require.Equal(t, 8*time.Hour, days[0].Total)
+	require.Equal(t, styleSuccess, days[0].style(now))
+	marker, _ := days[0].syncMarker()
+	require.Equal(t, "✓", marker)
+
+	require.Equal(t, styleWarning, days[1].style(now), "the target of Friday was missed")
+	marker, _ = days[1].syncMarker()
+	require.Equal(t, "~", marker, "the day has changed since it was submitted")
+
+	require.Equal(t, styleSeparator, days[2].style(now), "Saturday has no target")
+	require.Equal(t, styleSeparator, days[4].style(now), "holiday")
+	require.True(t, days[4].DayOff)
+
+	require.Equal(t, 3*time.Hour, days[5].Total, "the running booking counts until now")
+	require.Equal(t, styleText, days[5].style(now), "today isn't over yet")
+	marker, _ = days[5].syncMarker()
+	require.Equal(t, "✓", marker, "the running booking hasn't been submitted yet")
+	marker, _ = days[6].syncMarker()
+	require.Equal(t, "✗", marker)
+
+	require.Equal(t, styleError, days[7].style(day(9, 0)), "nothing was booked")
+	marker, _ = days[7].syncMarker()
+	require.Equal(t, " ", marker)
+}
+
+func TestCalendarNavigation(t *testing.T) {
+	require.Equal(t, time.Date(2018, 2, 28, 0, 0, 0, 0, time.Local), addMonths(time.Date(2018, 1, 31, 0, 0, 0, 0, time.Local), 1))
+	require.Equal(t, time.Date(2017, 12, 15, 0, 0, 0, 0, time.Local), addMonths(time.Date(2018, 1, 15, 0, 0, 0, 0, time.Local), -1))
+
+	app := newApplication()
+	app.db = database.NewInMemory()
+	app.switchMode(calendarMode)
+	v := app.activeView.(*calendarView)
+	v.SetDate(time.Date(2018, 3, 14, 15, 0, 0, 0, time.Local))
+	require.Len(t, v.days, 31)
+	col, row := v.position(v.selected)
+	require.Equal(t, 2, col, "Wednesday")
+	require.Equal(t, 2, row)
+
+	v.selectDay(v.selected.AddDate(0, 0, 21))
+	require.Len(t, v.days, 30, "April")
+	require.NoError(t, v.openSummary())
+	summary := app.activeView.(*summaryView)
+	require.Equal(t, time.Date(2018, 4, 4, 0, 0, 0, 0, time.Local), *summary.date)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/zerok/clocked/internal/backup"
+	"github.com/zerok/clocked/internal/config"
+	"github.com/zerok/clocked/internal/database"
+)
+
+// commandEnv contains everything a sub-command might need. It is set up by
+// main just like for the interactive application.
+type commandEnv struct {
+	storageFolder string
+	cfg           *config.Config
+	db            database.Database
+	backup        *backup.Backup
+	log           *logrus.Logger
+	in            io.Reader
+	out           io.Writer
+}
+
+// load opens the database and the backup unless that has already happened.
+func (e *commandEnv) load() error {
+	if e.db != nil {
+		return nil
+	}
+	db, bk, err := openStore(e.storageFolder, e.cfg, e.log)
+	if err != nil {
+		return err
+	}
+	e.db = db
+	e.backup = bk
+	if needsInitialSnapshot(db, bk) {
+		if err := bk.CreateSnapshot(); err != nil {
+			return fmt.Errorf("failed to create initial snapshot: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// snapshot creates a backup after a command has changed the store and
+// removes old snapshots if that is due.
+func (e *commandEnv) snapshot() error {
+	return newSnapshotScheduler(e.backup, e.cfg).Request()
+}
+
+type command struct {
+	usage string
+	run   func(env *commandEnv, args []string) error
+	// lazy commands call env.load themselves if they need the database.
+	// This keeps commands that are run very often cheap.
+	lazy bool
+}
+
+var commands = map[string]command{
+	"backup": {
+		usage: "backup [snapshot|list|forget [--prune]|restore <id>]: Create a snapshot of the store, list snapshots, remove old ones according to the retention policy or restore one",
+		run:   runBackupCommand,
+	},
+	"git-hook": {
+		usage: "git-hook install [--repo path]: Install git hooks that offer to clock into the task of the checked out branch",
+		run:   runGitHookCommand,
+		lazy:  true,
+	},
+	"git-switch": {
+		usage: "git-switch [--repo path] [--commit] [--yes]: Clock into the task named by the current branch or last commit",
+		run:   runGitSwitchCommand,
+	},
+	"invoice": {
+		usage: "invoice [--from date] [--to date] [--client name] [--format csv|json|markdown|html] [--output file]: Create invoices for last month (or the given range)",
+		run:   runInvoiceCommand,
+	},
+	"keys": {
+		usage: "keys: List all actions of the interactive interface and their key bindings",
+		run:   runKeysCommand,
+		lazy:  true,
+	},
+	"remind": {
+		usage: "remind [--interval duration] [--once]: Send desktop notifications as configured in the reminders section",
+		run:   runRemindCommand,
+	},
+	"serve": {
+		usage: "serve [--listen address] [--token token]: Serve the HTTP/JSON API",
+		run:   runServeCommand,
+	},
+	"tags": {
+		usage: "tags [--from date] [--to date]: List all tags with the number of their tasks and the time booked this week (or in the given range)",
+		run:   runTagsCommand,
+	},
+	"tag-rename": {
+		usage: "tag-rename <tag>... <new-tag>: Rename a tag or merge several tags into one across all tasks",
+		run:   runTagRenameCommand,
+	},
+	"team-push": {
+		usage: "team-push [--from date] [--to date]: Push the summary of this week (or the given range) to the team server",
+		run:   runTeamPushCommand,
+	},
+	"team-report": {
+		usage: "team-report [--group task|tag|person] [--from date] [--to date]: Show the aggregated report of the team",
+		run:   runTeamReportCommand,
+	},
+	"team-server": {
+		usage: "team-server --users users.yml [--listen address] [--data path]: Run the team aggregation server",
+		run:   runTeamServerCommand,
+	},
+	"status": {
+		usage: "status [--output plain|waybar|i3blocks|tmux] [--format template] [--toggle]: Print the active task for status bars",
+		run:   runStatusCommand,
+		lazy:  true,
+	},
+	"sync": {
+		usage: "sync <folder>: Merge this store with the one in the given local or mounted folder (remote URLs are not supported)",
+		run:   runSyncCommand,
+	},
+}
+
+func commandUsage() string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		lines = append(lines, "  "+commands[name].usage)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// runCommand executes the sub-command named by the first argument.
+func runCommand(env *commandEnv, args []string) error {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %s. Available commands:\n%s", args[0], commandUsage())
+	}
+	if !cmd.lazy {
+		if err := env.load(); err != nil {
+			return err
+		}
+	}
+	return cmd.run(env, args[1:])
+}
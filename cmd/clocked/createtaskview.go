@@ -1,16 +1,22 @@
 package main
 
 import (
+	"strings"
+	"time"
+
 	termbox "github.com/nsf/termbox-go"
+	"github.com/zerok/clocked/internal/config"
 	"github.com/zerok/clocked/internal/form"
+	"github.com/zerok/clocked/internal/templates"
 )
 
-func newCreateTaskForm() *form.Form {
+func newCreateTaskForm(a *application) *form.Form {
 	return form.NewForm([]form.Field{
 		{
 			Code:       "code",
 			Label:      "Code:",
 			IsRequired: true,
+			Validators: a.codeValidators(""),
 		}, {
 			Code:       "title",
 			Label:      "Title:",
@@ -19,6 +25,8 @@ func newCreateTaskForm() *form.Form {
 			Code:       "tags",
 			Label:      "Tags:",
 			IsRequired: false,
+			Type:       form.TagsField,
+			Choices:    knownTags(a.db),
 		},
 	})
 }
@@ -34,53 +42,65 @@ func newCreateTaskView(app *application) *createTaskView {
 	}
 }
 
-func (v *createTaskView) KeyMapping() []KeyMap {
-	return []KeyMap{
-		{Label: "Quit", Key: "^c"},
-		{Label: "Switch field", Key: "TAB"},
-		{Label: "Create task", Key: "ENTER"},
-		{Label: "Cancel", Key: "ESC"},
+func (v *createTaskView) Actions() []Action {
+	a := v.app
+	return []Action{
+		{Name: "form.next", Label: "Switch field", Keys: []string{"TAB"}, Run: func() error {
+			v.form.Next()
+			return nil
+		}},
+		{Name: "create.submit", Label: "Create task", Keys: []string{"ENTER"}, Run: v.submit},
+		{Name: "form.cancel", Label: "Cancel", Keys: []string{"ESC"}, Run: func() error {
+			a.switchMode(selectionMode)
+			return nil
+		}},
 	}
 }
 
+func (v *createTaskView) TextInputFocused() bool {
+	return v.form != nil
+}
+
 func (v *createTaskView) BeforeFocus() error {
-	v.form = newCreateTaskForm()
+	v.form = newCreateTaskForm(v.app)
 	return nil
 }
 
+// SetTemplate prefills the form with the values generated from the given
+// template.
+func (v *createTaskView) SetTemplate(tmpl config.TaskTemplate, t time.Time) {
+	task := templates.NewTask(tmpl, t)
+	v.form.SetValue("code", task.Code)
+	v.form.SetValue("title", task.Title)
+	v.form.SetValue("tags", strings.Join(task.Tags, " "))
+}
+
 func (v *createTaskView) Render(area Area) error {
 	v.app.redrawForm(area, v.form)
 	return nil
 }
 
-func (v *createTaskView) HandleKeyEvent(evt termbox.Event) error {
+func (v *createTaskView) submit() error {
 	a := v.app
-	switch evt.Key {
-	case termbox.KeyEsc:
-		a.switchMode(selectionMode)
-	case termbox.KeyTab:
-		v.form.Next()
-	case termbox.KeyEnter:
-		if v.form.Validate() {
-			t := convertToTask(v.form)
-			if err := a.db.AddTask(t); err != nil {
-				a.err = err
-				break
-			}
-			if a.backup != nil {
-				if err := a.backup.CreateSnapshot(); err != nil {
-					a.fatalError(err, "failed to create snapshot")
-				}
-			}
-			a.log.Infof("%s added", t)
-			a.switchMode(selectionMode)
-			if view, ok := a.activeView.(*tasklistView); ok {
-				view.updateTaskList()
-				view.list.SelectMatchingItem(selectByCode(t.Code))
-			}
-		}
-	default:
-		a.handleFieldInput(v.form, evt)
+	if !v.form.Validate() {
+		return nil
+	}
+	t := convertToTask(v.form)
+	if err := a.db.AddTask(t); err != nil {
+		a.err = err
+		return nil
 	}
+	a.snapshot()
+	a.log.Infof("%s added", t)
+	a.switchMode(selectionMode)
+	if view, ok := a.activeView.(*tasklistView); ok {
+		view.updateTaskList()
+		view.list.SelectMatchingItem(selectByCode(t.Code))
+	}
+	return nil
+}
+
+func (v *createTaskView) HandleKeyEvent(evt termbox.Event) error {
+	v.app.handleFieldInput(v.form, evt)
 	return nil
 }
@@ -17,28 +17,31 @@ type editTaskView struct {
 func newEditTaskView(app *application) *editTaskView {
 	return &editTaskView{
 		app: app,
-		form: form.NewForm([]form.Field{
-			{
-				Code:       "code",
-				Label:      "Code",
-				IsRequired: true,
-			},
-			{
-				Code:       "title",
-				Label:      "Title",
-				IsRequired: false,
-			},
-			{
-				Code:       "tags",
-				Label:      "Tags",
-				IsRequired: false,
-			},
-		}),
 	}
 }
 
 func (v *editTaskView) SetTask(task clocked.Task) {
 	v.task = task
+	v.form = form.NewForm([]form.Field{
+		{
+			Code:       "code",
+			Label:      "Code",
+			IsRequired: true,
+			Validators: v.app.codeValidators(task.Code),
+		},
+		{
+			Code:       "title",
+			Label:      "Title",
+			IsRequired: false,
+		},
+		{
+			Code:       "tags",
+			Label:      "Tags",
+			IsRequired: false,
+			Type:       form.TagsField,
+			Choices:    knownTags(v.app.db),
+		},
+	})
 	v.form.SetValue("code", task.Code)
 	v.form.SetValue("title", task.Title)
 	v.form.SetValue("tags", strings.Join(task.Tags, " "))
@@ -50,30 +53,34 @@ func (v *editTaskView) Render(area Area) error {
 }
 
 func (v *editTaskView) HandleKeyEvent(evt termbox.Event) error {
-	switch {
-	case evt.Key == termbox.KeyEsc:
-		return ErrCloseView
-	case evt.Key == termbox.KeyTab:
-		v.form.Next()
-	case evt.Key == termbox.KeyEnter:
-		t := convertToTask(v.form)
-		if err := v.app.db.UpdateTask(v.task.Code, t); err != nil {
-			v.app.err = err
-		} else {
-			return ErrCloseView
-		}
-
-	default:
-		v.app.handleFieldInput(v.form, evt)
-	}
+	v.app.handleFieldInput(v.form, evt)
 	return nil
 }
 
-func (v *editTaskView) KeyMapping() []KeyMap {
-	return []KeyMap{
-		{Label: "Quit", Key: "^c"},
-		{Label: "Focus next field", Key: "TAB"},
-		{Label: "Save changes", Key: "ENTER"},
-		{Label: "Cancel", Key: "ESC"},
+func (v *editTaskView) TextInputFocused() bool {
+	return v.form != nil
+}
+
+func (v *editTaskView) Actions() []Action {
+	return []Action{
+		{Name: "form.next", Label: "Focus next field", Keys: []string{"TAB"}, Run: func() error {
+			v.form.Next()
+			return nil
+		}},
+		{Name: "edit.submit", Label: "Save changes", Keys: []string{"ENTER"}, Run: func() error {
+			if !v.form.Validate() {
+				return nil
+			}
+			t := convertToTask(v.form)
+			if err := v.app.db.UpdateTask(v.task.Code, t); err != nil {
+				v.app.err = err
+				return nil
+			}
+			v.app.snapshot()
+			return ErrCloseView
+		}},
+		{Name: "form.cancel", Label: "Cancel", Keys: []string{"ESC"}, Run: func() error {
+			return ErrCloseView
+		}},
 	}
 }
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/gitint"
+)
+
+func runGitHookCommand(env *commandEnv, args []string) error {
+	var repoPath string
+	flags := pflag.NewFlagSet("git-hook", pflag.ContinueOnError)
+	flags.StringVar(&repoPath, "repo", ".", "Path of the repository")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 || flags.Arg(0) != "install" {
+		return fmt.Errorf("usage: clocked git-hook install [--repo path]")
+	}
+	clockedPath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	// The hooks run in the repository, so a relative store path would
+	// point somewhere else.
+	storePath, err := filepath.Abs(env.storageFolder)
+	if err != nil {
+		return err
+	}
+	installed, err := gitint.Repository{Path: repoPath}.Install(clockedPath, storePath)
+	for _, path := range installed {
+		fmt.Fprintf(env.out, "Installed %s\n", path)
+	}
+	return err
+}
+
+// confirm asks the user a yes/no question. An empty answer counts as yes.
+func confirm(env *commandEnv, question string) bool {
+	fmt.Fprintf(env.out, "%s [Y/n] ", question)
+	answer, err := bufio.NewReader(env.in).ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "" || answer == "y" || answer == "yes"
+}
+
+func runGitSwitchCommand(env *commandEnv, args []string) error {
+	var repoPath string
+	var useCommit, yes, hook bool
+	flags := pflag.NewFlagSet("git-switch", pflag.ContinueOnError)
+	flags.StringVar(&repoPath, "repo", ".", "Path of the repository")
+	flags.BoolVar(&useCommit, "commit", false, "Use the message of the last commit instead of the branch name")
+	flags.BoolVarP(&yes, "yes", "y", false, "Switch without asking")
+	flags.BoolVar(&hook, "hook", false, "Called from a git hook: stay silent if no code is found")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	yes = yes || env.cfg.GitAutoSwitch
+	repo := gitint.Repository{Path: repoPath}
+	var source string
+	var err error
+	if useCommit {
+		source, err = repo.LastCommitMessage()
+	} else {
+		source, err = repo.CurrentBranch()
+	}
+	if err != nil {
+		return err
+	}
+	code := gitint.ExtractCode(source)
+	if code == "" {
+		if hook {
+			return nil
+		}
+		return fmt.Errorf("no task code found in %q", source)
+	}
+	repoName, err := repo.Name()
+	if err != nil {
+		return err
+	}
+	// Switching repositories starts a new booking so that the time is
+	// attributed to the right one.
+	if active, ok := env.db.ActiveTask(); ok && active.Code == code && active.Bookings[len(active.Bookings)-1].Repository == repoName {
+		if !hook {
+			fmt.Fprintf(env.out, "Already clocked into %s\n", code)
+		}
+		return nil
+	}
+	repoTag := gitint.RepoTagPrefix + repoName
+
+	task, found := env.db.TaskByCode(code)
+	if !found {
+		task = clocked.Task{Code: code, Tags: []string{repoTag}}
+		if !useCommit {
+			task.Title = gitint.TitleFromBranch(source, code)
+		}
+		if !yes && !confirm(env, fmt.Sprintf("Create task %s and clock into it?", task.Label())) {
+			return nil
+		}
+		if err := env.db.AddTask(task); err != nil {
+			return err
+		}
+	} else {
+		if !yes && !confirm(env, fmt.Sprintf("Clock into %s?", task.Label())) {
+			return nil
+		}
+		if !task.HasTag(repoTag) {
+			// The tags are shared with the database.
+			task = task.Copy()
+			task.Tags = append(task.Tags, repoTag)
+			if err := env.db.UpdateTask(code, task); err != nil {
+				return err
+			}
+		}
+	}
+	if err := env.db.ClockIntoRepository(code, repoName); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.out, "Clocked into %s\n", code)
+	return env.snapshot()
+}
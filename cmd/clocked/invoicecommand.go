@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/zerok/clocked/internal/billing"
+	"github.com/zerok/clocked/internal/database"
+)
+
+// invoiceRange defaults to the previous month as invoices are usually
+// written once a month.
+func invoiceRange(from, to string, now time.Time) (time.Time, time.Time, error) {
+	start := database.StartOfMonth(now).AddDate(0, -1, 0)
+	if from != "" {
+		t, err := time.ParseInLocation(database.DayFormat, from, time.Local)
+		if err != nil {
+			return start, start, err
+		}
+		start = t
+	}
+	end := start.AddDate(0, 1, 0)
+	if to != "" {
+		t, err := time.ParseInLocation(database.DayFormat, to, time.Local)
+		if err != nil {
+			return start, end, err
+		}
+		end = t
+	}
+	if !end.After(start) {
+		return start, end, fmt.Errorf("--to has to be after --from")
+	}
+	return start, end, nil
+}
+
+func runInvoiceCommand(env *commandEnv, args []string) error {
+	var from, to, client, format, output string
+	flags := pflag.NewFlagSet("invoice", pflag.ContinueOnError)
+	flags.StringVar(&from, "from", "", "First day of the invoice (default: start of last month)")
+	flags.StringVar(&to, "to", "", "Day after the last day of the invoice (default: one month after --from)")
+	flags.StringVar(&client, "client", "", "Only create the invoice of the given client")
+	flags.StringVar(&format, "format", billing.FormatMarkdown, "Output as csv, json, markdown or html")
+	flags.StringVar(&output, "output", "", "Write the invoice into the given file instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	start, end, err := invoiceRange(from, to, time.Now())
+	if err != nil {
+		return err
+	}
+	invoices, err := billing.Generate(env.db, env.cfg.Billing, start, end)
+	if err != nil {
+		return err
+	}
+	if client != "" {
+		var filtered []billing.Invoice
+		for _, inv := range invoices {
+			if inv.Client.Name == client {
+				filtered = append(filtered, inv)
+			}
+		}
+		if len(filtered) == 0 {
+			return fmt.Errorf("no time booked for client %s", client)
+		}
+		invoices = filtered
+	}
+	if output == "" {
+		return billing.Write(env.out, invoices, format)
+	}
+	fp, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := billing.Write(fp, invoices, format); err != nil {
+		fp.Close()
+		return err
+	}
+	return fp.Close()
+}
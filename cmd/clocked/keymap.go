@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	termbox "github.com/nsf/termbox-go"
+	"gopkg.in/yaml.v2"
+)
+
+// KeymapFilename is the name of the file inside the store that overrides
+// the default key bindings.
+const KeymapFilename = "keymap.yml"
+
+// Action is a named operation offered by a view. Name is used to refer to
+// the action inside of the keymap file, e.g. tasklist.down.
+type Action struct {
+	Name  string
+	Label string
+	// Keys are the default key bindings.
+	Keys []string
+	// Enabled reports if the action is currently available. Actions without
+	// it are always available.
+	Enabled func() bool
+	// Hidden actions are not listed in the infoline.
+	Hidden bool
+	Run    func() error
+}
+
+func (a Action) enabled() bool {
+	return a.Enabled == nil || a.Enabled()
+}
+
+var namedKeys = map[string]termbox.Key{
+	"ENTER":     termbox.KeyEnter,
+	"ESC":       termbox.KeyEsc,
+	"TAB":       termbox.KeyTab,
+	"SPACE":     termbox.KeySpace,
+	"BACKSPACE": termbox.KeyBackspace2,
+	"DELETE":    termbox.KeyDelete,
+	"UP":        termbox.KeyArrowUp,
+	"DOWN":      termbox.KeyArrowDown,
+	"LEFT":      termbox.KeyArrowLeft,
+	"RIGHT":     termbox.KeyArrowRight,
+	"HOME":      termbox.KeyHome,
+	"END":       termbox.KeyEnd,
+	"PGUP":      termbox.KeyPgup,
+	"PGDN":      termbox.KeyPgdn,
+	"F1":        termbox.KeyF1,
+	"F2":        termbox.KeyF2,
+	"F3":        termbox.KeyF3,
+	"F4":        termbox.KeyF4,
+	"F5":        termbox.KeyF5,
+	"F6":        termbox.KeyF6,
+	"F7":        termbox.KeyF7,
+	"F8":        termbox.KeyF8,
+	"F9":        termbox.KeyF9,
+	"F10":       termbox.KeyF10,
+	"F11":       termbox.KeyF11,
+	"F12":       termbox.KeyF12,
+}
+
+// keySpec is a parsed key binding. Either ch or key is set.
+type keySpec struct {
+	ch  rune
+	key termbox.Key
+}
+
+// parseKey understands single characters (j), control combinations (^s)
+// and the names listed in namedKeys (ENTER).
+func parseKey(s string) (keySpec, error) {
+	if k, ok := namedKeys[strings.ToUpper(s)]; ok {
+		return keySpec{key: k}, nil
+	}
+	r := []rune(s)
+	if len(r) == 1 {
+		return keySpec{ch: r[0]}, nil
+	}
+	if len(r) == 2 && r[0] == '^' {
+		c := r[1]
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		if c >= 'a' && c <= 'z' {
+			return keySpec{key: termbox.Key(c - 'a' + 1)}, nil
+		}
+	}
+	return keySpec{}, fmt.Errorf("unknown key %s", s)
+}
+
+func (k keySpec) matches(evt termbox.Event) bool {
+	if k.ch != 0 {
+		return evt.Ch == k.ch
+	}
+	return evt.Ch == 0 && evt.Key == k.key
+}
+
+// Keymap maps action names to the keys that should trigger them instead of
+// their defaults.
+type Keymap map[string][]string
+
+// LoadKeymap reads the keymap file at the given path. A missing file
+// results in an empty keymap.
+func LoadKeymap(path string) (Keymap, error) {
+	km := Keymap{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return km, nil
+		}
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &km); err != nil {
+		return nil, err
+	}
+	for name, keys := range km {
+		for _, k := range keys {
+			if _, err := parseKey(k); err != nil {
+				return nil, fmt.Errorf("%s: %s", name, err.Error())
+			}
+		}
+	}
+	return km, nil
+}
+
+// Keys returns the keys bound to the given action.
+func (k Keymap) Keys(a Action) []string {
+	if keys, ok := k[a.Name]; ok {
+		return keys
+	}
+	return a.Keys
+}
+
+// Lookup returns the enabled action the event is bound to. Bindings from
+// the keymap file take precedence over the defaults so that overriding a
+// key doesn't require rebinding every other action using it.
+func (k Keymap) Lookup(actions []Action, evt termbox.Event) (Action, bool) {
+	for _, overridden := range []bool{true, false} {
+		for _, a := range actions {
+			if _, ok := k[a.Name]; ok != overridden || !a.enabled() {
+				continue
+			}
+			for _, key := range k.Keys(a) {
+				spec, err := parseKey(key)
+				if err == nil && spec.matches(evt) {
+					return a, true
+				}
+			}
+		}
+	}
+	return Action{}, false
+}
+
+// Mapping generates the infoline entries for all visible actions.
+func (k Keymap) Mapping(actions []Action) []KeyMap {
+	result := make([]KeyMap, 0, len(actions))
+	for _, a := range actions {
+		keys := k.Keys(a)
+		if a.Hidden || len(keys) == 0 || !a.enabled() {
+			continue
+		}
+		result = append(result, KeyMap{Label: a.Label, Key: strings.Join(keys, "/"), Action: a.Name})
+	}
+	return result
+}
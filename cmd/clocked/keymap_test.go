@@ -0,0 +1,110 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	termbox "github.com/nsf/termbox-go"
+	"github.com/stretchr/testify/require"
+	"github.com/zerok/clocked/internal/database"
+)
+
+func TestParseKey(t *testing.T) {
+	k, err := parseKey("j")
+	require.NoError(t, err)
+	require.True(t, k.matches(termbox.Event{Ch: 'j'}))
+	require.False(t, k.matches(termbox.Event{Ch: 'k'}))
+
+	k, err = parseKey("^S")
+	require.NoError(t, err)
+	require.True(t, k.matches(termbox.Event{Key: termbox.KeyCtrlS}))
+
+	k, err = parseKey("down")
+	require.NoError(t, err)
+	require.True(t, k.matches(termbox.Event{Key: termbox.KeyArrowDown}))
+
+	_, err = parseKey("^1")
+	require.Error(t, err)
+	_, err = parseKey("unknown")
+	require.Error(t, err)
+}
+
+func TestKeymapLookup(t *testing.T) {
+	var called string
+	action := func(name string, keys ...string) Action {
+		return Action{Name: name, Label: name, Keys: keys, Run: func() error {
+			called = name
+			return nil
+		}}
+	}
+	actions := []Action{
+		action("create", "n", "^n"),
+		action("down", "j"),
+		{Name: "disabled", Keys: []string{"x"}, Enabled: func() bool { return false }},
+	}
+
+	km := Keymap{}
+	a, ok := km.Lookup(actions, termbox.Event{Key: termbox.KeyCtrlN})
+	require.True(t, ok)
+	require.NoError(t, a.Run())
+	require.Equal(t, "create", called)
+	_, ok = km.Lookup(actions, termbox.Event{Ch: 'x'})
+	require.False(t, ok, "disabled actions should not be triggered")
+
+	// Overridden bindings win over defaults using the same key.
+	km = Keymap{"down": []string{"j", "^n"}}
+	a, ok = km.Lookup(actions, termbox.Event{Key: termbox.KeyCtrlN})
+	require.True(t, ok)
+	require.Equal(t, "down", a.Name)
+
+	require.Equal(t, []KeyMap{
+		{Label: "create", Key: "n/^n", Action: "create"},
+		{Label: "down", Key: "j/^n", Action: "down"},
+	}, km.Mapping(actions))
+}
+
+func TestLoadKeymap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clocked-keymap")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, KeymapFilename)
+
+	km, err := LoadKeymap(path)
+	require.NoError(t, err)
+	require.Len(t, km, 0, "a missing file should result in the default bindings")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("tasklist.down: [j, ^n]\n"), 0600))
+	km, err = LoadKeymap(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"j", "^n"}, km["tasklist.down"])
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("tasklist.down: [^1]\n"), 0600))
+	_, err = LoadKeymap(path)
+	require.Error(t, err)
+}
+
+func TestPrintableBindingsDontBlockTyping(t *testing.T) {
+	app := newApplication()
+	app.db = database.NewInMemory()
+	app.keymap = Keymap{"create.submit": []string{"s"}}
+	v := newCreateTaskView(app)
+	require.NoError(t, v.BeforeFocus())
+	app.activeView = v
+
+	for _, ch := range "sync" {
+		app.handleKey(termbox.Event{Ch: ch})
+	}
+	require.Equal(t, "sync", v.form.Value("code"), "s should be typed into the focused field")
+	require.True(t, app.db.Empty(), "the task should not have been submitted")
+
+	// Outside of text fields, printable bindings still work.
+	list := app.views[selectionMode].(*tasklistView)
+	app.keymap = Keymap{"tasklist.filter": []string{"s"}}
+	app.activeView = list
+	app.handleKey(termbox.Event{Ch: 's'})
+	require.True(t, list.filterFocused)
+	app.handleKey(termbox.Event{Ch: 's'})
+	require.Equal(t, "s", list.filter.Value())
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// runKeysCommand lists all actions together with their current key
+// bindings so that users know what they can override in the keymap file.
+func runKeysCommand(env *commandEnv, args []string) error {
+	keymap, err := LoadKeymap(filepath.Join(env.storageFolder, KeymapFilename))
+	if err != nil {
+		return err
+	}
+	app := newApplication()
+	actions := app.globalActions()
+	modes := make([]int, 0, len(app.views))
+	for mode := range app.views {
+		modes = append(modes, mode)
+	}
+	sort.Ints(modes)
+	for _, mode := range modes {
+		if p, ok := app.views[mode].(ActionProvider); ok {
+			actions = append(actions, p.Actions()...)
+		}
+	}
+	seen := make(map[string]struct{})
+	names := make([]string, 0, len(actions))
+	byName := make(map[string]Action)
+	for _, a := range actions {
+		if _, found := seen[a.Name]; found {
+			continue
+		}
+		seen[a.Name] = struct{}{}
+		names = append(names, a.Name)
+		byName[a.Name] = a
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		a := byName[name]
+		fmt.Fprintf(env.out, "%-26s %-14s %s\n", name, strings.Join(keymap.Keys(a), "/"), a.Label)
+	}
+	return nil
+}
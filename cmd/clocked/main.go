@@ -13,6 +13,9 @@ import (
 	"github.com/zerok/clocked/internal/config"
 	"github.com/zerok/clocked/internal/database"
 	"github.com/zerok/clocked/internal/jira"
+	"github.com/zerok/clocked/internal/submissions"
+	"github.com/zerok/clocked/internal/tags"
+	"github.com/zerok/clocked/internal/templates"
 )
 
 var version, commit, date string
@@ -26,6 +29,10 @@ func main() {
 	pflag.StringVar(&logFile, "log-file", "", "Path to a logfile")
 	pflag.StringVar(&storageFolder, "store", filepath.Join(os.Getenv("HOME"), ".clocked"), "Path where clocked will store its data")
 	pflag.BoolVar(&showVersion, "version", false, "Show version information")
+	pflag.CommandLine.SetInterspersed(false)
+	pflag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: clocked [flags] [command]\n\nFlags:\n%s\nCommands:\n%s\n", pflag.CommandLine.FlagUsages(), commandUsage())
+	}
 	pflag.Parse()
 
 	if showVersion {
@@ -45,6 +52,9 @@ func main() {
 
 	if verbose {
 		log.SetLevel(logrus.DebugLevel)
+	} else if pflag.NArg() > 0 && logFile == "" {
+		// Commands should only report problems.
+		log.SetLevel(logrus.WarnLevel)
 	}
 
 	if err := ensureStorageFolder(storageFolder); err != nil {
@@ -56,36 +66,72 @@ func main() {
 		log.WithError(err).Fatalf("Failed to load configuration file")
 	}
 
-	db, err := database.NewDatabase(storageFolder, log)
+	if pflag.NArg() > 0 {
+		env := &commandEnv{
+			storageFolder: storageFolder,
+			cfg:           cfg,
+			log:           log,
+			in:            os.Stdin,
+			out:           os.Stdout,
+		}
+		if err := runCommand(env, pflag.Args()); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", pflag.Arg(0), err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	db, bk, err := openStore(storageFolder, cfg, log)
 	if err != nil {
-		log.WithError(err).Fatalf("Failed to load database from %s", storageFolder)
+		log.WithError(err).Fatal("Failed to open store")
 	}
-	if err := db.LoadState(); err != nil {
-		log.WithError(err).Fatalf("Failed to load database")
+
+	keymap, err := LoadKeymap(filepath.Join(storageFolder, KeymapFilename))
+	if err != nil {
+		log.WithError(err).Fatalf("Failed to load %s", KeymapFilename)
 	}
 
-	bk, err := backup.New(&backup.Options{
-		SourcePath:     storageFolder,
-		RepositoryPath: cfg.BackupsPath,
-	})
+	theme, err := LoadTheme(filepath.Join(storageFolder, ThemeFilename))
 	if err != nil {
-		log.WithError(err).Fatal("Failed to configure backup")
+		log.WithError(err).Fatalf("Failed to load %s", ThemeFilename)
 	}
-	if !bk.Available() {
-		log.Info("Backing up not possible. Most likely restic is not installed.")
-	} else {
-		if err := bk.Init(); err != nil {
-			log.WithError(err).Fatalf("Failed to initialize backup")
-		}
-		if bk.Created() && !db.Empty() {
-			if err := bk.CreateSnapshot(); err != nil {
-				log.WithError(err).Fatalf("Failed to create initial snapshot")
-			}
-		}
+
+	tagColorsPath := filepath.Join(storageFolder, tags.Filename)
+	tagColors, err := tags.LoadColors(tagColorsPath)
+	if err != nil {
+		log.WithError(err).Fatalf("Failed to load %s", tags.Filename)
+	}
+
+	submissionsPath := filepath.Join(storageFolder, submissions.Filename)
+	submitted, err := submissions.Load(submissionsPath)
+	if err != nil {
+		log.WithError(err).Fatalf("Failed to load %s", submissions.Filename)
+	}
+
+	recurringPath := filepath.Join(storageFolder, templates.Filename)
+	recurring, err := templates.LoadHistory(recurringPath)
+	if err != nil {
+		log.WithError(err).Fatalf("Failed to load %s", templates.Filename)
 	}
 
 	app := newApplication()
+	app.tagColors = tagColors
+	app.tagColorsPath = tagColorsPath
+	app.submissions = submitted
+	app.submissionsPath = submissionsPath
+	app.recurring = recurring
+	app.recurringPath = recurringPath
+	app.keymap = keymap
+	app.setTheme(theme)
 	app.backup = bk
+	app.backups = backup.NewWorker(newSnapshotScheduler(bk, cfg), func() {
+		// Interrupt blocks until the event has been polled.
+		go termbox.Interrupt()
+	})
+	if needsInitialSnapshot(db, bk) {
+		app.backups.Request()
+	}
+	app.cfg = cfg
 	app.db = db
 	app.log = log
 	if cfg.JIRAURL != "" && cfg.JIRAPassword != "" && cfg.JIRAUsername != "" {
@@ -102,10 +148,64 @@ func main() {
 		log.WithError(err).Fatal("Failed to initialize application")
 	}
 	defer termbox.Close()
+	app.setOutputMode()
+	if !cfg.DisableMouse {
+		termbox.SetInputMode(termbox.InputEsc | termbox.InputMouse)
+	}
 	app.handleResize()
 	app.start()
 }
 
+// openStore loads the database inside of the storage folder and prepares
+// its backup. If the backup repository has just been created, an initial
+// snapshot should be taken (see needsInitialSnapshot).
+func openStore(storageFolder string, cfg *config.Config, log *logrus.Logger) (database.Database, *backup.Backup, error) {
+	db, err := database.Open(cfg.Storage, storageFolder, log)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load database from %s: %s", storageFolder, err.Error())
+	}
+	if err := db.LoadState(); err != nil {
+		return nil, nil, fmt.Errorf("failed to load database: %s", err.Error())
+	}
+
+	bk, err := backup.New(&backup.Options{
+		SourcePath:     storageFolder,
+		RepositoryPath: cfg.BackupsPath,
+		Backend:        cfg.Backups.Backend,
+		ResticPath:     cfg.Backups.ResticPath,
+		Encrypt:        cfg.Backups.Encrypt,
+		Policy: backup.Policy{
+			Last:    cfg.Backups.KeepLast,
+			Hourly:  cfg.Backups.KeepHourly,
+			Daily:   cfg.Backups.KeepDaily,
+			Weekly:  cfg.Backups.KeepWeekly,
+			Monthly: cfg.Backups.KeepMonthly,
+		},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to configure backup: %s", err.Error())
+	}
+	if !bk.Available() {
+		log.Info("Backing up not possible. Most likely restic is not installed.")
+		return db, bk, nil
+	}
+	if err := bk.Init(); err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize backup: %s", err.Error())
+	}
+	return db, bk, nil
+}
+
+// needsInitialSnapshot reports if the backup repository has just been
+// created for a store that already contains data.
+func needsInitialSnapshot(db database.Database, bk *backup.Backup) bool {
+	return bk.Created() && !db.Empty()
+}
+
+// newSnapshotScheduler takes snapshots of the store as often as configured.
+func newSnapshotScheduler(bk *backup.Backup, cfg *config.Config) *backup.Scheduler {
+	return backup.NewScheduler(bk, cfg.Backups.MinInterval, cfg.Backups.PruneInterval)
+}
+
 func ensureStorageFolder(storageFolder string) error {
 	return os.MkdirAll(storageFolder, 0700)
 }
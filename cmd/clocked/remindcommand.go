@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/zerok/clocked/internal/notify"
+	"github.com/zerok/clocked/internal/reminder"
+)
+
+func runRemindCommand(env *commandEnv, args []string) error {
+	var interval time.Duration
+	var once bool
+	flags := pflag.NewFlagSet("remind", pflag.ContinueOnError)
+	flags.DurationVar(&interval, "interval", time.Minute, "How often the store should be checked")
+	flags.BoolVar(&once, "once", false, "Check only once and exit")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	notifier := notify.NewDBusNotifier()
+	if !notifier.Available() {
+		return fmt.Errorf("gdbus is required for sending notifications")
+	}
+	return remind(env, notifier, interval, once)
+}
+
+// remind checks the store every interval or only once. What has already
+// been reported is kept in the store so that running it only once, e.g.
+// from cron, doesn't repeat notifications.
+func remind(env *commandEnv, notifier notify.Notifier, interval time.Duration, once bool) error {
+	r := reminder.New(env.cfg, notifier)
+	r.StatePath = filepath.Join(env.storageFolder, reminder.StateFilename)
+	for {
+		// Other processes might have changed the store in the meantime.
+		if err := env.db.LoadState(); err != nil {
+			return err
+		}
+		if err := r.Check(env.db, time.Now()); err != nil {
+			if once {
+				return err
+			}
+			env.log.WithError(err).Error("Failed to send reminders")
+		}
+		if once {
+			return nil
+		}
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/config"
+	"github.com/zerok/clocked/internal/database"
+	"github.com/zerok/clocked/internal/notify"
+)
+
+func TestRemindOnceKeepsState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clocked-remind")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db := database.NewInMemory()
+	require.NoError(t, db.AddTask(clocked.Task{Code: "A"}))
+	require.NoError(t, db.ClockInto("A"))
+	require.NoError(t, db.UpdateBooking("A", 0, booking(time.Now().Add(-3*time.Hour), time.Time{})))
+	env := &commandEnv{
+		storageFolder: dir,
+		cfg:           &config.Config{Reminders: config.Reminders{LongBooking: time.Hour}},
+		db:            db,
+		log:           logrus.New(),
+	}
+
+	// Every run of `clocked remind --once` is a new process.
+	fake := &notify.Fake{}
+	require.NoError(t, remind(env, fake, 0, true))
+	require.Len(t, fake.Notifications(), 1)
+	require.NoError(t, remind(env, fake, 0, true))
+	require.Len(t, fake.Notifications(), 1, "the running booking has already been reported")
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"unicode/utf8"
 
 	"github.com/nsf/termbox-go"
 )
@@ -12,12 +13,39 @@ type ScrollableListItem interface {
 	Label() string
 }
 
+// ColumnListItem is an item that has additional information that should be
+// rendered in right-aligned columns next to its label.
+type ColumnListItem interface {
+	ScrollableListItem
+	Columns() []string
+}
+
+// HighlightedListItem is an item that wants some characters of its label
+// emphasized, e.g. because they were matched by a filter.
+type HighlightedListItem interface {
+	ScrollableListItem
+	Highlights() []int
+}
+
+// Badge is a short, styled text rendered behind the label of an item.
+type Badge struct {
+	Text  string
+	Style Style
+}
+
+// BadgedListItem is an item with badges, e.g. for its tags.
+type BadgedListItem interface {
+	ScrollableListItem
+	Badges() []Badge
+}
+
 type ScrollableList struct {
 	selectedIndex int
 	items         []ScrollableListItem
 	windowSize    int
 	offset        int
 	area          Area
+	theme         *Theme
 }
 
 func NewScrollableList(area Area) *ScrollableList {
@@ -29,6 +57,13 @@ func NewScrollableList(area Area) *ScrollableList {
 	return sv
 }
 
+// newThemedList creates a list using the theme of the application.
+func newThemedList(app *application) *ScrollableList {
+	s := NewScrollableList(Area{})
+	s.theme = app.theme
+	return s
+}
+
 func (s *ScrollableList) SelectItemByLabel(l string) (int, bool) {
 	for idx, i := range s.items {
 		if i.Label() == l {
@@ -60,22 +95,96 @@ func (s *ScrollableList) SelectMatchingItem(matcher ItemMatcherFunc) (int, bool)
 
 func (s *ScrollableList) drawWindow() {
 	line := 0
+	widths := s.columnWidths()
 	for i := s.offset; i < s.offset+s.windowSize; i++ {
 		if i >= len(s.items) {
 			break
 		}
-		s.renderItem(i, line)
+		s.renderItem(i, line, widths)
 		line++
 	}
 }
 
-func (s *ScrollableList) renderItem(idx int, line int) {
+// columnWidths calculates the width of each column of the currently visible
+// items.
+func (s *ScrollableList) columnWidths() []int {
+	widths := make([]int, 0, 3)
+	for i := s.offset; i < s.offset+s.windowSize && i < len(s.items); i++ {
+		ci, ok := s.items[i].(ColumnListItem)
+		if !ok {
+			continue
+		}
+		for idx, c := range ci.Columns() {
+			if idx >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if l := utf8.RuneCountInString(c); l > widths[idx] {
+				widths[idx] = l
+			}
+		}
+	}
+	return widths
+}
+
+func (s *ScrollableList) renderItem(idx int, line int, widths []int) {
 	item := s.items[idx]
+	yOffset := s.area.YMin() + line
+	text := s.theme.Style(styleText)
 	if idx == s.selectedIndex {
-		termbox.SetCell(s.area.XMin(), s.area.YMin()+line, '>', termbox.ColorDefault|termbox.AttrBold, termbox.ColorDefault)
+		selected := s.theme.Style(styleSelected)
+		termbox.SetCell(s.area.XMin(), yOffset, '>', selected.Fg, selected.Bg)
+	}
+	labelEnd := s.area.XMax()
+	if ci, ok := item.(ColumnListItem); ok && len(widths) > 0 {
+		xOffset := s.area.XMax() + 1
+		columns := ci.Columns()
+		for colIdx := len(widths) - 1; colIdx >= 0; colIdx-- {
+			xOffset -= widths[colIdx] + 2
+			if colIdx >= len(columns) {
+				continue
+			}
+			c := columns[colIdx]
+			start := xOffset + widths[colIdx] - utf8.RuneCountInString(c)
+			for chIdx, ch := range []rune(c) {
+				termbox.SetCell(start+chIdx, yOffset, ch, text.Fg, text.Bg)
+			}
+		}
+		labelEnd = xOffset - 1
+	}
+	highlights := make(map[int]struct{})
+	if hi, ok := item.(HighlightedListItem); ok {
+		for _, pos := range hi.Highlights() {
+			highlights[pos] = struct{}{}
+		}
+	}
+	chIdx := 0
+	for _, c := range item.Label() {
+		x := s.area.XMin() + 3 + chIdx
+		if x >= labelEnd {
+			termbox.SetCell(labelEnd, yOffset, '\u2026', text.Fg, text.Bg)
+			break
+		}
+		style := text
+		if _, found := highlights[chIdx]; found {
+			style = s.theme.Style(styleHighlight)
+		}
+		termbox.SetCell(x, yOffset, c, style.Fg, style.Bg)
+		chIdx++
+	}
+	bi, ok := item.(BadgedListItem)
+	if !ok {
+		return
 	}
-	for chIdx, c := range item.Label() {
-		termbox.SetCell(s.area.XMin()+3+chIdx, s.area.YMin()+line, c, termbox.ColorDefault, termbox.ColorDefault)
+	x := s.area.XMin() + 3 + chIdx
+	for _, b := range bi.Badges() {
+		x++
+		for _, c := range b.Text {
+			if x >= labelEnd {
+				return
+			}
+			termbox.SetCell(x, yOffset, c, b.Style.Fg, b.Style.Bg)
+			x++
+		}
 	}
 }
 
@@ -119,6 +228,53 @@ func (s *ScrollableList) recalculateOffset() {
 	}
 }
 
+// Scroll moves the visible window by delta items without changing the
+// selection.
+func (s *ScrollableList) Scroll(delta int) {
+	s.offset += delta
+	if max := len(s.items) - s.windowSize; s.offset > max {
+		s.offset = max
+	}
+	if s.offset < 0 {
+		s.offset = 0
+	}
+}
+
+// ItemAt returns the index of the item rendered at the given cell.
+func (s *ScrollableList) ItemAt(x, y int) (int, bool) {
+	if !s.area.Contains(x, y) {
+		return -1, false
+	}
+	line := y - s.area.YMin()
+	if line >= s.windowSize {
+		return -1, false
+	}
+	idx := s.offset + line
+	if idx >= len(s.items) {
+		return -1, false
+	}
+	return idx, true
+}
+
+// HandleMouseEvent scrolls the list using the mouse wheel and selects
+// clicked items. It returns true if an item has been clicked.
+func (s *ScrollableList) HandleMouseEvent(evt termbox.Event) bool {
+	switch evt.Key {
+	case termbox.MouseWheelUp:
+		s.Scroll(-1)
+	case termbox.MouseWheelDown:
+		s.Scroll(1)
+	case termbox.MouseLeft:
+		idx, ok := s.ItemAt(evt.MouseX, evt.MouseY)
+		if !ok {
+			return false
+		}
+		s.SelectItemByIndex(idx)
+		return true
+	}
+	return false
+}
+
 func (s *ScrollableList) Render() {
 	s.drawWindow()
 	s.renderPager()
@@ -126,7 +282,17 @@ func (s *ScrollableList) Render() {
 
 func (s *ScrollableList) UpdateArea(a Area) {
 	s.area = a
-	s.windowSize = a.Height - 1 // -1 for the pager
+	windowSize := a.Height - 1 // -1 for the pager
+	// Views update the area on every render. Keep the offset unless the
+	// size changed so that scrolling with the mouse wheel isn't undone.
+	if windowSize == s.windowSize {
+		return
+	}
+	s.windowSize = windowSize
+	// Items might have been selected before the list knew its size.
+	if s.selectedIndex >= 0 && s.windowSize > 0 {
+		s.recalculateOffset()
+	}
 }
 
 func (s *ScrollableList) UpdateItems(i []ScrollableListItem) {
@@ -140,7 +306,8 @@ func (s *ScrollableList) renderPager() {
 	text := fmt.Sprintf("[%d/%d]", s.selectedIndex+1, len(s.items))
 	xOffset := s.area.XMax() - len(text)
 	yOffset := s.area.YMax()
+	style := s.theme.Style(styleText)
 	for idx, c := range text {
-		termbox.SetCell(xOffset+idx, yOffset, c, termbox.ColorDefault, termbox.ColorDefault)
+		termbox.SetCell(xOffset+idx, yOffset, c, style.Fg, style.Bg)
 	}
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"testing"
 
+	termbox "github.com/nsf/termbox-go"
 	"github.com/stretchr/testify/require"
 )
 
@@ -56,3 +57,40 @@ func TestScrollViewSelectedItem(t *testing.T) {
 	_, ok = sv.SelectedItem()
 	require.False(t, ok, "No item should have been selected")
 }
+
+func TestScrollviewMouse(t *testing.T) {
+	sv := NewScrollableList(Area{X: 0, Y: 2, Width: 10, Height: 4})
+	sv.UpdateItems([]ScrollableListItem{
+		MockScrollViewItem("a"),
+		MockScrollViewItem("b"),
+		MockScrollViewItem("c"),
+		MockScrollViewItem("d"),
+		MockScrollViewItem("e"),
+	})
+
+	require.True(t, sv.HandleMouseEvent(termbox.Event{Key: termbox.MouseLeft, MouseX: 3, MouseY: 3}))
+	require.Equal(t, 1, sv.selectedIndex)
+	require.False(t, sv.HandleMouseEvent(termbox.Event{Key: termbox.MouseLeft, MouseX: 3, MouseY: 5}), "the pager is no item")
+	require.False(t, sv.HandleMouseEvent(termbox.Event{Key: termbox.MouseLeft, MouseX: 3, MouseY: 0}))
+
+	sv.HandleMouseEvent(termbox.Event{Key: termbox.MouseWheelDown})
+	sv.HandleMouseEvent(termbox.Event{Key: termbox.MouseWheelDown})
+	sv.HandleMouseEvent(termbox.Event{Key: termbox.MouseWheelDown})
+	require.Equal(t, 2, sv.offset, "scrolling should stop at the last item")
+	require.Equal(t, 1, sv.selectedIndex, "scrolling should keep the selection")
+	idx, ok := sv.ItemAt(0, 2)
+	require.True(t, ok)
+	require.Equal(t, 2, idx)
+	sv.HandleMouseEvent(termbox.Event{Key: termbox.MouseWheelUp})
+	sv.HandleMouseEvent(termbox.Event{Key: termbox.MouseWheelUp})
+	sv.HandleMouseEvent(termbox.Event{Key: termbox.MouseWheelUp})
+	require.Equal(t, 0, sv.offset)
+}
+
+func TestSelectionBeforeUpdateArea(t *testing.T) {
+	s := NewScrollableList(Area{})
+	s.UpdateItems([]ScrollableListItem{MockScrollViewItem("a"), MockScrollViewItem("b"), MockScrollViewItem("c")})
+	s.SelectItemByIndex(0)
+	s.UpdateArea(Area{Width: 10, Height: 5})
+	require.Equal(t, 0, s.offset)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/satori/go.uuid"
+	"github.com/spf13/pflag"
+	"github.com/zerok/clocked/internal/api"
+)
+
+const apiTokenFilename = "api.token"
+
+// newHTTPServer returns a server with timeouts so that slow or stalled
+// clients can't keep connections open forever.
+func newHTTPServer(listen string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              listen,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+}
+
+func runServeCommand(env *commandEnv, args []string) error {
+	var listen string
+	var token string
+	flags := pflag.NewFlagSet("serve", pflag.ContinueOnError)
+	flags.StringVar(&listen, "listen", "127.0.0.1:8425", "Address the API should be served on")
+	flags.StringVar(&token, "token", "", "Token clients have to send (default: api_token setting or generated)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if token == "" {
+		token = env.cfg.APIToken
+	}
+	if token == "" {
+		t, err := loadOrCreateToken(filepath.Join(env.storageFolder, apiTokenFilename))
+		if err != nil {
+			return err
+		}
+		token = t
+		fmt.Fprintf(env.out, "Using the token stored in %s\n", filepath.Join(env.storageFolder, apiTokenFilename))
+	}
+	srv, err := api.NewServer(env.db, api.Options{
+		Token:       token,
+		Reload:      true,
+		CodePattern: env.cfg.CodeRegexp(),
+		Log:         env.log,
+		OnChange: func() {
+			if err := env.snapshot(); err != nil {
+				env.log.WithError(err).Error("Failed to create snapshot")
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(env.out, "Serving the API on http://%s/api/\n", listen)
+	return newHTTPServer(listen, srv).ListenAndServe()
+}
+
+// loadOrCreateToken reads the token from the given file. If the file
+// doesn't exist yet, a random token is generated and stored in it.
+func loadOrCreateToken(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err == nil {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+	token := uuid.NewV4().String()
+	return token, ioutil.WriteFile(path, []byte(token), 0600)
+}
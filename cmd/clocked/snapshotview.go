@@ -14,7 +14,7 @@ type snapshotView struct {
 func newSnapshotView(app *application) *snapshotView {
 	return &snapshotView{
 		app:  app,
-		list: NewScrollableList(Area{}),
+		list: newThemedList(app),
 	}
 }
 
@@ -38,38 +38,55 @@ func (v *snapshotView) Render(area Area) error {
 	return nil
 }
 
-func (v *snapshotView) KeyMapping() []KeyMap {
-	var result []KeyMap
-	result = append(result, KeyMap{Label: "Back", Key: "q/ESC"})
-	if _, ok := v.list.SelectedItem(); ok {
-		result = append(result, KeyMap{Label: "Restore", Key: "ENTER"})
+func (v *snapshotView) Actions() []Action {
+	return []Action{
+		{Name: "snapshots.close", Label: "Back", Keys: []string{"q", "ESC"}, Run: func() error {
+			return ErrCloseView
+		}},
+		{Name: "snapshots.log", Label: "Backup log", Keys: []string{"l"}, Run: func() error {
+			v.app.switchMode(backupLogMode)
+			return nil
+		}},
+		{Name: "snapshots.restore", Label: "Restore", Keys: []string{"ENTER"}, Enabled: func() bool {
+			_, ok := v.list.SelectedItem()
+			return ok
+		}, Run: v.restoreSelected},
+		{Name: "snapshots.next", Label: "Next", Keys: []string{"j", "DOWN"}, Run: func() error {
+			v.list.Next()
+			return nil
+		}},
+		{Name: "snapshots.previous", Label: "Previous", Keys: []string{"k", "UP"}, Run: func() error {
+			v.list.Previous()
+			return nil
+		}},
 	}
-	result = append(result, KeyMap{Label: "Next", Key: "j"}, KeyMap{Label: "Previous", Key: "k"})
-	return result
 }
 
-func (v *snapshotView) HandleKeyEvent(evt termbox.Event) error {
-	switch {
-	case evt.Ch == 'q' || evt.Key == termbox.KeyEsc:
-		return ErrCloseView
-	case evt.Ch == 'j':
-		v.list.Next()
-	case evt.Ch == 'k':
-		v.list.Previous()
-	case evt.Key == termbox.KeyEnter:
-		if item, ok := v.list.SelectedItem(); ok {
-			if snapshot, ok := item.(backup.Snapshot); ok {
-				if err := v.app.backup.Restore(snapshot.ID); err != nil {
-					v.app.err = err
-				} else {
-					if err := v.app.db.LoadState(); err != nil {
-						v.app.err = err
-					} else {
-						return ErrCloseView
-					}
-				}
-			}
-		}
+func (v *snapshotView) restoreSelected() error {
+	item, ok := v.list.SelectedItem()
+	if !ok {
+		return nil
+	}
+	snapshot, ok := item.(backup.Snapshot)
+	if !ok {
+		return nil
+	}
+	if err := v.app.backup.Restore(snapshot.ID); err != nil {
+		v.app.err = err
+		return nil
 	}
+	if err := v.app.db.LoadState(); err != nil {
+		v.app.err = err
+		return nil
+	}
+	return ErrCloseView
+}
+
+func (v *snapshotView) HandleMouseEvent(evt termbox.Event, double bool) error {
+	v.list.HandleMouseEvent(evt)
+	return nil
+}
+
+func (v *snapshotView) HandleKeyEvent(evt termbox.Event) error {
 	return nil
 }
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nsf/termbox-go"
+	"github.com/zerok/clocked/internal/database"
+	"github.com/zerok/clocked/internal/stats"
+)
+
+// Number of weeks shown by the statistics view and the height of its chart.
+const (
+	defaultStatsWeeks = 4
+	maxStatsWeeks     = 26
+	statsChartHeight  = 8
+)
+
+// statsView shows charts of the time booked over the last weeks.
+type statsView struct {
+	app   *application
+	weeks int
+	// offset moves the period by that many weeks into the past.
+	offset int
+	stats  stats.Stats
+}
+
+func newStatsView(app *application) *statsView {
+	return &statsView{
+		app:   app,
+		weeks: defaultStatsWeeks,
+	}
+}
+
+func (v *statsView) BeforeFocus() error {
+	v.offset = 0
+	v.update()
+	return nil
+}
+
+// period returns the range of the shown weeks.
+func (v *statsView) period(now time.Time) (time.Time, time.Time) {
+	to := database.StartOfWeek(now).AddDate(0, 0, 7*(1-v.offset))
+	return to.AddDate(0, 0, -7*v.weeks), to
+}
+
+func (v *statsView) update() {
+	if v.app.db == nil {
+		return
+	}
+	from, to := v.period(time.Now())
+	v.stats = stats.Calculate(v.app.db, from, to)
+}
+
+func (v *statsView) Actions() []Action {
+	a := v.app
+	return []Action{
+		{Name: "stats.more", Label: "More weeks", Keys: []string{"+"}, Enabled: func() bool { return v.weeks < maxStatsWeeks }, Run: func() error {
+			v.weeks++
+			v.update()
+			return nil
+		}},
+		{Name: "stats.less", Label: "Fewer weeks", Keys: []string{"-"}, Enabled: func() bool { return v.weeks > 1 }, Run: func() error {
+			v.weeks--
+			v.update()
+			return nil
+		}},
+		{Name: "stats.earlier", Label: "Earlier", Keys: []string{"k", "LEFT"}, Run: func() error {
+			v.offset++
+			v.update()
+			return nil
+		}},
+		{Name: "stats.later", Label: "Later", Keys: []string{"j", "RIGHT"}, Enabled: func() bool { return v.offset > 0 }, Run: func() error {
+			v.offset--
+			v.update()
+			return nil
+		}},
+		{Name: "stats.close", Label: "Summary", Keys: []string{"q", "ESC"}, Run: func() error {
+			a.switchMode(summaryMode)
+			return nil
+		}},
+	}
+}
+
+func (v *statsView) Render(area Area) error {
+	a := v.app
+	s := v.stats
+	a.drawHeadline(area.XMin(), area.YMin(), fmt.Sprintf("Statistics for %s - %s", s.From.Format("2 Jan"), s.To.AddDate(0, 0, -1).Format("2 Jan 2006")))
+
+	height := statsChartHeight
+	if max := area.Height - 8; height > max {
+		height = max
+	}
+	y := area.YMin() + 2
+	if height > 0 {
+		v.renderDays(area.XMin(), y, area.Width, height)
+		y += height + 2
+	}
+
+	width := area.Width / 3
+	rows := area.YMax() - y
+	tagRows := rows
+	if len(s.Repositories) > 0 {
+		// The repositories share the first column with the tags.
+		tagRows = rows - rows/3
+		v.renderShares(area.XMin(), y+tagRows, width-1, rows-tagRows, "Repositories", s.Repositories, false)
+	}
+	v.renderShares(area.XMin(), y, width-1, tagRows-1, "Tags", s.Tags, true)
+	v.renderShares(area.XMin()+width, y, width-1, rows, "Tasks", s.Tasks, false)
+	v.renderDay(area.XMin()+2*width, y, rows)
+	return nil
+}
+
+// renderDays draws a bar per day with a small gap between the weeks.
+func (v *statsView) renderDays(x, y, width, height int) {
+	a := v.app
+	end := x + width
+	max := v.stats.MaxDay()
+	axis := database.FormatHours(max)
+	a.drawStyled(x, y, axis, styleLabel)
+	a.drawStyled(x, y+height-1, "0h", styleLabel)
+	x += len(axis) + 1
+	days := len(v.stats.Days)
+	colWidth := 2
+	if x+days*colWidth+days/7 > end {
+		colWidth = 1
+	}
+	for idx, d := range v.stats.Days {
+		col := x + idx*colWidth + idx/7
+		if col >= end {
+			break
+		}
+		style := a.theme.Style(styleHighlight)
+		if target := a.cfg.TargetFor(d.Date); target > 0 && d.Total >= target {
+			style = a.theme.Style(styleSuccess)
+		}
+		for row, ch := range stats.VerticalBar(d.Total, max, height) {
+			termbox.SetCell(col, y+row, ch, style.Fg, style.Bg)
+		}
+		if d.Date.Weekday() == time.Monday {
+			a.drawStyled(col, y+height, d.Date.Format("2 Jan"), styleLabel)
+		}
+	}
+}
+
+// renderShares draws the given tags or tasks with a horizontal bar each.
+// Bars of tags with a color use that color if colored is set.
+func (v *statsView) renderShares(x, y, width, rows int, title string, shares []stats.Share, colored bool) {
+	a := v.app
+	a.drawStyled(x, y, title, styleEmphasis)
+	if len(shares) == 0 {
+		a.drawStyled(x, y+1, "-", styleText)
+		return
+	}
+	nameWidth := width / 3
+	barWidth := width - nameWidth - 8
+	if nameWidth < 3 || barWidth < 1 {
+		return
+	}
+	max := shares[0].Total
+	for idx, sh := range shares {
+		if idx+1 >= rows {
+			break
+		}
+		name := []rune(sh.Name)
+		if len(name) > nameWidth-1 {
+			name = append(name[:nameWidth-2], '…')
+		}
+		row := y + 1 + idx
+		a.drawStyled(x, row, string(name), styleLabel)
+		style := a.theme.Style(styleHighlight)
+		if st, ok := a.tagStyle(sh.Name); ok && colored {
+			style = st
+		}
+		a.drawText(x+nameWidth, row, stats.HorizontalBar(sh.Total, max, barWidth), style.Fg, style.Bg)
+		a.drawStyled(x+nameWidth+barWidth+1, row, database.FormatHours(sh.Total), styleText)
+	}
+}
+
+// renderDay shows the average working day and the longest focus sessions.
+func (v *statsView) renderDay(x, y, rows int) {
+	a := v.app
+	s := v.stats
+	a.drawStyled(x, y, "Average day", styleEmphasis)
+	if s.WorkDays == 0 {
+		a.drawStyled(x, y+1, "-", styleText)
+		return
+	}
+	a.drawStyled(x, y+1, fmt.Sprintf("%s - %s, %s on %d days", formatTimeOfDay(s.AverageStart), formatTimeOfDay(s.AverageEnd), database.FormatHours(s.Total/time.Duration(s.WorkDays)), s.WorkDays), styleText)
+	if rows < 4 {
+		return
+	}
+	a.drawStyled(x, y+3, "Longest sessions", styleEmphasis)
+	for idx, session := range s.Sessions {
+		if idx+4 >= rows {
+			break
+		}
+		row := y + 4 + idx
+		next := a.drawStyled(x, row, database.FormatHours(session.Duration()), styleText)
+		next = a.drawStyled(next+1, row, session.Code, styleLabel)
+		a.drawStyled(next+1, row, session.Start.Format("2 Jan 15:04"), styleText)
+	}
+}
+
+// formatTimeOfDay renders an offset from midnight as time of the day.
+func formatTimeOfDay(d time.Duration) string {
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+func (v *statsView) HandleKeyEvent(evt termbox.Event) error {
+	return nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsViewPeriod(t *testing.T) {
+	v := newStatsView(newApplication())
+	now := time.Date(2018, 3, 14, 15, 0, 0, 0, time.Local)
+	from, to := v.period(now)
+	require.Equal(t, time.Date(2018, 2, 19, 0, 0, 0, 0, time.Local), from)
+	require.Equal(t, time.Date(2018, 3, 19, 0, 0, 0, 0, time.Local), to)
+
+	v.weeks = 1
+	v.offset = 2
+	from, to = v.period(now)
+	require.Equal(t, time.Date(2018, 2, 26, 0, 0, 0, 0, time.Local), from)
+	require.Equal(t, time.Date(2018, 3, 5, 0, 0, 0, 0, time.Local), to)
+
+	require.Equal(t, "08:05", formatTimeOfDay(8*time.Hour+5*time.Minute))
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/status"
+)
+
+func runStatusCommand(env *commandEnv, args []string) error {
+	var output, format string
+	var toggle bool
+	flags := pflag.NewFlagSet("status", pflag.ContinueOnError)
+	flags.StringVar(&output, "output", status.OutputPlain, "Output for plain, waybar, i3blocks or tmux")
+	flags.StringVar(&format, "format", status.DefaultFormat, "Template for the status text")
+	flags.BoolVar(&toggle, "toggle", false, "Clock out of the active task or into the last used one")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	// i3blocks runs the command again with BLOCK_BUTTON set if the block
+	// has been clicked.
+	if output == status.OutputI3Blocks && os.Getenv("BLOCK_BUTTON") == "1" {
+		toggle = true
+	}
+	var s status.Status
+	now := time.Now()
+	if toggle || (env.cfg.Storage != "" && env.cfg.Storage != "folder") {
+		if err := env.load(); err != nil {
+			return err
+		}
+		if toggle {
+			if err := toggleActiveTask(env); err != nil {
+				return err
+			}
+		}
+		s = status.FromDatabase(env.db, now)
+	} else {
+		var err error
+		s, err = status.Read(env.storageFolder, now)
+		if err != nil {
+			return err
+		}
+	}
+	text, err := status.Render(s, format, output)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(env.out, text)
+	return nil
+}
+
+// toggleActiveTask clocks out of the active task. If no task is active, it
+// clocks into the task that has been used last.
+func toggleActiveTask(env *commandEnv) error {
+	if code := env.db.ActiveCode(); code != "" {
+		if err := env.db.ClockOutOf(code); err != nil {
+			return err
+		}
+		return env.snapshot()
+	}
+	tasks, err := env.db.AllTasks()
+	if err != nil {
+		return err
+	}
+	var last *clocked.Task
+	for idx := range tasks {
+		used := tasks[idx].LastUsed()
+		if used == nil {
+			continue
+		}
+		if last == nil || used.After(*last.LastUsed()) {
+			last = &tasks[idx]
+		}
+	}
+	if last == nil {
+		return fmt.Errorf("no task has been used yet")
+	}
+	if err := env.db.ClockInto(last.Code); err != nil {
+		return err
+	}
+	return env.snapshot()
+}
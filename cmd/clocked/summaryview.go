@@ -6,6 +6,7 @@ import (
 
 	"github.com/nsf/termbox-go"
 	"github.com/zerok/clocked/internal/backup"
+	"github.com/zerok/clocked/internal/balance"
 	"github.com/zerok/clocked/internal/database"
 )
 
@@ -17,13 +18,60 @@ type summaryView struct {
 	area    Area
 }
 
-func (v *summaryView) KeyMapping() []KeyMap {
-	return []KeyMap{
-		{Label: "Quit", Key: "^c"},
-		{Label: "Task list", Key: "q/ESC"},
-		{Label: "Later", Key: "j"},
-		{Label: "Earlier", Key: "k"},
-		{Label: "JIRA sync", Key: "^j"},
+func (v *summaryView) Actions() []Action {
+	return []Action{
+		{Name: "summary.close", Label: "Task list", Keys: []string{"q", "ESC"}, Run: func() error {
+			v.app.switchMode(selectionMode)
+			v.date = nil
+			return nil
+		}},
+		{Name: "summary.later", Label: "Later", Keys: []string{"j", "RIGHT"}, Run: func() error {
+			v.moveDate(1)
+			return nil
+		}},
+		{Name: "summary.earlier", Label: "Earlier", Keys: []string{"k", "LEFT"}, Run: func() error {
+			v.moveDate(-1)
+			return nil
+		}},
+		{Name: "summary.jira-sync", Label: "JIRA sync", Keys: []string{"^j"}, Run: func() error {
+			if v.app.jiraClient == nil {
+				v.app.err = fmt.Errorf("JIRA not configured")
+				return nil
+			}
+			if view, ok := v.app.views[syncMode].(*syncView); ok {
+				view.SetSummary(*v.date, v.summary)
+			}
+			v.app.switchMode(syncMode)
+			return nil
+		}},
+		{Name: "summary.timeline", Label: "Timeline", Keys: []string{"t"}, Run: func() error {
+			date := *v.date
+			v.app.switchMode(timelineMode)
+			if view, ok := v.app.views[timelineMode].(*timelineView); ok {
+				view.SetDate(date)
+			}
+			return nil
+		}},
+		{Name: "summary.calendar", Label: "Calendar", Keys: []string{"c"}, Run: func() error {
+			date := *v.date
+			v.app.switchMode(calendarMode)
+			if view, ok := v.app.views[calendarMode].(*calendarView); ok {
+				view.SetDate(date)
+			}
+			return nil
+		}},
+		{Name: "summary.stats", Label: "Statistics", Keys: []string{"s"}, Run: func() error {
+			v.app.switchMode(statsMode)
+			return nil
+		}},
+		{Name: "summary.balance", Label: "Balance", Keys: []string{"b"}, Run: func() error {
+			date := *v.date
+			v.app.switchMode(balanceMode)
+			if view, ok := v.app.views[balanceMode].(*balanceView); ok {
+				view.SetDate(date)
+			}
+			return nil
+		}},
 	}
 }
 
@@ -44,61 +92,76 @@ func (v *summaryView) renderSummary() {
 	area := v.area
 	v.app.drawHeadline(area.XMin(), area.YMin(), fmt.Sprintf("Summary for %s", v.date.Format("Mon, 2 Jan 2006")))
 	for idx, b := range v.summary.Bookings {
-		var color termbox.Attribute
+		var style string
 		switch b.SubmissionStatus {
 		case database.SubmissionStatusOK:
-			color = termbox.ColorGreen
+			style = styleSuccess
 		case database.SubmissionStatusSkipped:
-			color = termbox.ColorYellow
+			style = styleWarning
 		default:
-			color = termbox.ColorDefault
+			style = styleText
 		}
-		v.app.drawText(area.XMin(), area.YMin()+1+idx, fmt.Sprintf("%s - %s (%s)", formatTime(b.Start), formatTime(b.Stop), b.Code), color, termbox.ColorDefault)
+		v.app.drawStyled(area.XMin(), area.YMin()+1+idx, fmt.Sprintf("%s - %s (%s)", formatTime(b.Start), formatTime(b.Stop), b.Code), style)
 	}
 
 	idx := 0
 	for key, dur := range v.summary.Totals {
-		v.app.drawText(area.XMin()+area.Width/2, area.YMin()+1+idx, fmt.Sprintf("%s: %s", key, dur), termbox.ColorDefault, termbox.ColorDefault)
+		v.app.drawStyled(area.XMin()+area.Width/2, area.YMin()+1+idx, fmt.Sprintf("%s: %s", key, dur), styleText)
 		idx++
 	}
 	idx++
-	v.app.drawText(area.XMin()+area.Width/2, area.YMin()+1+idx, "Total: ", termbox.ColorDefault|termbox.AttrBold, termbox.ColorDefault)
-	v.app.drawText(area.XMin()+area.Width/2+7, area.YMin()+1+idx, v.summary.Total.String(), termbox.ColorDefault, termbox.ColorDefault)
+	v.app.drawStyled(area.XMin()+area.Width/2, area.YMin()+1+idx, "Total: ", styleEmphasis)
+	v.app.drawStyled(area.XMin()+area.Width/2+7, area.YMin()+1+idx, v.summary.Total.String(), styleText)
+	idx++
+	v.renderTarget(area.XMin()+area.Width/2, area.YMin()+1+idx)
 }
 
-func (v *summaryView) HandleKeyEvent(evt termbox.Event) error {
-	switch {
-	case evt.Key == termbox.KeyEsc || evt.Ch == 'q':
-		v.app.switchMode(selectionMode)
-		v.date = nil
-	case evt.Key == termbox.KeyCtrlJ:
-		if v.app.jiraClient != nil {
-			if view, ok := v.app.views[syncMode].(*syncView); ok {
-				view.SetSummary(*v.date, v.summary)
-			}
-			v.app.switchMode(syncMode)
-		} else {
-			v.app.err = fmt.Errorf("JIRA not configured")
-		}
-	default:
-		dateDelta := 0
+// renderTarget shows how much time is left until the day's target is
+// reached or how much overtime has already been done.
+func (v *summaryView) renderTarget(xOffset, yOffset int) {
+	cfg := v.app.cfg
+	if cfg.IsDayOff(*v.date) {
+		v.app.drawStyled(xOffset, yOffset, "Day off", styleEmphasis)
+		return
+	}
+	if cfg.TargetFor(*v.date) == 0 {
+		return
+	}
+	from := database.StartOfDay(*v.date)
+	b := balance.Calculate(cfg, v.summary, from, from.AddDate(0, 0, 1), time.Now())
+	v.app.drawStyled(xOffset, yOffset, "Target: ", styleEmphasis)
+	v.app.drawStyled(xOffset+8, yOffset, b.Target.String(), styleText)
+	if b.Overtime() >= 0 {
+		v.app.drawStyled(xOffset, yOffset+1, "Overtime: ", styleEmphasis)
+		v.app.drawStyled(xOffset+10, yOffset+1, formatBalance(b.Overtime()), styleSuccess)
+	} else {
+		v.app.drawStyled(xOffset, yOffset+1, "Remaining: ", styleEmphasis)
+		v.app.drawStyled(xOffset+11, yOffset+1, formatBalance(-b.Remaining()), styleWarning)
+	}
+}
 
-		switch evt.Ch {
-		case 'j':
-			dateDelta = 1
-		case 'k':
-			dateDelta = -1
-		}
+func (v *summaryView) HandleKeyEvent(evt termbox.Event) error {
+	return nil
+}
 
-		if dateDelta != 0 {
-			nextDate := time.Now()
-			if v.date == nil {
-				nextDate = nextDate.AddDate(0, 0, dateDelta)
-			} else {
-				nextDate = v.date.AddDate(0, 0, dateDelta)
-			}
-			v.date = &nextDate
-		}
+// HandleMouseEvent moves between days using the mouse wheel.
+func (v *summaryView) HandleMouseEvent(evt termbox.Event, double bool) error {
+	switch evt.Key {
+	case termbox.MouseWheelUp:
+		v.moveDate(-1)
+	case termbox.MouseWheelDown:
+		v.moveDate(1)
 	}
 	return nil
 }
+
+// moveDate shows the summary of the day delta days after the current one.
+func (v *summaryView) moveDate(delta int) {
+	nextDate := time.Now()
+	if v.date == nil {
+		nextDate = nextDate.AddDate(0, 0, delta)
+	} else {
+		nextDate = v.date.AddDate(0, 0, delta)
+	}
+	v.date = &nextDate
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zerok/clocked/internal/storesync"
+)
+
+func runSyncCommand(env *commandEnv, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: clocked sync <folder>")
+	}
+	if env.cfg.Storage != "" && env.cfg.Storage != "folder" {
+		return fmt.Errorf("sync is only supported for the folder storage format")
+	}
+	remote := args[0]
+	// Only folders are supported. Remote stores have to be mounted or
+	// synced by other tools.
+	if strings.Contains(remote, "://") {
+		return fmt.Errorf("%s is not supported as remote. Please use a local (or mounted) folder", remote)
+	}
+	report, err := storesync.SyncFolders(env.storageFolder, remote, env.log)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(env.out, "Synchronized with %s: %s\n", remote, report)
+	if report.ActiveCode != "" {
+		fmt.Fprintf(env.out, "Active task: %s\n", report.ActiveCode)
+	}
+	return env.snapshot()
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/nsf/termbox-go"
 	"github.com/zerok/clocked/internal/database"
+	"github.com/zerok/clocked/internal/submissions"
 )
 
 type syncView struct {
@@ -24,11 +25,13 @@ func newSyncView(app *application) *syncView {
 	}
 }
 
-func (v *syncView) KeyMapping() []KeyMap {
-	return []KeyMap{
-		{Label: "Quit", Key: "^c"},
-		{Label: "Start", Key: "s"},
-		{Label: "Cancel", Key: "q/ESC"},
+func (v *syncView) Actions() []Action {
+	return []Action{
+		{Name: "sync.start", Label: "Start", Keys: []string{"s"}, Run: v.start},
+		{Name: "sync.cancel", Label: "Cancel", Keys: []string{"q", "ESC"}, Run: func() error {
+			v.app.switchMode(summaryMode)
+			return nil
+		}},
 	}
 }
 
@@ -60,16 +63,16 @@ func (v *syncView) renderListing() {
 	}
 	yOffset := 0
 	for idx, booking := range onlineBookings {
-		v.app.drawText(v.area.XMin(), v.area.YMin()+idx+1, fmt.Sprintf("[%s] %s - %s: %s", v.renderStatus(v.syncStatus[idx], maxStatusLength), formatTime(booking.Start), formatTime(booking.Stop), booking.Code), termbox.ColorDefault, termbox.ColorDefault)
+		v.app.drawStyled(v.area.XMin(), v.area.YMin()+idx+1, fmt.Sprintf("[%s] %s - %s: %s", v.renderStatus(v.syncStatus[idx], maxStatusLength), formatTime(booking.Start), formatTime(booking.Stop), booking.Code), styleText)
 		yOffset = v.area.YMin() + idx + 1
 	}
 
 	if len(offlineBookings) > 0 {
 		yOffset += 2
-		v.app.drawText(v.area.XMin(), yOffset, "Offline bookings:", termbox.ColorDefault|termbox.AttrBold, termbox.ColorDefault)
+		v.app.drawStyled(v.area.XMin(), yOffset, "Offline bookings:", styleEmphasis)
 		yOffset++
 		for _, b := range offlineBookings {
-			v.app.drawText(v.area.XMin(), yOffset, fmt.Sprintf("%s - %s: %s", formatTime(b.Start), formatTime(b.Stop), b.Code), termbox.ColorDefault, termbox.ColorDefault)
+			v.app.drawStyled(v.area.XMin(), yOffset, fmt.Sprintf("%s - %s: %s", formatTime(b.Start), formatTime(b.Stop), b.Code), styleText)
 		}
 	}
 }
@@ -90,26 +93,41 @@ func (v *syncView) filterOfflineBookings(all []database.TaskBooking) ([]database
 }
 
 func (v *syncView) HandleKeyEvent(evt termbox.Event) error {
-	switch {
-	case evt.Ch == 'q' || evt.Key == termbox.KeyEsc:
-		v.app.switchMode(summaryMode)
-	case evt.Ch == 's':
-		termbox.Close()
-		if err := v.app.jiraClient.RemoveDatedWorklogs(context.Background(), v.date); err != nil {
+	return nil
+}
+
+func (v *syncView) start() error {
+	termbox.Close()
+	if err := v.app.jiraClient.RemoveDatedWorklogs(context.Background(), v.date); err != nil {
+		v.app.err = err
+		return err
+	}
+	_, onlineBookings := v.filterOfflineBookings(v.summary.Bookings)
+	status := submissions.StatusOK
+	for idx, b := range onlineBookings {
+		if err := v.app.jiraClient.AddWorklog(context.Background(), b.Code, *b.Start, b.Duration()); err != nil {
 			v.app.err = err
-			return err
-		}
-		_, onlineBookings := v.filterOfflineBookings(v.summary.Bookings)
-		for idx, b := range onlineBookings {
-			if err := v.app.jiraClient.AddWorklog(context.Background(), b.Code, *b.Start, b.Duration()); err != nil {
-				v.app.err = err
-				v.syncStatus[idx] = "error"
-				break
-			}
-			v.syncStatus[idx] = "done"
+			v.syncStatus[idx] = "error"
+			status = submissions.StatusFailed
+			break
 		}
-		termbox.Init()
-		termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
+		v.syncStatus[idx] = "done"
+	}
+	v.app.submissions.Record(v.date, submissions.Submission{Status: status, Submitted: time.Now(), Total: v.summary.Total})
+	if err := v.app.saveSubmissions(); err != nil && v.app.err == nil {
+		v.app.err = err
 	}
+	termbox.Init()
+	v.app.setOutputMode()
+	text := v.app.theme.Style(styleText)
+	termbox.Clear(text.Fg, text.Bg)
 	return nil
 }
+
+// saveSubmissions persists the JIRA submission status of all days.
+func (a *application) saveSubmissions() error {
+	if a.submissionsPath == "" {
+		return nil
+	}
+	return a.submissions.Save(a.submissionsPath)
+}
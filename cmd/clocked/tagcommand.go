@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/pflag"
+	"github.com/zerok/clocked/internal/database"
+	"github.com/zerok/clocked/internal/tags"
+)
+
+func runTagsCommand(env *commandEnv, args []string) error {
+	var from, to string
+	flags := pflag.NewFlagSet("tags", pflag.ContinueOnError)
+	flags.StringVar(&from, "from", "", "First day of the report (default: start of this week)")
+	flags.StringVar(&to, "to", "", "Day after the last day of the report (default: one week after --from)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	start, end, err := teamRange(from, to)
+	if err != nil {
+		return err
+	}
+	infos, err := tags.Collect(env.db, start, end)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		fmt.Fprintf(env.out, "%-30s %5d %s\n", info.Name, info.Tasks, database.FormatHours(info.Total))
+	}
+	return nil
+}
+
+func runTagRenameCommand(env *commandEnv, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: tag-rename <tag>... <new-tag>")
+	}
+	sources := args[:len(args)-1]
+	target := args[len(args)-1]
+	changed, err := tags.Merge(env.db, sources, target)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(env.storageFolder, tags.Filename)
+	colors, err := tags.LoadColors(path)
+	if err != nil {
+		return err
+	}
+	colors.Merge(sources, target)
+	if err := colors.Save(path); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.out, "%d tasks changed\n", changed)
+	if changed == 0 {
+		return nil
+	}
+	return env.snapshot()
+}
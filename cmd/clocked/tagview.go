@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nsf/termbox-go"
+	"github.com/zerok/clocked/internal/database"
+	"github.com/zerok/clocked/internal/form"
+	"github.com/zerok/clocked/internal/tags"
+)
+
+// tagColorCycle are the colors the tag view cycles through.
+var tagColorCycle = []string{"", "red", "green", "yellow", "blue", "magenta", "cyan"}
+
+// tagItem is a single tag in the tag view.
+type tagItem struct {
+	tags.Info
+	week   time.Duration
+	marked bool
+	badges []Badge
+}
+
+func (i tagItem) Label() string {
+	if i.marked {
+		return "* " + i.Name
+	}
+	return "  " + i.Name
+}
+
+func (i tagItem) Columns() []string {
+	tasks := fmt.Sprintf("%d tasks", i.Tasks)
+	if i.Tasks == 1 {
+		tasks = "1 task"
+	}
+	return []string{tasks, formatOptionalHours(i.week), formatOptionalHours(i.Total)}
+}
+
+func (i tagItem) Badges() []Badge {
+	return i.badges
+}
+
+// tagView lists all tags with the number of their tasks and the time booked
+// on them this week and overall. Tags can be renamed, merged by marking
+// several of them before renaming, and assigned a color.
+type tagView struct {
+	app    *application
+	list   *ScrollableList
+	marked map[string]struct{}
+	// form is set while tags are being renamed.
+	form *form.Form
+}
+
+func newTagView(app *application) *tagView {
+	return &tagView{
+		app:    app,
+		list:   newThemedList(app),
+		marked: make(map[string]struct{}),
+	}
+}
+
+func (v *tagView) BeforeFocus() error {
+	v.form = nil
+	v.marked = make(map[string]struct{})
+	if err := v.updateTags(); err != nil {
+		return err
+	}
+	v.list.SelectItemByIndex(0)
+	return nil
+}
+
+func (v *tagView) updateTags() error {
+	now := time.Now()
+	all, err := tags.Collect(v.app.db, time.Time{}, now.AddDate(1, 0, 0))
+	if err != nil {
+		return err
+	}
+	week, err := tags.Collect(v.app.db, database.StartOfWeek(now), database.StartOfWeek(now).AddDate(0, 0, 7))
+	if err != nil {
+		return err
+	}
+	weekTotals := make(map[string]time.Duration)
+	for _, info := range week {
+		weekTotals[info.Name] = info.Total
+	}
+	items := make([]ScrollableListItem, 0, len(all))
+	for _, info := range all {
+		_, marked := v.marked[info.Name]
+		var badges []Badge
+		if style, ok := v.app.tagStyle(info.Name); ok {
+			badges = []Badge{{Text: "■", Style: style}}
+		}
+		items = append(items, tagItem{Info: info, week: weekTotals[info.Name], marked: marked, badges: badges})
+	}
+	v.list.UpdateItems(items)
+	return nil
+}
+
+func (v *tagView) selectedTag() (string, bool) {
+	item, ok := v.list.SelectedItem()
+	if !ok {
+		return "", false
+	}
+	return item.(tagItem).Name, true
+}
+
+// sources returns the marked tags or the selected one if none are marked.
+func (v *tagView) sources() []string {
+	result := make([]string, 0, len(v.marked))
+	for _, item := range v.list.items {
+		if _, found := v.marked[item.(tagItem).Name]; found {
+			result = append(result, item.(tagItem).Name)
+		}
+	}
+	if len(result) == 0 {
+		if tag, ok := v.selectedTag(); ok {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+// TextInputFocused reports if the rename form is shown.
+func (v *tagView) TextInputFocused() bool {
+	return v.form != nil
+}
+
+func (v *tagView) Actions() []Action {
+	a := v.app
+	renaming := func() bool {
+		return v.form != nil
+	}
+	browsing := func() bool {
+		return v.form == nil
+	}
+	selected := func() bool {
+		return v.form == nil && v.list.selectedIndex >= 0
+	}
+	return []Action{
+		{Name: "tags.rename.submit", Label: "Rename", Keys: []string{"ENTER"}, Enabled: renaming, Run: v.rename},
+		{Name: "tags.rename.cancel", Label: "Cancel", Keys: []string{"ESC"}, Enabled: renaming, Run: func() error {
+			v.form = nil
+			return nil
+		}},
+		{Name: "tags.show", Label: "Show tasks", Keys: []string{"ENTER"}, Enabled: selected, Run: v.showTasks},
+		{Name: "tags.mark", Label: "Mark", Keys: []string{"SPACE"}, Enabled: selected, Run: func() error {
+			tag, _ := v.selectedTag()
+			if _, found := v.marked[tag]; found {
+				delete(v.marked, tag)
+			} else {
+				v.marked[tag] = struct{}{}
+			}
+			return v.updateTags()
+		}},
+		{Name: "tags.rename", Label: "Rename/merge", Keys: []string{"r"}, Enabled: selected, Run: func() error {
+			v.form = v.newRenameForm()
+			return nil
+		}},
+		{Name: "tags.color", Label: "Color", Keys: []string{"c"}, Enabled: selected, Run: v.cycleColor},
+		{Name: "tags.down", Label: "Down", Keys: []string{"j", "DOWN"}, Enabled: browsing, Run: func() error {
+			v.list.Next()
+			return nil
+		}},
+		{Name: "tags.up", Label: "Up", Keys: []string{"k", "UP"}, Enabled: browsing, Run: func() error {
+			v.list.Previous()
+			return nil
+		}},
+		{Name: "tags.close", Label: "Tasks", Keys: []string{"q", "ESC"}, Enabled: browsing, Run: func() error {
+			a.switchMode(selectionMode)
+			return nil
+		}},
+	}
+}
+
+func (v *tagView) newRenameForm() *form.Form {
+	sources := v.sources()
+	value := ""
+	if len(sources) == 1 {
+		value = sources[0]
+	}
+	return form.NewForm([]form.Field{
+		{
+			Code:       "name",
+			Label:      "New name:",
+			Value:      value,
+			IsRequired: true,
+			Type:       form.TagsField,
+			Choices:    knownTags(v.app.db),
+			Validators: []form.Validator{
+				form.Pattern(regexp.MustCompile(`^\S+$`), "Tags must not contain whitespace."),
+			},
+		},
+	})
+}
+
+func (v *tagView) rename() error {
+	a := v.app
+	if !v.form.Validate() {
+		return nil
+	}
+	sources := v.sources()
+	target := strings.TrimSpace(v.form.Value("name"))
+	changed, err := tags.Merge(a.db, sources, target)
+	if err != nil {
+		a.err = err
+		return nil
+	}
+	a.snapshot()
+	a.tagColors.Merge(sources, target)
+	if err := a.saveTagColors(); err != nil {
+		a.err = err
+		return nil
+	}
+	v.form = nil
+	v.marked = make(map[string]struct{})
+	a.notice = fmt.Sprintf("Renamed %s to %s on %d tasks", strings.Join(sources, ", "), target, changed)
+	if err := v.updateTags(); err != nil {
+		return err
+	}
+	v.list.SelectMatchingItem(func(i ScrollableListItem) bool {
+		return i.(tagItem).Name == target
+	})
+	return nil
+}
+
+func (v *tagView) cycleColor() error {
+	a := v.app
+	tag, _ := v.selectedTag()
+	next := 0
+	for idx, c := range tagColorCycle {
+		if c == a.tagColors[tag] {
+			next = (idx + 1) % len(tagColorCycle)
+		}
+	}
+	if tagColorCycle[next] == "" {
+		delete(a.tagColors, tag)
+	} else {
+		a.tagColors[tag] = tagColorCycle[next]
+	}
+	if err := a.saveTagColors(); err != nil {
+		a.err = err
+		return nil
+	}
+	return v.updateTags()
+}
+
+// showTasks filters the task list by the selected tag.
+func (v *tagView) showTasks() error {
+	a := v.app
+	tag, _ := v.selectedTag()
+	a.switchMode(selectionMode)
+	if view, ok := a.activeView.(*tasklistView); ok {
+		view.filter.SetValue("tag:" + tag)
+		view.filterChanged()
+	}
+	return nil
+}
+
+func (v *tagView) Render(area Area) error {
+	a := v.app
+	if v.form != nil {
+		a.drawHeadline(area.XMin(), area.YMin(), fmt.Sprintf("Rename %s", strings.Join(v.sources(), ", ")))
+		a.redrawForm(Area{X: area.X, Y: area.Y + 1, Width: area.Width, Height: area.Height - 1}, v.form)
+		return nil
+	}
+	a.drawHeadline(area.XMin(), area.YMin(), "Tags")
+	v.list.UpdateArea(Area{
+		X:      area.X,
+		Y:      area.Y + 2,
+		Width:  area.Width,
+		Height: area.Height - 2,
+	})
+	v.list.Render()
+	return nil
+}
+
+func (v *tagView) HandleKeyEvent(evt termbox.Event) error {
+	if v.form != nil {
+		v.app.handleFieldInput(v.form, evt)
+	}
+	return nil
+}
+
+// HandleMouseEvent selects clicked tags and shows their tasks on
+// double-click.
+func (v *tagView) HandleMouseEvent(evt termbox.Event, double bool) error {
+	if v.form != nil || !v.list.HandleMouseEvent(evt) {
+		return nil
+	}
+	if double {
+		return v.showTasks()
+	}
+	return nil
+}
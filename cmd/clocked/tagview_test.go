@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/database"
+)
+
+func TestTagViewShowTasks(t *testing.T) {
+	app := newApplication()
+	app.db = database.NewInMemory()
+	app.db.AddTask(clocked.Task{Code: "a", Tags: []string{"review"}})
+	app.db.AddTask(clocked.Task{Code: "b", Tags: []string{"meeting"}})
+
+	app.switchMode(tagMode)
+	v := app.activeView.(*tagView)
+	require.Len(t, v.list.items, 2)
+	v.list.Next()
+	tag, ok := v.selectedTag()
+	require.True(t, ok)
+	require.Equal(t, "review", tag)
+
+	require.NoError(t, v.showTasks())
+	list := app.activeView.(*tasklistView)
+	require.Equal(t, "tag:review", list.filter.Value())
+	require.Len(t, list.list.items, 1)
+}
+
+func TestTagViewColors(t *testing.T) {
+	app := newApplication()
+	app.db = database.NewInMemory()
+	app.db.AddTask(clocked.Task{Code: "a", Tags: []string{"review", "x"}})
+
+	app.switchMode(tagMode)
+	v := app.activeView.(*tagView)
+	require.NoError(t, v.cycleColor())
+	require.Equal(t, "red", app.tagColors["review"])
+
+	list := app.views[selectionMode].(*tasklistView)
+	list.updateTaskList()
+	badges := list.list.items[0].(taskItem).Badges()
+	require.Len(t, badges, 1)
+	require.Equal(t, "review", badges[0].Text)
+
+	for range tagColorCycle[1:] {
+		require.NoError(t, v.cycleColor())
+	}
+	require.NotContains(t, app.tagColors, "review")
+}
@@ -3,71 +3,287 @@ package main
 import (
 	"fmt"
 	"sort"
+	"time"
 
 	termbox "github.com/nsf/termbox-go"
 	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/database"
+	"github.com/zerok/clocked/internal/form"
+	"github.com/zerok/clocked/internal/query"
 )
 
+const (
+	sortByCode = iota
+	sortByRecentUse
+	sortByWeekTime
+	sortByTitle
+	numSortOrders
+)
+
+var sortOrderLabels = map[int]string{
+	sortByCode:      "code",
+	sortByRecentUse: "recently used",
+	sortByWeekTime:  "time this week",
+	sortByTitle:     "title",
+}
+
 type tasklistView struct {
 	app                  *application
 	list                 *ScrollableList
 	taskStatusLineHeight int
 	filterLineHeight     int
-	filter               string
+	filter               *form.LineEditor
 	filterFocused        bool
+	filterArea           Area
+	sortOrder            int
 }
 
-func (v *tasklistView) KeyMapping() []KeyMap {
-	if v.filterFocused {
-		return []KeyMap{
-			{Label: "Quit", Key: "^c"},
-			{Label: "Apply", Key: "ENTER"},
-			{Label: "Cancel", Key: "ESC"},
-		}
+func (v *tasklistView) TextInputFocused() bool {
+	return v.filterFocused
+}
+
+func (v *tasklistView) Actions() []Action {
+	a := v.app
+	filtering := func() bool {
+		return v.filterFocused
 	}
-	result := make([]KeyMap, 0, 7)
-	result = append(result, KeyMap{Label: "Quit", Key: "^c"})
-	if v.list.selectedIndex >= 0 {
-		result = append(result, KeyMap{Label: "Clock in/out", Key: "ENTER"})
-		result = append(result, KeyMap{Label: "Edit task", Key: "e"})
+	browsing := func() bool {
+		return !v.filterFocused
 	}
-	result = append(result, KeyMap{Label: "Create task", Key: "n"})
-	result = append(result, KeyMap{Label: "Down", Key: "j"})
-	result = append(result, KeyMap{Label: "Up", Key: "k"})
-	result = append(result, KeyMap{Label: "Filter", Key: "f"})
-	if v.app.backup.Available() {
-		result = append(result, KeyMap{Label: "Backups", Key: "b"})
+	selected := func() bool {
+		return !v.filterFocused && v.list.selectedIndex >= 0
 	}
-	if v.app.db.ActiveCode() != "" {
-		result = append(result, KeyMap{Label: "Jump to active", Key: "^a"})
+	undoer, canUndo := a.db.(database.Undoer)
+	return []Action{
+		{Name: "tasklist.filter.apply", Label: "Apply", Keys: []string{"ENTER"}, Enabled: filtering, Run: func() error {
+			v.filterFocused = false
+			return nil
+		}},
+		{Name: "tasklist.filter.cancel", Label: "Cancel", Keys: []string{"ESC"}, Enabled: filtering, Run: func() error {
+			v.filterFocused = false
+			v.clearFilter()
+			return nil
+		}},
+		{Name: "tasklist.clock", Label: "Clock in/out", Keys: []string{"ENTER"}, Enabled: selected, Run: v.toggleSelectedTask},
+		{Name: "tasklist.edit", Label: "Edit task", Keys: []string{"e"}, Enabled: selected, Run: v.editSelectedTask},
+		{Name: "tasklist.create", Label: "Create task", Keys: []string{"n", "^n"}, Enabled: browsing, Run: func() error {
+			if len(a.cfg.Templates) > 0 {
+				a.switchMode(templateMode)
+			} else {
+				a.switchMode(newTaskMode)
+			}
+			return nil
+		}},
+		{Name: "tasklist.down", Label: "Down", Keys: []string{"j", "DOWN"}, Enabled: browsing, Run: func() error {
+			v.selectNextRow()
+			return nil
+		}},
+		{Name: "tasklist.up", Label: "Up", Keys: []string{"k", "UP"}, Enabled: browsing, Run: func() error {
+			v.selectPreviousRow()
+			return nil
+		}},
+		{Name: "tasklist.filter", Label: "Filter", Keys: []string{"f", "^f"}, Enabled: browsing, Run: func() error {
+			v.filterFocused = true
+			return nil
+		}},
+		{Name: "tasklist.clear-filter", Label: "Clear filter", Keys: []string{"g"}, Enabled: browsing, Hidden: true, Run: func() error {
+			v.clearFilter()
+			return nil
+		}},
+		{Name: "tasklist.tags", Label: "Tags", Keys: []string{"t"}, Enabled: browsing, Run: func() error {
+			a.switchMode(tagMode)
+			return nil
+		}},
+		// Text filters rank the tasks by how well they match.
+		{Name: "tasklist.sort", Label: fmt.Sprintf("Sort (%s)", sortOrderLabels[v.sortOrder]), Keys: []string{"s"}, Enabled: func() bool {
+			return browsing() && !v.ranked()
+		}, Run: func() error {
+			v.cycleSortOrder()
+			return nil
+		}},
+		{Name: "tasklist.backups", Label: "Backups", Keys: []string{"b"}, Enabled: func() bool {
+			return browsing() && a.backup != nil && a.backup.Available()
+		}, Run: func() error {
+			a.switchMode(snapshotsMode)
+			return nil
+		}},
+		{Name: "tasklist.jump-to-active", Label: "Jump to active", Keys: []string{"^a", "a"}, Enabled: func() bool {
+			return browsing() && a.db.ActiveCode() != ""
+		}, Run: func() error {
+			v.clearFilter()
+			v.jumpToActiveTask()
+			return nil
+		}},
+		// Without undo support, the keys only tell the user about it.
+		{Name: "tasklist.undo", Label: "Undo", Keys: []string{"u"}, Hidden: !canUndo, Enabled: func() bool {
+			return browsing() && (!canUndo || undoer.CanUndo())
+		}, Run: func() error {
+			a.undo()
+			v.updateTaskList()
+			return nil
+		}},
+		{Name: "tasklist.redo", Label: "Redo", Keys: []string{"^r"}, Hidden: !canUndo, Enabled: func() bool {
+			return browsing() && (!canUndo || undoer.CanRedo())
+		}, Run: func() error {
+			a.redo()
+			v.updateTaskList()
+			return nil
+		}},
 	}
-	result = append(result, KeyMap{Label: "Daily summary", Key: "^s"})
-	return result
 }
 
 func (v *tasklistView) BeforeFocus() error {
+	v.app.generateRecurringTasks()
 	v.updateTaskList()
 	return nil
 }
 
 func newTasklistView(app *application) *tasklistView {
 	return &tasklistView{
-		app:  app,
-		list: NewScrollableList(Area{}),
+		app:    app,
+		list:   newThemedList(app),
+		filter: form.NewLineEditor(""),
+	}
+}
+
+// taskItem wraps a task for rendering it inside the task list together
+// with some statistics about it.
+type taskItem struct {
+	clocked.Task
+	highlights []int
+	today      time.Duration
+	week       time.Duration
+	lastUsed   *time.Time
+	badges     []Badge
+}
+
+func (i taskItem) Badges() []Badge {
+	return i.badges
+}
+
+func (i taskItem) Highlights() []int {
+	return i.highlights
+}
+
+func (i taskItem) Columns() []string {
+	return []string{
+		formatOptionalHours(i.today),
+		formatOptionalHours(i.week),
+		formatLastUsed(i.lastUsed, time.Now()),
+	}
+}
+
+func formatOptionalHours(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return database.FormatHours(d)
+}
+
+// formatLastUsed renders the given time as short as possible depending on
+// how long ago it was.
+func formatLastUsed(t *time.Time, now time.Time) string {
+	if t == nil {
+		return "never"
+	}
+	switch {
+	case database.SameDay(*t, now):
+		return t.Format("15:04")
+	case now.Sub(*t) < 6*24*time.Hour:
+		return t.Format("Mon")
+	case t.Year() == now.Year():
+		return t.Format("2 Jan")
+	default:
+		return t.Format("Jan 2006")
+	}
+}
+
+// byDuration sorts tasks by the duration assigned to their code in
+// descending order.
+type byDuration struct {
+	tasks  []clocked.Task
+	totals map[string]time.Duration
+}
+
+func (l byDuration) Len() int {
+	return len(l.tasks)
+}
+
+func (l byDuration) Less(i, j int) bool {
+	return l.totals[l.tasks[i].Code] > l.totals[l.tasks[j].Code]
+}
+
+func (l byDuration) Swap(i, j int) {
+	l.tasks[i], l.tasks[j] = l.tasks[j], l.tasks[i]
+}
+
+// withRunning returns the totals of the given summary including the time
+// of running bookings up until now.
+func withRunning(s database.Summary, now time.Time) map[string]time.Duration {
+	totals := make(map[string]time.Duration, len(s.Totals))
+	for code, d := range s.Totals {
+		totals[code] = d
+	}
+	for _, b := range s.Bookings {
+		if b.Start != nil && b.Stop == nil && b.Start.Before(now) {
+			totals[b.Code] += now.Sub(*b.Start)
+		}
 	}
+	return totals
+}
+
+// ranked reports if the tasks are ordered by how well they match the
+// filter instead of the selected sort order.
+func (v *tasklistView) ranked() bool {
+	return query.Parse(v.filter.Value()).Ranked()
 }
 
 func (v *tasklistView) updateTaskList() {
 	a := v.app
-	tasks, _ := a.db.FilteredTasks(v.filter)
-	sort.Sort(clocked.ByCode(tasks))
+	now := time.Now()
+	tasks, _ := a.db.FilteredTasks(v.filter.Value())
+	today := withRunning(a.db.GenerateDailySummary(now), now)
+	week := withRunning(a.db.GenerateSummary(database.StartOfWeek(now), database.StartOfWeek(now).AddDate(0, 0, 7)), now)
+	q := query.Parse(v.filter.Value())
+	if !q.Ranked() {
+		sorted := make([]clocked.Task, len(tasks))
+		copy(sorted, tasks)
+		tasks = sorted
+		sort.Sort(clocked.ByCode(tasks))
+		switch v.sortOrder {
+		case sortByRecentUse:
+			sort.Stable(clocked.ByLastUsed(tasks))
+		case sortByWeekTime:
+			sort.Stable(byDuration{tasks: tasks, totals: week})
+		case sortByTitle:
+			sort.Stable(clocked.ByTitle(tasks))
+		}
+	}
 	items := make([]ScrollableListItem, 0, len(tasks))
 	for _, t := range tasks {
-		items = append(items, t)
+		items = append(items, taskItem{
+			Task:       t,
+			highlights: q.Highlights(t.Label()),
+			today:      today[t.Code],
+			week:       week[t.Code],
+			lastUsed:   t.LastUsed(),
+			badges:     a.tagBadges(t.Tags),
+		})
 	}
 	v.list.UpdateItems(items)
 }
 
+// cycleSortOrder switches to the next sort order and keeps the selected
+// task selected.
+func (v *tasklistView) cycleSortOrder() {
+	v.sortOrder = (v.sortOrder + 1) % numSortOrders
+	selected, ok := v.list.SelectedItem()
+	v.updateTaskList()
+	if ok {
+		v.list.SelectMatchingItem(selectByCode(selected.(taskItem).Code))
+	}
+}
+
 func (v *tasklistView) Render(area Area) error {
 	if err := v.renderFilter(area); err != nil {
 		return err
@@ -110,8 +326,8 @@ func (v *tasklistView) renderActiveTask(area Area) error {
 	}
 	v.app.drawLine(yOffset)
 	xOffset = v.app.drawLabel(xOffset, yOffset+1, "Active task: ", false)
-	xOffset = v.app.drawText(xOffset, yOffset+1, v.app.db.ActiveCode(), termbox.AttrBold|termbox.ColorGreen, termbox.ColorDefault)
-	v.app.drawText(xOffset+1, yOffset+1, fmt.Sprintf("(%s)", task.Title), termbox.ColorWhite, termbox.ColorDefault)
+	xOffset = v.app.drawStyled(xOffset, yOffset+1, v.app.db.ActiveCode(), styleActive)
+	v.app.drawStyled(xOffset+1, yOffset+1, fmt.Sprintf("(%s)", task.Title), styleLabel)
 	v.taskStatusLineHeight = 2
 	return nil
 }
@@ -120,11 +336,9 @@ func (v *tasklistView) renderFilter(area Area) error {
 	a := v.app
 	yOffset := area.YMax()
 	a.drawLine(yOffset - 1)
+	v.filterArea = Area{X: area.XMin(), Y: yOffset, Width: area.Width, Height: 1}
 	xOffset := a.drawLabel(area.XMin(), yOffset, "Search:", v.filterFocused)
-	a.drawFieldValue(xOffset+1, area.XMax(), yOffset, v.filter, v.filterFocused)
-	if v.filterFocused {
-		termbox.SetCursor(xOffset+len(v.filter)+2, yOffset)
-	}
+	a.drawEditor(xOffset+1, area.XMax(), yOffset, v.filter, v.filterFocused)
 	v.filterLineHeight = 2
 	return nil
 }
@@ -147,22 +361,17 @@ func (v *tasklistView) renderList() error {
 }
 
 func (v *tasklistView) pushFilter(c rune) {
-	v.filter = appendRune(v.filter, c)
-	v.updateTaskList()
-	v.selectFirstRow()
+	v.filter.Insert(string(c))
+	v.filterChanged()
 }
 
 func (v *tasklistView) clearFilter() {
-	v.filter = ""
-	v.updateTaskList()
-	v.selectFirstRow()
+	v.filter.SetValue("")
+	v.filterChanged()
 }
 
-func (v *tasklistView) popFilter() {
-	if len(v.filter) == 0 {
-		return
-	}
-	v.filter = v.filter[0 : len(v.filter)-1]
+// filterChanged updates the list once the filter has been edited.
+func (v *tasklistView) filterChanged() {
 	v.updateTaskList()
 	v.selectFirstRow()
 }
@@ -176,69 +385,71 @@ func (v *tasklistView) jumpToActiveTask() bool {
 	return ok
 }
 
+// HandleKeyEvent passes all keys not bound to an action to the filter if it
+// is focused.
 func (v *tasklistView) HandleKeyEvent(evt termbox.Event) error {
-	a := v.app
-	switch {
-	case v.filterFocused && evt.Key == termbox.KeyEsc:
-		v.filterFocused = false
-		v.clearFilter()
-	case v.filterFocused && evt.Key == termbox.KeyEnter:
-		v.filterFocused = false
-	case v.filterFocused && (evt.Key == termbox.KeyBackspace || evt.Key == termbox.KeyBackspace2):
-		v.popFilter()
-	case v.filterFocused:
-		v.pushFilter(evt.Ch)
-	case evt.Key == termbox.KeyCtrlN || evt.Ch == 'n':
-		a.switchMode(newTaskMode)
-	case evt.Key == termbox.KeyCtrlA || evt.Ch == 'a':
-		v.clearFilter()
-		v.jumpToActiveTask()
-	case v.app.backup.Available() && evt.Ch == 'b':
-		v.app.switchMode(snapshotsMode)
-	case evt.Ch == 'g':
-		v.clearFilter()
-	case evt.Key == termbox.KeyCtrlF || evt.Ch == 'f':
+	if !v.filterFocused {
+		return nil
+	}
+	previous := v.filter.Value()
+	if v.filter.HandleEvent(evt) && v.filter.Value() != previous {
+		v.filterChanged()
+	}
+	return nil
+}
+
+// HandleMouseEvent selects clicked tasks and clocks in or out on
+// double-click. Clicking the search line focuses the filter.
+func (v *tasklistView) HandleMouseEvent(evt termbox.Event, double bool) error {
+	if evt.Key == termbox.MouseLeft && v.filterArea.Contains(evt.MouseX, evt.MouseY) {
 		v.filterFocused = true
-	case evt.Key == termbox.KeyArrowDown || evt.Ch == 'j':
-		v.selectNextRow()
-	case evt.Key == termbox.KeyArrowUp || evt.Ch == 'k':
-		v.selectPreviousRow()
-	case evt.Ch == 'e':
-		selectedItem, selected := v.list.SelectedItem()
-		if !selected {
-			return nil
-		}
-		selectedTask := selectedItem.(clocked.Task)
-		a.switchMode(editTaskMode)
-		a.selectTask(selectedTask)
-	case evt.Key == termbox.KeyEnter:
-		selectedItem, selected := v.list.SelectedItem()
-		if !selected {
-			return nil
-		}
-		selectedTask := selectedItem.(clocked.Task)
-		if a.db.ActiveCode() == selectedTask.Code {
-			if err := a.db.ClockOutOf(a.db.ActiveCode()); err != nil {
-				a.err = err
-			} else if a.backup != nil {
-				if err := a.backup.CreateSnapshot(); err != nil {
-					a.fatalError(err, "failed to create snapshot")
-				}
-			}
-			return nil
-		}
-		if err := a.db.ClockInto(selectedTask.Code); err != nil {
+		return nil
+	}
+	if !v.list.HandleMouseEvent(evt) {
+		return nil
+	}
+	v.filterFocused = false
+	if double {
+		return v.toggleSelectedTask()
+	}
+	return nil
+}
+
+func (v *tasklistView) editSelectedTask() error {
+	a := v.app
+	selectedItem, selected := v.list.SelectedItem()
+	if !selected {
+		return nil
+	}
+	selectedTask := selectedItem.(taskItem).Task
+	a.switchMode(editTaskMode)
+	a.selectTask(selectedTask)
+	return nil
+}
+
+// toggleSelectedTask clocks out of the selected task if it is active or
+// into it otherwise.
+func (v *tasklistView) toggleSelectedTask() error {
+	a := v.app
+	selectedItem, selected := v.list.SelectedItem()
+	if !selected {
+		return nil
+	}
+	selectedTask := selectedItem.(taskItem).Task
+	if a.db.ActiveCode() == selectedTask.Code {
+		if err := a.db.ClockOutOf(a.db.ActiveCode()); err != nil {
 			a.err = err
-			return nil
+		} else {
+			a.snapshot()
 		}
-		if a.backup != nil {
-			if err := a.backup.CreateSnapshot(); err != nil {
-				a.fatalError(err, "failed to create snapshot")
-			}
-		}
-		v.clearFilter()
-		v.jumpToActiveTask()
-	default:
+		return nil
+	}
+	if err := a.db.ClockInto(selectedTask.Code); err != nil {
+		a.err = err
+		return nil
 	}
+	a.snapshot()
+	v.clearFilter()
+	v.jumpToActiveTask()
 	return nil
 }
@@ -2,7 +2,9 @@ package main
 
 import (
 	"testing"
+	"time"
 
+	termbox "github.com/nsf/termbox-go"
 	"github.com/stretchr/testify/require"
 	"github.com/zerok/clocked"
 	"github.com/zerok/clocked/internal/database"
@@ -47,3 +49,129 @@ func TestFiltering(t *testing.T) {
 	selected, ok = v.list.SelectedItem()
 	require.False(t, ok, "Since there is no matching item, nothing should be selected")
 }
+
+func TestSortByRecentUse(t *testing.T) {
+	app := newApplication()
+	app.db = database.NewInMemory()
+	app.db.AddTask(clocked.Task{Code: "a"})
+	app.db.AddTask(clocked.Task{Code: "b"})
+	app.db.AddTask(clocked.Task{Code: "c"})
+	require.NoError(t, app.db.ClockInto("b"))
+
+	v := newTasklistView(app)
+	v.updateTaskList()
+	v.selectFirstRow()
+	selected, _ := v.list.SelectedItem()
+	require.Equal(t, "a ", selected.Label(), "tasks should be sorted by code by default")
+
+	v.cycleSortOrder()
+	require.Equal(t, sortByRecentUse, v.sortOrder)
+	selected, _ = v.list.SelectedItem()
+	require.Equal(t, "a ", selected.Label(), "the selection should be kept when the sort order changes")
+	v.selectFirstRow()
+	selected, _ = v.list.SelectedItem()
+	require.Equal(t, "b ", selected.Label(), "the recently used task should be listed first")
+}
+
+func TestDoubleClickClocksIn(t *testing.T) {
+	app := newApplication()
+	app.db = database.NewInMemory()
+	app.db.AddTask(clocked.Task{Code: "a"})
+	app.db.AddTask(clocked.Task{Code: "b"})
+	v := newTasklistView(app)
+	app.views[selectionMode] = v
+	app.activeView = v
+	app.mode = selectionMode
+	v.updateTaskList()
+	v.list.UpdateArea(Area{Width: 20, Height: 5})
+
+	click := termbox.Event{Type: termbox.EventMouse, Key: termbox.MouseLeft, MouseX: 4, MouseY: 1}
+	now := time.Now()
+	require.True(t, app.handleMouse(click, now))
+	selected, ok := v.list.SelectedItem()
+	require.True(t, ok)
+	require.Equal(t, "b", selected.(taskItem).Code)
+	require.Equal(t, "", app.db.ActiveCode(), "a single click should only select")
+
+	require.True(t, app.handleMouse(click, now.Add(2*doubleClickInterval)))
+	require.Equal(t, "", app.db.ActiveCode(), "clicks too far apart are no double-click")
+	require.True(t, app.handleMouse(click, now.Add(2*doubleClickInterval+100*time.Millisecond)))
+	require.Equal(t, "b", app.db.ActiveCode())
+}
+
+func TestClickOnKeyHint(t *testing.T) {
+	app := newApplication()
+	app.db = database.NewInMemory()
+	v := newTasklistView(app)
+	app.activeView = v
+	app.mode = selectionMode
+	app.hints = []keyHint{{area: Area{X: 10, Y: 9, Width: 8, Height: 1}, action: "tasklist.filter"}}
+
+	require.True(t, app.handleMouse(termbox.Event{Key: termbox.MouseLeft, MouseX: 12, MouseY: 9}, time.Now()))
+	require.True(t, v.filterFocused)
+}
+
+func TestWheelScrollSurvivesRender(t *testing.T) {
+	app := newApplication()
+	app.db = database.NewInMemory()
+	for _, code := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		app.db.AddTask(clocked.Task{Code: code})
+	}
+	v := newTasklistView(app)
+	v.updateTaskList()
+	v.selectFirstRow()
+	area := Area{Width: 40, Height: 8}
+	require.NoError(t, v.Render(area))
+	require.Equal(t, 0, v.list.offset)
+
+	wheel := termbox.Event{Type: termbox.EventMouse, Key: termbox.MouseWheelDown}
+	require.NoError(t, v.HandleMouseEvent(wheel, false))
+	require.NoError(t, v.HandleMouseEvent(wheel, false))
+	require.Equal(t, 2, v.list.offset)
+	require.NoError(t, v.Render(area))
+	require.Equal(t, 2, v.list.offset, "rendering should keep the scroll position")
+}
+
+func TestUndoWithoutJournal(t *testing.T) {
+	app := newApplication()
+	app.db = database.NewInMemory()
+	app.switchMode(selectionMode)
+
+	require.True(t, app.handleKey(termbox.Event{Type: termbox.EventKey, Ch: 'u'}))
+	require.Error(t, app.err, "the user should be told that undo isn't available")
+}
+
+func TestSortFilteredTasks(t *testing.T) {
+	app := newApplication()
+	app.db = database.NewInMemory()
+	app.db.AddTask(clocked.Task{Code: "a", Title: "Zeta", Tags: []string{"x"}})
+	app.db.AddTask(clocked.Task{Code: "b", Title: "Alpha", Tags: []string{"x"}})
+	app.db.AddTask(clocked.Task{Code: "c", Title: "Beta"})
+
+	v := newTasklistView(app)
+	v.sortOrder = sortByTitle
+	for _, r := range "tag:x" {
+		v.pushFilter(r)
+	}
+	require.Len(t, v.list.items, 2)
+	require.Equal(t, "b", v.list.items[0].(taskItem).Code, "structured filters keep the sort order")
+	require.False(t, v.ranked())
+
+	v.pushFilter(' ')
+	v.pushFilter('z')
+	require.True(t, v.ranked(), "text terms rank the tasks instead")
+}
+
+func TestTotalsIncludeRunningBooking(t *testing.T) {
+	app := newApplication()
+	app.db = database.NewInMemory()
+	app.db.AddTask(clocked.Task{Code: "a", Bookings: []clocked.Booking{
+		booking(time.Now().Add(-time.Second), time.Time{}),
+	}})
+
+	v := newTasklistView(app)
+	v.updateTaskList()
+	item := v.list.items[0].(taskItem)
+	require.True(t, item.today >= time.Second)
+	require.True(t, item.week >= time.Second)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/zerok/clocked/internal/database"
+	"github.com/zerok/clocked/internal/team"
+)
+
+func runTeamServerCommand(env *commandEnv, args []string) error {
+	var listen, data, users string
+	flags := pflag.NewFlagSet("team-server", pflag.ContinueOnError)
+	flags.StringVar(&listen, "listen", "127.0.0.1:8426", "Address the team server should be served on")
+	flags.StringVar(&data, "data", "", "File the pushed entries are stored in (default: kept in memory)")
+	flags.StringVar(&users, "users", "", "YAML file with the name and token of every team member")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if users == "" {
+		return fmt.Errorf("--users is required")
+	}
+	members, err := team.LoadUsers(users)
+	if err != nil {
+		return fmt.Errorf("failed to load users from %s: %s", users, err.Error())
+	}
+	store, err := team.NewStore(data)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %s", data, err.Error())
+	}
+	fmt.Fprintf(env.out, "Serving the team server for %d users on http://%s/api/\n", len(members), listen)
+	return newHTTPServer(listen, team.NewServer(store, members)).ListenAndServe()
+}
+
+// teamRange parses the --from and --to flags of the team commands. Both are
+// dates and to is exclusive. Without flags the current week is used.
+func teamRange(from, to string) (time.Time, time.Time, error) {
+	start := database.StartOfWeek(time.Now())
+	if from != "" {
+		t, err := time.ParseInLocation(database.DayFormat, from, time.Local)
+		if err != nil {
+			return start, start, err
+		}
+		start = t
+	}
+	end := start.AddDate(0, 0, 7)
+	if to != "" {
+		t, err := time.ParseInLocation(database.DayFormat, to, time.Local)
+		if err != nil {
+			return start, end, err
+		}
+		end = t
+	}
+	if !end.After(start) {
+		return start, end, fmt.Errorf("--to has to be after --from")
+	}
+	return start, end, nil
+}
+
+func teamClient(env *commandEnv) (*team.Client, error) {
+	if env.cfg.TeamURL == "" || env.cfg.TeamToken == "" {
+		return nil, fmt.Errorf("please set team_url and team_token in your config file")
+	}
+	return team.NewClient(env.cfg.TeamURL, env.cfg.TeamToken), nil
+}
+
+func runTeamPushCommand(env *commandEnv, args []string) error {
+	var from, to string
+	flags := pflag.NewFlagSet("team-push", pflag.ContinueOnError)
+	flags.StringVar(&from, "from", "", "First day to push (default: start of this week)")
+	flags.StringVar(&to, "to", "", "Day after the last day to push (default: one week after --from)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	start, end, err := teamRange(from, to)
+	if err != nil {
+		return err
+	}
+	client, err := teamClient(env)
+	if err != nil {
+		return err
+	}
+	entries := team.EntriesFromSummary(env.db, env.db.GenerateSummary(start, end))
+	if err := client.Push(start, end, entries); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.out, "Pushed %d entries from %s to %s\n", len(entries), start.Format(database.DayFormat), end.Format(database.DayFormat))
+	return nil
+}
+
+func runTeamReportCommand(env *commandEnv, args []string) error {
+	var from, to, group string
+	flags := pflag.NewFlagSet("team-report", pflag.ContinueOnError)
+	flags.StringVar(&from, "from", "", "First day of the report (default: start of this week)")
+	flags.StringVar(&to, "to", "", "Day after the last day of the report (default: one week after --from)")
+	flags.StringVar(&group, "group", team.GroupByTask, "Group the report by task, tag or person")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	start, end, err := teamRange(from, to)
+	if err != nil {
+		return err
+	}
+	client, err := teamClient(env)
+	if err != nil {
+		return err
+	}
+	rows, err := client.Report(start, end, group)
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, r := range rows {
+		key := r.Key
+		if key == "" {
+			key = "(none)"
+		}
+		fmt.Fprintf(env.out, "%-30s %s\n", key, database.FormatHours(time.Duration(r.Seconds)*time.Second))
+		total += r.Seconds
+	}
+	fmt.Fprintf(env.out, "%-30s %s\n", "Total", database.FormatHours(time.Duration(total)*time.Second))
+	return nil
+}
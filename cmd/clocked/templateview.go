@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nsf/termbox-go"
+	"github.com/zerok/clocked/internal/config"
+)
+
+// templateItem is an entry of the template selection. If tmpl is nil, an
+// empty task should be created.
+type templateItem struct {
+	tmpl *config.TaskTemplate
+}
+
+func (i templateItem) Label() string {
+	if i.tmpl == nil {
+		return "Empty task"
+	}
+	return fmt.Sprintf("%s (%s)", i.tmpl.Name, i.tmpl.Code)
+}
+
+// templateView lets the user pick a template before creating a new task.
+type templateView struct {
+	app  *application
+	list *ScrollableList
+}
+
+func newTemplateView(app *application) *templateView {
+	return &templateView{
+		app:  app,
+		list: newThemedList(app),
+	}
+}
+
+func (v *templateView) BeforeFocus() error {
+	items := make([]ScrollableListItem, 0, len(v.app.cfg.Templates)+1)
+	items = append(items, templateItem{})
+	for idx := range v.app.cfg.Templates {
+		items = append(items, templateItem{tmpl: &v.app.cfg.Templates[idx]})
+	}
+	v.list.UpdateItems(items)
+	v.list.SelectItemByIndex(0)
+	return nil
+}
+
+func (v *templateView) Render(area Area) error {
+	v.app.drawHeadline(area.XMin(), area.YMin(), "Create task from template")
+	v.list.UpdateArea(Area{
+		X:      area.X,
+		Y:      area.Y + 2,
+		Width:  area.Width,
+		Height: area.Height - 2,
+	})
+	v.list.Render()
+	return nil
+}
+
+func (v *templateView) Actions() []Action {
+	return []Action{
+		{Name: "templates.select", Label: "Select", Keys: []string{"ENTER"}, Run: v.selectTemplate},
+		{Name: "templates.next", Label: "Next", Keys: []string{"j", "DOWN"}, Run: func() error {
+			v.list.Next()
+			return nil
+		}},
+		{Name: "templates.previous", Label: "Previous", Keys: []string{"k", "UP"}, Run: func() error {
+			v.list.Previous()
+			return nil
+		}},
+		{Name: "templates.cancel", Label: "Cancel", Keys: []string{"q", "ESC"}, Run: func() error {
+			return ErrCloseView
+		}},
+	}
+}
+
+func (v *templateView) selectTemplate() error {
+	item, ok := v.list.SelectedItem()
+	if !ok {
+		return nil
+	}
+	v.app.switchMode(newTaskMode)
+	if tmpl := item.(templateItem).tmpl; tmpl != nil {
+		if view, ok := v.app.activeView.(*createTaskView); ok {
+			view.SetTemplate(*tmpl, time.Now())
+		}
+	}
+	return nil
+}
+
+// HandleMouseEvent selects the clicked template and uses it on
+// double-click.
+func (v *templateView) HandleMouseEvent(evt termbox.Event, double bool) error {
+	if v.list.HandleMouseEvent(evt) && double {
+		return v.selectTemplate()
+	}
+	return nil
+}
+
+func (v *templateView) HandleKeyEvent(evt termbox.Event) error {
+	return nil
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	termbox "github.com/nsf/termbox-go"
+	"gopkg.in/yaml.v2"
+)
+
+// ThemeFilename is the name of the file inside the store that selects and
+// customizes the theme.
+const ThemeFilename = "theme.yml"
+
+// Names of the styles a theme has to provide.
+const (
+	styleText       = "text"
+	styleHeadline   = "headline"
+	styleLabel      = "label"
+	styleInput      = "input"
+	styleError      = "error"
+	styleNotice     = "notice"
+	styleActive     = "active"
+	styleSelected   = "selected"
+	styleHighlight  = "highlight"
+	styleKeyHint    = "keyhint"
+	styleKeyLabel   = "keylabel"
+	styleSeparator  = "separator"
+	styleEmphasis   = "emphasis"
+	styleSuccess    = "success"
+	styleWarning    = "warning"
+	styleSuggestion = "suggestion"
+)
+
+// Style is the appearance of a piece of text.
+type Style struct {
+	Fg termbox.Attribute
+	Bg termbox.Attribute
+}
+
+// Theme assigns a style to every named element of the interface.
+type Theme struct {
+	Name   string
+	Styles map[string]Style
+	// Uses256Colors is set if the theme requires the 256 color mode.
+	Uses256Colors bool
+}
+
+// Style returns the style with the given name. Unknown styles use the
+// terminal's default colors.
+func (t *Theme) Style(name string) Style {
+	if t == nil {
+		return builtinThemes["dark"].Style(name)
+	}
+	if s, ok := t.Styles[name]; ok {
+		return s
+	}
+	return Style{Fg: termbox.ColorDefault, Bg: termbox.ColorDefault}
+}
+
+func (t *Theme) copy() *Theme {
+	c := &Theme{Name: t.Name, Uses256Colors: t.Uses256Colors, Styles: make(map[string]Style, len(t.Styles))}
+	for k, v := range t.Styles {
+		c.Styles[k] = v
+	}
+	return c
+}
+
+const bold = termbox.AttrBold
+
+var builtinThemes = map[string]*Theme{
+	"dark": {
+		Name: "dark",
+		Styles: map[string]Style{
+			styleText:       {termbox.ColorDefault, termbox.ColorDefault},
+			styleHeadline:   {termbox.ColorBlue | bold, termbox.ColorDefault},
+			styleLabel:      {termbox.ColorWhite, termbox.ColorDefault},
+			styleInput:      {termbox.ColorWhite, termbox.ColorBlack},
+			styleSuggestion: {termbox.ColorCyan, termbox.ColorBlack},
+			styleError:      {termbox.ColorRed, termbox.ColorDefault},
+			styleNotice:     {termbox.ColorGreen, termbox.ColorDefault},
+			styleActive:     {termbox.ColorGreen | bold, termbox.ColorDefault},
+			styleSelected:   {termbox.ColorDefault | bold, termbox.ColorDefault},
+			styleHighlight:  {termbox.ColorYellow | bold, termbox.ColorDefault},
+			styleKeyHint:    {termbox.ColorWhite | bold, termbox.ColorBlack},
+			styleKeyLabel:   {termbox.ColorWhite, termbox.ColorBlack},
+			styleSeparator:  {termbox.ColorBlue, termbox.ColorDefault},
+			styleEmphasis:   {termbox.ColorDefault | bold, termbox.ColorDefault},
+			styleSuccess:    {termbox.ColorGreen, termbox.ColorDefault},
+			styleWarning:    {termbox.ColorYellow, termbox.ColorDefault},
+		},
+	},
+	"light": {
+		Name: "light",
+		Styles: map[string]Style{
+			styleText:       {termbox.ColorDefault, termbox.ColorDefault},
+			styleHeadline:   {termbox.ColorBlue | bold, termbox.ColorDefault},
+			styleLabel:      {termbox.ColorBlack, termbox.ColorDefault},
+			styleInput:      {termbox.ColorBlack, termbox.ColorWhite},
+			styleSuggestion: {termbox.ColorCyan, termbox.ColorWhite},
+			styleError:      {termbox.ColorRed | bold, termbox.ColorDefault},
+			styleNotice:     {termbox.ColorGreen | bold, termbox.ColorDefault},
+			styleActive:     {termbox.ColorGreen | bold, termbox.ColorDefault},
+			styleSelected:   {termbox.ColorDefault | bold, termbox.ColorDefault},
+			styleHighlight:  {termbox.ColorMagenta | bold, termbox.ColorDefault},
+			styleKeyHint:    {termbox.ColorBlack | bold, termbox.ColorWhite},
+			styleKeyLabel:   {termbox.ColorBlack, termbox.ColorWhite},
+			styleSeparator:  {termbox.ColorBlue, termbox.ColorDefault},
+			styleEmphasis:   {termbox.ColorDefault | bold, termbox.ColorDefault},
+			styleSuccess:    {termbox.ColorGreen, termbox.ColorDefault},
+			styleWarning:    {termbox.ColorRed, termbox.ColorDefault},
+		},
+	},
+	"mono": {
+		Name: "mono",
+		Styles: map[string]Style{
+			styleText:       {termbox.ColorDefault, termbox.ColorDefault},
+			styleHeadline:   {termbox.ColorDefault | bold | termbox.AttrUnderline, termbox.ColorDefault},
+			styleLabel:      {termbox.ColorDefault, termbox.ColorDefault},
+			styleInput:      {termbox.ColorDefault | termbox.AttrUnderline, termbox.ColorDefault},
+			styleSuggestion: {termbox.ColorDefault, termbox.ColorDefault},
+			styleError:      {termbox.ColorDefault | bold, termbox.ColorDefault},
+			styleNotice:     {termbox.ColorDefault, termbox.ColorDefault},
+			styleActive:     {termbox.ColorDefault | bold, termbox.ColorDefault},
+			styleSelected:   {termbox.ColorDefault | bold, termbox.ColorDefault},
+			styleHighlight:  {termbox.ColorDefault | termbox.AttrUnderline, termbox.ColorDefault},
+			styleKeyHint:    {termbox.ColorDefault | bold | termbox.AttrReverse, termbox.ColorDefault},
+			styleKeyLabel:   {termbox.ColorDefault | termbox.AttrReverse, termbox.ColorDefault},
+			styleSeparator:  {termbox.ColorDefault, termbox.ColorDefault},
+			styleEmphasis:   {termbox.ColorDefault | bold, termbox.ColorDefault},
+			styleSuccess:    {termbox.ColorDefault, termbox.ColorDefault},
+			styleWarning:    {termbox.ColorDefault | bold, termbox.ColorDefault},
+		},
+	},
+	"dark256": {
+		Name:          "dark256",
+		Uses256Colors: true,
+		Styles: map[string]Style{
+			styleText:       {termbox.ColorDefault, termbox.ColorDefault},
+			styleHeadline:   {color256(75) | bold, termbox.ColorDefault},
+			styleLabel:      {color256(250), termbox.ColorDefault},
+			styleInput:      {color256(255), color256(236)},
+			styleSuggestion: {color256(244), color256(236)},
+			styleError:      {color256(203), termbox.ColorDefault},
+			styleNotice:     {color256(114), termbox.ColorDefault},
+			styleActive:     {color256(114) | bold, termbox.ColorDefault},
+			styleSelected:   {color256(215) | bold, termbox.ColorDefault},
+			styleHighlight:  {color256(221) | bold, termbox.ColorDefault},
+			styleKeyHint:    {color256(255) | bold, color256(238)},
+			styleKeyLabel:   {color256(250), color256(238)},
+			styleSeparator:  {color256(60), termbox.ColorDefault},
+			styleEmphasis:   {termbox.ColorDefault | bold, termbox.ColorDefault},
+			styleSuccess:    {color256(114), termbox.ColorDefault},
+			styleWarning:    {color256(221), termbox.ColorDefault},
+		},
+	},
+}
+
+// tagStyle returns the style of the given tag if a color has been assigned
+// to it.
+func (a *application) tagStyle(tag string) (Style, bool) {
+	name, ok := a.tagColors[tag]
+	if !ok {
+		return Style{}, false
+	}
+	c, _, err := parseColor(name)
+	if err != nil {
+		return Style{}, false
+	}
+	return Style{Fg: c, Bg: a.theme.Style(styleText).Bg}, true
+}
+
+// tagBadges returns a badge for every tag with a color.
+func (a *application) tagBadges(tagNames []string) []Badge {
+	var result []Badge
+	for _, t := range tagNames {
+		if style, ok := a.tagStyle(t); ok {
+			result = append(result, Badge{Text: t, Style: style})
+		}
+	}
+	return result
+}
+
+func (a *application) saveTagColors() error {
+	if a.tagColorsPath == "" {
+		return nil
+	}
+	return a.tagColors.Save(a.tagColorsPath)
+}
+
+// setOutputMode switches to the 256 color mode if either the theme or
+// any tag color requires it.
+func (a *application) setOutputMode() {
+	uses256 := a.theme.Uses256Colors
+	for _, name := range a.tagColors {
+		if _, extended, err := parseColor(name); err == nil && extended {
+			uses256 = true
+		}
+	}
+	if uses256 {
+		termbox.SetOutputMode(termbox.Output256)
+	}
+}
+
+// color256 returns the attribute of the given color of the 256 color
+// palette. termbox reserves 0 for the default color.
+func color256(n int) termbox.Attribute {
+	return termbox.Attribute(n + 1)
+}
+
+var colorNames = map[string]termbox.Attribute{
+	"default": termbox.ColorDefault,
+	"black":   termbox.ColorBlack,
+	"red":     termbox.ColorRed,
+	"green":   termbox.ColorGreen,
+	"yellow":  termbox.ColorYellow,
+	"blue":    termbox.ColorBlue,
+	"magenta": termbox.ColorMagenta,
+	"cyan":    termbox.ColorCyan,
+	"white":   termbox.ColorWhite,
+}
+
+var attributeNames = map[string]termbox.Attribute{
+	"bold":      termbox.AttrBold,
+	"underline": termbox.AttrUnderline,
+	"reverse":   termbox.AttrReverse,
+}
+
+// parseColor understands color names and numbers of the 256 color palette.
+// The latter are reported by the second return value.
+func parseColor(s string) (termbox.Attribute, bool, error) {
+	if s == "" {
+		return termbox.ColorDefault, false, nil
+	}
+	if c, ok := colorNames[strings.ToLower(s)]; ok {
+		return c, false, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > 255 {
+		return 0, false, fmt.Errorf("unknown color %s", s)
+	}
+	return color256(n), n > 7, nil
+}
+
+type styleConfig struct {
+	Fg    string   `yaml:"fg"`
+	Bg    string   `yaml:"bg"`
+	Attrs []string `yaml:"attrs"`
+}
+
+type themeConfig struct {
+	Base   string                 `yaml:"base"`
+	Styles map[string]styleConfig `yaml:"styles"`
+}
+
+// LoadTheme reads the theme file at the given path. It selects one of the
+// built-in themes as base and overrides single styles of it. Without a
+// theme file the dark theme is used.
+func LoadTheme(path string) (*Theme, error) {
+	var cfg themeConfig
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Base == "" {
+		cfg.Base = "dark"
+	}
+	base, ok := builtinThemes[cfg.Base]
+	if !ok {
+		return nil, fmt.Errorf("unknown theme %s", cfg.Base)
+	}
+	theme := base.copy()
+	for name, sc := range cfg.Styles {
+		if _, known := base.Styles[name]; !known {
+			return nil, fmt.Errorf("unknown style %s", name)
+		}
+		fg, fg256, err := parseColor(sc.Fg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err.Error())
+		}
+		bg, bg256, err := parseColor(sc.Bg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err.Error())
+		}
+		for _, attr := range sc.Attrs {
+			a, ok := attributeNames[strings.ToLower(attr)]
+			if !ok {
+				return nil, fmt.Errorf("%s: unknown attribute %s", name, attr)
+			}
+			fg |= a
+		}
+		theme.Styles[name] = Style{Fg: fg, Bg: bg}
+		theme.Uses256Colors = theme.Uses256Colors || fg256 || bg256
+	}
+	return theme, nil
+}
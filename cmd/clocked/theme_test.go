@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	termbox "github.com/nsf/termbox-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseColor(t *testing.T) {
+	c, uses256, err := parseColor("Red")
+	require.NoError(t, err)
+	require.Equal(t, termbox.ColorRed, c)
+	require.False(t, uses256)
+
+	c, uses256, err = parseColor("1")
+	require.NoError(t, err)
+	require.Equal(t, termbox.ColorRed, c)
+	require.False(t, uses256)
+
+	c, uses256, err = parseColor("202")
+	require.NoError(t, err)
+	require.Equal(t, termbox.Attribute(203), c)
+	require.True(t, uses256)
+
+	_, _, err = parseColor("256")
+	require.Error(t, err)
+	_, _, err = parseColor("purple")
+	require.Error(t, err)
+}
+
+func TestLoadTheme(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clocked-theme")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, ThemeFilename)
+
+	theme, err := LoadTheme(path)
+	require.NoError(t, err)
+	require.Equal(t, "dark", theme.Name)
+	require.False(t, theme.Uses256Colors)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("base: light\nstyles:\n  headline:\n    fg: 25\n    attrs: [bold]\n"), 0600))
+	theme, err = LoadTheme(path)
+	require.NoError(t, err)
+	require.Equal(t, "light", theme.Name)
+	require.True(t, theme.Uses256Colors)
+	require.Equal(t, Style{Fg: color256(25) | termbox.AttrBold, Bg: termbox.ColorDefault}, theme.Style(styleHeadline))
+	require.Equal(t, builtinThemes["light"].Style(styleLabel), theme.Style(styleLabel))
+	// The built-in theme must not be modified by overrides.
+	require.Equal(t, termbox.ColorBlue|termbox.AttrBold, builtinThemes["light"].Style(styleHeadline).Fg)
+
+	for _, content := range []string{
+		"base: unknown\n",
+		"styles:\n  unknown:\n    fg: red\n",
+		"styles:\n  text:\n    fg: purple\n",
+		"styles:\n  text:\n    attrs: [blink]\n",
+	} {
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+		_, err = LoadTheme(path)
+		require.Error(t, err, content)
+	}
+}
@@ -0,0 +1,590 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/nsf/termbox-go"
+	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/database"
+	"github.com/zerok/clocked/internal/form"
+)
+
+// timelinePalette is used for tasks without a tag color.
+var timelinePalette = []termbox.Attribute{
+	termbox.ColorBlue,
+	termbox.ColorGreen,
+	termbox.ColorMagenta,
+	termbox.ColorCyan,
+	termbox.ColorYellow,
+	termbox.ColorRed,
+}
+
+// Default range of hours shown by the timeline. It is extended to include
+// all bookings of the day.
+const (
+	timelineFirstHour = 8
+	timelineLastHour  = 18
+)
+
+// timelineBlock is a single booking shown in the timeline.
+type timelineBlock struct {
+	Code string
+	// Index is the position of the booking within the task's bookings.
+	Index   int
+	Row     int
+	Start   time.Time
+	Stop    time.Time
+	Running bool
+}
+
+func (b timelineBlock) Duration() time.Duration {
+	return b.Stop.Sub(b.Start)
+}
+
+type byBlockStart []timelineBlock
+
+func (b byBlockStart) Len() int           { return len(b) }
+func (b byBlockStart) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byBlockStart) Less(i, j int) bool { return b[i].Start.Before(b[j].Start) }
+
+// interval is a period of time without a booking (a gap) or with more than
+// one booking (an overlap).
+type interval struct {
+	Start time.Time
+	Stop  time.Time
+}
+
+// buildTimeline collects all bookings of the given tasks that started on
+// day. Running bookings end at now and all times are converted to the
+// location of day. The returned rows contain the codes of the tasks in the
+// order they were first worked on.
+func buildTimeline(tasks []clocked.Task, day time.Time, now time.Time) ([]string, []timelineBlock) {
+	from := database.StartOfDay(day)
+	to := from.AddDate(0, 0, 1)
+	var blocks []timelineBlock
+	for _, t := range tasks {
+		for idx, b := range t.Bookings {
+			start := b.StartTime()
+			if start == nil || start.Before(from) || !start.Before(to) {
+				continue
+			}
+			block := timelineBlock{Code: t.Code, Index: idx, Start: start.In(day.Location())}
+			if stop := b.StopTime(); stop != nil {
+				block.Stop = stop.In(day.Location())
+			} else {
+				block.Stop = now
+				block.Running = true
+			}
+			if block.Stop.Before(block.Start) {
+				block.Stop = block.Start
+			}
+			blocks = append(blocks, block)
+		}
+	}
+	sort.Stable(byBlockStart(blocks))
+	var rows []string
+	rowOf := make(map[string]int)
+	for idx := range blocks {
+		row, found := rowOf[blocks[idx].Code]
+		if !found {
+			row = len(rows)
+			rowOf[blocks[idx].Code] = row
+			rows = append(rows, blocks[idx].Code)
+		}
+		blocks[idx].Row = row
+	}
+	return rows, blocks
+}
+
+// gapsAndOverlaps returns the periods between the first and the last
+// booking that are not booked at all and those that are booked more than
+// once. blocks have to be sorted by their start.
+func gapsAndOverlaps(blocks []timelineBlock) ([]interval, []interval) {
+	var gaps, overlaps []interval
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+	end := blocks[0].Stop
+	for _, b := range blocks[1:] {
+		if b.Start.After(end) {
+			gaps = append(gaps, interval{Start: end, Stop: b.Start})
+		} else if b.Start.Before(end) {
+			stop := b.Stop
+			if end.Before(stop) {
+				stop = end
+			}
+			overlaps = appendInterval(overlaps, interval{Start: b.Start, Stop: stop})
+		}
+		if b.Stop.After(end) {
+			end = b.Stop
+		}
+	}
+	return gaps, overlaps
+}
+
+// appendInterval adds i to intervals and merges it with the last one if
+// they touch.
+func appendInterval(intervals []interval, i interval) []interval {
+	if !i.Start.Before(i.Stop) {
+		return intervals
+	}
+	if n := len(intervals); n > 0 && !i.Start.After(intervals[n-1].Stop) {
+		if i.Stop.After(intervals[n-1].Stop) {
+			intervals[n-1].Stop = i.Stop
+		}
+		return intervals
+	}
+	return append(intervals, i)
+}
+
+// timelineHours returns the range of hours that covers all blocks.
+func timelineHours(blocks []timelineBlock) (int, int) {
+	first, last := timelineFirstHour, timelineLastHour
+	if len(blocks) == 0 {
+		return first, last
+	}
+	day := database.StartOfDay(blocks[0].Start)
+	for _, b := range blocks {
+		if h := int(b.Start.Sub(day).Hours()); h < first {
+			first = h
+		}
+		end := b.Stop.Sub(day)
+		h := int(end.Hours())
+		if end > time.Duration(h)*time.Hour {
+			h++
+		}
+		if h > last {
+			last = h
+		}
+	}
+	if last > 24 {
+		last = 24
+	}
+	return first, last
+}
+
+// timelineScale maps times of a day onto columns of the given width.
+type timelineScale struct {
+	from  time.Time
+	to    time.Time
+	width int
+}
+
+func (s timelineScale) column(t time.Time) int {
+	if !t.After(s.from) {
+		return 0
+	}
+	if !t.Before(s.to) {
+		return s.width
+	}
+	return int(int64(t.Sub(s.from)) * int64(s.width) / int64(s.to.Sub(s.from)))
+}
+
+// columns returns the first column and the column after the last one used
+// by the given period. Every period uses at least one column.
+func (s timelineScale) columns(start, stop time.Time) (int, int) {
+	first, last := s.column(start), s.column(stop)
+	if last <= first {
+		last = first + 1
+	}
+	if last > s.width {
+		first, last = s.width-1, s.width
+	}
+	return first, last
+}
+
+// timelineView shows the bookings of a day as blocks on a line per task
+// with the hours of the day across the screen. Selected bookings can be
+// edited.
+type timelineView struct {
+	app      *application
+	date     time.Time
+	rows     []string
+	blocks   []timelineBlock
+	selected int
+	// form is set while the selected booking is being edited.
+	form *form.Form
+
+	// Set while rendering so that mouse events can be mapped to blocks.
+	scale     timelineScale
+	chartX    int
+	chartY    int
+	chartRows int
+}
+
+func newTimelineView(app *application) *timelineView {
+	return &timelineView{
+		app:  app,
+		date: time.Now(),
+	}
+}
+
+// SetDate selects the day shown by the timeline.
+func (v *timelineView) SetDate(date time.Time) {
+	v.date = date
+	v.selected = 0
+	v.form = nil
+	v.update()
+}
+
+func (v *timelineView) BeforeFocus() error {
+	v.form = nil
+	v.selected = 0
+	v.update()
+	return nil
+}
+
+func (v *timelineView) update() {
+	if v.app.db == nil {
+		return
+	}
+	tasks, err := v.app.db.AllTasks()
+	if err != nil {
+		v.app.err = err
+		return
+	}
+	v.rows, v.blocks = buildTimeline(tasks, v.date, time.Now())
+	if v.selected >= len(v.blocks) {
+		v.selected = len(v.blocks) - 1
+	}
+	if v.selected < 0 {
+		v.selected = 0
+	}
+}
+
+func (v *timelineView) selectedBlock() (timelineBlock, bool) {
+	if v.selected < 0 || v.selected >= len(v.blocks) {
+		return timelineBlock{}, false
+	}
+	return v.blocks[v.selected], true
+}
+
+// TextInputFocused reports if a booking is being edited.
+func (v *timelineView) TextInputFocused() bool {
+	return v.form != nil
+}
+
+func (v *timelineView) Actions() []Action {
+	a := v.app
+	editing := func() bool {
+		return v.form != nil
+	}
+	browsing := func() bool {
+		return v.form == nil
+	}
+	selected := func() bool {
+		return v.form == nil && len(v.blocks) > 0
+	}
+	return []Action{
+		{Name: "form.next", Label: "Focus next field", Keys: []string{"TAB"}, Enabled: editing, Run: func() error {
+			v.form.Next()
+			return nil
+		}},
+		{Name: "timeline.edit.submit", Label: "Save booking", Keys: []string{"ENTER"}, Enabled: editing, Run: v.saveBooking},
+		{Name: "timeline.edit.cancel", Label: "Cancel", Keys: []string{"ESC"}, Enabled: editing, Run: func() error {
+			v.form = nil
+			return nil
+		}},
+		{Name: "timeline.edit", Label: "Edit booking", Keys: []string{"ENTER"}, Enabled: selected, Run: v.editBooking},
+		{Name: "timeline.next", Label: "Next booking", Keys: []string{"l", "RIGHT"}, Enabled: selected, Run: func() error {
+			v.moveSelection(1)
+			return nil
+		}},
+		{Name: "timeline.previous", Label: "Previous booking", Keys: []string{"h", "LEFT"}, Enabled: selected, Run: func() error {
+			v.moveSelection(-1)
+			return nil
+		}},
+		{Name: "timeline.later", Label: "Next day", Keys: []string{"j", "]"}, Enabled: browsing, Run: func() error {
+			v.SetDate(v.date.AddDate(0, 0, 1))
+			return nil
+		}},
+		{Name: "timeline.earlier", Label: "Previous day", Keys: []string{"k", "["}, Enabled: browsing, Run: func() error {
+			v.SetDate(v.date.AddDate(0, 0, -1))
+			return nil
+		}},
+		{Name: "timeline.close", Label: "Summary", Keys: []string{"q", "ESC"}, Enabled: browsing, Run: func() error {
+			a.switchMode(summaryMode)
+			if view, ok := a.views[summaryMode].(*summaryView); ok {
+				date := v.date
+				view.date = &date
+			}
+			return nil
+		}},
+	}
+}
+
+func (v *timelineView) moveSelection(delta int) {
+	if len(v.blocks) == 0 {
+		return
+	}
+	v.selected = (v.selected + delta + len(v.blocks)) % len(v.blocks)
+}
+
+func (v *timelineView) editBooking() error {
+	b, ok := v.selectedBlock()
+	if !ok {
+		return nil
+	}
+	fields := []form.Field{
+		{Code: "start", Label: "Start", IsRequired: true, Type: form.TimeField},
+	}
+	// The running booking is stopped by clocking out. Otherwise the stop
+	// would be overwritten by the next clock-out.
+	if !b.Running {
+		fields = append(fields, form.Field{Code: "stop", Label: "Stop", IsRequired: true, Type: form.TimeField})
+	}
+	v.form = form.NewForm(fields)
+	v.form.SetValue("start", b.Start.Format(form.TimeFormat))
+	if !b.Running {
+		v.form.SetValue("stop", b.Stop.Format(form.TimeFormat))
+	}
+	return nil
+}
+
+func (v *timelineView) saveBooking() error {
+	a := v.app
+	b, ok := v.selectedBlock()
+	if !ok || !v.form.Validate() {
+		return nil
+	}
+	day := database.StartOfDay(b.Start)
+	start, err := v.form.Time("start", day)
+	if err != nil {
+		a.err = err
+		return nil
+	}
+	booking := clocked.Booking{}
+	booking.SetStart(start)
+	if !b.Running {
+		// Bookings that end after midnight keep ending on that day.
+		stop, err := v.form.Time("stop", database.StartOfDay(b.Stop))
+		if err != nil {
+			a.err = err
+			return nil
+		}
+		booking.SetStop(stop)
+	}
+	if err := a.db.UpdateBooking(b.Code, b.Index, booking); err != nil {
+		a.err = err
+		return nil
+	}
+	a.snapshot()
+	v.form = nil
+	a.notice = fmt.Sprintf("Updated booking of %s", b.Code)
+	v.update()
+	for idx, other := range v.blocks {
+		if other.Code == b.Code && other.Index == b.Index {
+			v.selected = idx
+		}
+	}
+	return nil
+}
+
+// blockStyle returns the style of the given task's blocks: the color of
+// its first colored tag or one of the palette's.
+func (v *timelineView) blockStyle(code string, row int) Style {
+	if t, ok := v.app.db.TaskByCode(code); ok {
+		for _, tag := range t.Tags {
+			if s, ok := v.app.tagStyle(tag); ok {
+				return s
+			}
+		}
+	}
+	return Style{Fg: timelinePalette[row%len(timelinePalette)], Bg: v.app.theme.Style(styleText).Bg}
+}
+
+func (v *timelineView) Render(area Area) error {
+	a := v.app
+	if v.form != nil {
+		b, _ := v.selectedBlock()
+		a.drawHeadline(area.XMin(), area.YMin(), fmt.Sprintf("Edit booking of %s on %s", b.Code, b.Start.Format("Mon, 2 Jan 2006")))
+		a.redrawForm(Area{X: area.X, Y: area.Y + 1, Width: area.Width, Height: area.Height - 1}, v.form)
+		return nil
+	}
+	a.drawHeadline(area.XMin(), area.YMin(), fmt.Sprintf("Timeline for %s", v.date.Format("Mon, 2 Jan 2006")))
+	if len(v.blocks) == 0 {
+		a.drawStyled(area.XMin(), area.YMin()+2, "Nothing booked on this day.", styleText)
+		return nil
+	}
+
+	labelWidth := 0
+	for _, code := range v.rows {
+		if l := len([]rune(code)); l > labelWidth {
+			labelWidth = l
+		}
+	}
+	if labelWidth > 12 {
+		labelWidth = 12
+	}
+	labelWidth++
+	first, last := timelineHours(v.blocks)
+	day := database.StartOfDay(v.date)
+	v.chartX = area.XMin() + labelWidth
+	v.chartY = area.YMin() + 2
+	v.scale = timelineScale{
+		from:  day.Add(time.Duration(first) * time.Hour),
+		to:    day.Add(time.Duration(last) * time.Hour),
+		width: area.Width - labelWidth,
+	}
+	if v.scale.width < 1 {
+		return nil
+	}
+
+	// Hours axis
+	for h := first; h < last; h++ {
+		x := v.chartX + v.scale.column(day.Add(time.Duration(h)*time.Hour))
+		a.drawStyled(x, area.YMin()+1, fmt.Sprintf("%02d", h), styleLabel)
+	}
+
+	v.chartRows = len(v.rows)
+	if max := area.Height - 7; v.chartRows > max {
+		v.chartRows = max
+	}
+	for row := 0; row < v.chartRows; row++ {
+		label := []rune(v.rows[row])
+		if len(label) > labelWidth-1 {
+			label = append(label[:labelWidth-2], '…')
+		}
+		a.drawStyled(area.XMin(), v.chartY+row, string(label), styleLabel)
+		v.drawGrid(first, last, v.chartY+row)
+	}
+	for idx, b := range v.blocks {
+		if b.Row >= v.chartRows {
+			continue
+		}
+		s := v.blockStyle(b.Code, b.Row)
+		ch := '█'
+		if idx == v.selected {
+			s = a.theme.Style(styleHighlight)
+			ch = '▓'
+		}
+		from, to := v.scale.columns(b.Start, b.Stop)
+		for x := from; x < to; x++ {
+			termbox.SetCell(v.chartX+x, v.chartY+b.Row, ch, s.Fg, s.Bg)
+		}
+	}
+
+	// A combined line that marks gaps and overlaps
+	gaps, overlaps := gapsAndOverlaps(v.blocks)
+	y := v.chartY + v.chartRows
+	a.drawStyled(area.XMin(), y, "all", styleEmphasis)
+	worked := a.theme.Style(styleSuccess)
+	for _, b := range v.blocks {
+		from, to := v.scale.columns(b.Start, b.Stop)
+		for x := from; x < to; x++ {
+			termbox.SetCell(v.chartX+x, y, '█', worked.Fg, worked.Bg)
+		}
+	}
+	v.drawIntervals(gaps, y, '·', a.theme.Style(styleText))
+	v.drawIntervals(overlaps, y, '▒', a.theme.Style(styleWarning))
+
+	y += 2
+	if b, ok := v.selectedBlock(); ok {
+		stop := formatTime(&b.Stop)
+		if b.Running {
+			stop = formatTime(nil)
+		}
+		x := a.drawStyled(area.XMin(), y, b.Code, styleEmphasis)
+		title := ""
+		if t, ok := a.db.TaskByCode(b.Code); ok && t.Title != "" {
+			title = " " + t.Title
+		}
+		a.drawStyled(x, y, fmt.Sprintf(" %s - %s (%s)%s", formatTime(&b.Start), stop, database.FormatHours(b.Duration()), title), styleText)
+	}
+	y++
+	x := a.drawStyled(area.XMin(), y, "Gaps: ", styleEmphasis)
+	x = a.drawStyled(x, y, formatIntervals(gaps), styleText)
+	x = a.drawStyled(x+2, y, "Overlaps: ", styleEmphasis)
+	style := styleText
+	if len(overlaps) > 0 {
+		style = styleWarning
+	}
+	a.drawStyled(x, y, formatIntervals(overlaps), style)
+	return nil
+}
+
+// drawGrid marks the full hours on a line of the chart.
+func (v *timelineView) drawGrid(first, last, y int) {
+	s := v.app.theme.Style(styleSeparator)
+	day := database.StartOfDay(v.date)
+	for h := first; h < last; h++ {
+		x := v.scale.column(day.Add(time.Duration(h) * time.Hour))
+		termbox.SetCell(v.chartX+x, y, '│', s.Fg, s.Bg)
+	}
+}
+
+func (v *timelineView) drawIntervals(intervals []interval, y int, ch rune, s Style) {
+	for _, i := range intervals {
+		from, to := v.scale.columns(i.Start, i.Stop)
+		for x := from; x < to; x++ {
+			termbox.SetCell(v.chartX+x, y, ch, s.Fg, s.Bg)
+		}
+	}
+}
+
+// formatIntervals lists the given periods with their duration.
+func formatIntervals(intervals []interval) string {
+	if len(intervals) == 0 {
+		return "none"
+	}
+	result := ""
+	for idx, i := range intervals {
+		if idx > 0 {
+			result += ", "
+		}
+		result += fmt.Sprintf("%s-%s (%s)", i.Start.Format(form.TimeFormat), i.Stop.Format(form.TimeFormat), database.FormatHours(i.Stop.Sub(i.Start)))
+	}
+	return result
+}
+
+// blockAt returns the index of the block shown at the given position.
+func (v *timelineView) blockAt(x, y int) (int, bool) {
+	row := y - v.chartY
+	col := x - v.chartX
+	if row < 0 || row >= v.chartRows || col < 0 || col >= v.scale.width {
+		return 0, false
+	}
+	for idx, b := range v.blocks {
+		if b.Row != row {
+			continue
+		}
+		from, to := v.scale.columns(b.Start, b.Stop)
+		if col >= from && col < to {
+			return idx, true
+		}
+	}
+	return 0, false
+}
+
+func (v *timelineView) HandleKeyEvent(evt termbox.Event) error {
+	if v.form != nil {
+		v.app.handleFieldInput(v.form, evt)
+	}
+	return nil
+}
+
+// HandleMouseEvent selects clicked blocks and edits them on double-click.
+// The mouse wheel moves between days.
+func (v *timelineView) HandleMouseEvent(evt termbox.Event, double bool) error {
+	if v.form != nil {
+		return nil
+	}
+	switch evt.Key {
+	case termbox.MouseWheelUp:
+		v.SetDate(v.date.AddDate(0, 0, -1))
+	case termbox.MouseWheelDown:
+		v.SetDate(v.date.AddDate(0, 0, 1))
+	case termbox.MouseLeft:
+		idx, ok := v.blockAt(evt.MouseX, evt.MouseY)
+		if !ok {
+			return nil
+		}
+		v.selected = idx
+		if double {
+			return v.editBooking()
+		}
+	}
+	return nil
+}
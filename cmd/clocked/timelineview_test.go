@@ -0,0 +1,135 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/database"
+)
+
+func timelineAt(h, m int) time.Time {
+	return time.Date(2018, 3, 1, h, m, 0, 0, time.Local)
+}
+
+func booking(from, to time.Time) clocked.Booking {
+	b := clocked.Booking{}
+	b.SetStart(from)
+	if !to.IsZero() {
+		b.SetStop(to)
+	}
+	return b
+}
+
+func TestBuildTimeline(t *testing.T) {
+	tasks := []clocked.Task{
+		{Code: "b", Bookings: []clocked.Booking{
+			booking(timelineAt(8, 0).AddDate(0, 0, -1), timelineAt(9, 0).AddDate(0, 0, -1)),
+			booking(timelineAt(10, 0), timelineAt(11, 0)),
+			booking(timelineAt(13, 0), time.Time{}),
+		}},
+		{Code: "a", Bookings: []clocked.Booking{
+			booking(timelineAt(9, 0), timelineAt(10, 30)),
+		}},
+	}
+	rows, blocks := buildTimeline(tasks, timelineAt(12, 0), timelineAt(14, 0))
+	require.Equal(t, []string{"a", "b"}, rows)
+	require.Len(t, blocks, 3)
+	require.Equal(t, "a", blocks[0].Code)
+	require.Equal(t, 0, blocks[0].Row)
+	require.Equal(t, 1, blocks[1].Index, "the index refers to the task's bookings")
+	require.Equal(t, 1, blocks[1].Row)
+	require.True(t, blocks[2].Running)
+	require.Equal(t, timelineAt(14, 0), blocks[2].Stop)
+
+	gaps, overlaps := gapsAndOverlaps(blocks)
+	require.Equal(t, []interval{{timelineAt(11, 0), timelineAt(13, 0)}}, gaps)
+	require.Equal(t, []interval{{timelineAt(10, 0), timelineAt(10, 30)}}, overlaps)
+
+	first, last := timelineHours(blocks)
+	require.Equal(t, timelineFirstHour, first)
+	require.Equal(t, timelineLastHour, last)
+	blocks[0].Start = timelineAt(6, 30)
+	blocks[2].Stop = timelineAt(19, 15)
+	first, last = timelineHours(blocks)
+	require.Equal(t, 6, first)
+	require.Equal(t, 20, last)
+}
+
+func TestTimelineScale(t *testing.T) {
+	s := timelineScale{from: timelineAt(8, 0), to: timelineAt(18, 0), width: 50}
+	require.Equal(t, 0, s.column(timelineAt(7, 0)))
+	require.Equal(t, 5, s.column(timelineAt(9, 0)))
+	require.Equal(t, 50, s.column(timelineAt(19, 0)))
+	from, to := s.columns(timelineAt(9, 0), timelineAt(9, 5))
+	require.Equal(t, 5, from)
+	require.Equal(t, 6, to, "short bookings use at least one column")
+	from, to = s.columns(timelineAt(18, 0), timelineAt(19, 0))
+	require.Equal(t, 49, from)
+	require.Equal(t, 50, to)
+}
+
+func TestTimelineEditBooking(t *testing.T) {
+	app := newApplication()
+	app.db = database.NewInMemory()
+	app.db.AddTask(clocked.Task{Code: "a", Bookings: []clocked.Booking{
+		booking(timelineAt(9, 0), timelineAt(10, 0)),
+		booking(timelineAt(11, 0), timelineAt(12, 0)),
+	}})
+
+	app.switchMode(timelineMode)
+	v := app.activeView.(*timelineView)
+	v.SetDate(timelineAt(0, 0))
+	require.Len(t, v.blocks, 2)
+	v.moveSelection(1)
+	require.NoError(t, v.editBooking())
+	require.Equal(t, "11:00", v.form.Value("start"))
+	require.Equal(t, "12:00", v.form.Value("stop"))
+
+	v.form.SetValue("stop", "10:30")
+	require.NoError(t, v.saveBooking())
+	require.Error(t, app.err, "a booking cannot stop before it started")
+	require.NotNil(t, v.form)
+
+	app.err = nil
+	v.form.SetValue("start", "10:15")
+	require.NoError(t, v.saveBooking())
+	require.NoError(t, app.err)
+	require.Nil(t, v.form)
+	task, _ := app.db.TaskByCode("a")
+	require.True(t, timelineAt(10, 15).Equal(*task.Bookings[1].StartTime()))
+	require.True(t, timelineAt(10, 30).Equal(*task.Bookings[1].StopTime()))
+	require.Equal(t, 1, v.selected)
+}
+
+func TestTimelineEditAcrossMidnight(t *testing.T) {
+	app := newApplication()
+	app.db = database.NewInMemory()
+	app.db.AddTask(clocked.Task{Code: "a", Bookings: []clocked.Booking{
+		booking(timelineAt(22, 0), timelineAt(22, 0).Add(3*time.Hour)),
+	}})
+	require.NoError(t, app.db.ClockInto("a"))
+	require.NoError(t, app.db.UpdateBooking("a", 1, booking(timelineAt(23, 30), time.Time{})))
+
+	app.switchMode(timelineMode)
+	v := app.activeView.(*timelineView)
+	v.SetDate(timelineAt(0, 0))
+	require.Len(t, v.blocks, 2)
+	require.NoError(t, v.editBooking())
+	require.Equal(t, "01:00", v.form.Value("stop"))
+	v.form.SetValue("stop", "00:30")
+	require.NoError(t, v.saveBooking())
+	require.NoError(t, app.err)
+	task, _ := app.db.TaskByCode("a")
+	require.True(t, timelineAt(22, 0).Add(150*time.Minute).Equal(*task.Bookings[0].StopTime()), "the booking still ends on the next day")
+
+	v.moveSelection(1)
+	require.NoError(t, v.editBooking())
+	require.Len(t, v.form.Fields(), 1, "the running booking is stopped by clocking out")
+	v.form.SetValue("start", "23:45")
+	require.NoError(t, v.saveBooking())
+	require.NoError(t, app.err)
+	task, _ = app.db.TaskByCode("a")
+	require.Nil(t, task.Bookings[1].StopTime())
+}
@@ -19,16 +19,34 @@ type Focusable interface {
 	BeforeFocus() error
 }
 
-// Keymapper is an interface that views should implement in order to render
-// a simple infoline showing which key-combinations are available in each
-// view.
-type Keymapper interface {
-	KeyMapping() []KeyMap
+// ActionProvider is implemented by views that declare the operations they
+// offer as named actions. Key events are dispatched to the matching action
+// first and only passed to HandleKeyEvent if no action matched. The infoline
+// showing which key-combinations are available is generated from the
+// actions as well.
+type ActionProvider interface {
+	Actions() []Action
 }
 
+// TextInput is implemented by views that can contain a text field. While
+// one has focus, printable characters are typed into it instead of
+// triggering the actions bound to them.
+type TextInput interface {
+	TextInputFocused() bool
+}
+
+// MouseHandler is implemented by views that react to mouse events. double
+// is set for the second click of a double-click.
+type MouseHandler interface {
+	HandleMouseEvent(evt termbox.Event, double bool) error
+}
+
+// KeyMap is a single entry of the infoline. Action is the name of the
+// action triggered when the entry is clicked.
 type KeyMap struct {
-	Key   string
-	Label string
+	Key    string
+	Label  string
+	Action string
 }
 
 type TaskCentricView interface {
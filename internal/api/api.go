@@ -0,0 +1,393 @@
+// Package api exposes the operations of a database.Database as HTTP/JSON
+// API so that editor plugins, status bars etc. can read and drive clocked
+// without having to parse the store themselves.
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/database"
+)
+
+const dateFormat = "2006-01-02"
+
+// Options configures a Server.
+type Options struct {
+	// Token has to be sent by clients as bearer token.
+	Token string
+	// Reload makes the server load the state of the database before every
+	// request so that changes made by other processes are picked up.
+	Reload bool
+	// OnChange is called after every successful modification.
+	OnChange func()
+	// CodePattern has to be matched by the codes of created or updated
+	// tasks.
+	CodePattern *regexp.Regexp
+	Log         *logrus.Logger
+}
+
+// Server is a http.Handler serving the API.
+type Server struct {
+	db   database.Database
+	opts Options
+	mux  *http.ServeMux
+	lock sync.Mutex
+}
+
+func NewServer(db database.Database, opts Options) (*Server, error) {
+	if opts.Token == "" {
+		return nil, fmt.Errorf("a token is required")
+	}
+	if opts.Log == nil {
+		opts.Log = logrus.New()
+	}
+	s := &Server{
+		db:   db,
+		opts: opts,
+		mux:  http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/api/tasks", s.handleTasks)
+	s.mux.HandleFunc("/api/tasks/", s.handleTask)
+	s.mux.HandleFunc("/api/active", s.handleActive)
+	s.mux.HandleFunc("/api/clock-in", s.handleClockIn)
+	s.mux.HandleFunc("/api/clock-out", s.handleClockOut)
+	s.mux.HandleFunc("/api/summary", s.handleSummary)
+	s.mux.HandleFunc("/api/bookings", s.handleBookings)
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, fmt.Errorf("invalid or missing token"))
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.opts.Reload {
+		if err := s.db.LoadState(); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+	token := strings.TrimPrefix(header, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Token)) == 1
+}
+
+func (s *Server) changed() {
+	if s.opts.OnChange != nil {
+		s.opts.OnChange()
+	}
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	return false
+}
+
+// TaskRequest is the body expected for creating and updating tasks. When
+// updating a task, its tags are kept if Tags is left out.
+type TaskRequest struct {
+	Code  string   `json:"code"`
+	Title string   `json:"title"`
+	Tags  []string `json:"tags"`
+}
+
+// validateCode checks that code can be stored and matches the configured
+// pattern.
+func (s *Server) validateCode(code string) error {
+	if err := database.ValidateCode(code); err != nil {
+		return err
+	}
+	if s.opts.CodePattern != nil && !s.opts.CodePattern.MatchString(code) {
+		return fmt.Errorf("the code has to match %s", s.opts.CodePattern)
+	}
+	return nil
+}
+
+func (s *Server) handleTasks(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+	if r.Method == http.MethodGet {
+		tasks, err := s.db.FilteredTasks(r.URL.Query().Get("filter"))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if tasks == nil {
+			tasks = []clocked.Task{}
+		}
+		writeJSON(w, http.StatusOK, tasks)
+		return
+	}
+	var req TaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.validateCode(req.Code); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	task := clocked.Task{Code: req.Code, Title: req.Title, Tags: req.Tags}
+	if err := s.db.AddTask(task); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	s.changed()
+	task, _ = s.db.TaskByCode(req.Code)
+	writeJSON(w, http.StatusCreated, task)
+}
+
+func (s *Server) handleTask(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPut) {
+		return
+	}
+	code := strings.TrimPrefix(r.URL.Path, "/api/tasks/")
+	task, found := s.db.TaskByCode(code)
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Errorf("task %s not found", code))
+		return
+	}
+	if r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, task)
+		return
+	}
+	var req TaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Code == "" {
+		req.Code = code
+	}
+	if req.Tags == nil {
+		req.Tags = task.Tags
+	}
+	if err := s.validateCode(req.Code); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.db.UpdateTask(code, clocked.Task{Code: req.Code, Title: req.Title, Tags: req.Tags}); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	s.changed()
+	task, _ = s.db.TaskByCode(req.Code)
+	writeJSON(w, http.StatusOK, task)
+}
+
+// ActiveResponse describes the currently active task.
+type ActiveResponse struct {
+	Code           string        `json:"code"`
+	Task           *clocked.Task `json:"task,omitempty"`
+	Since          *time.Time    `json:"since,omitempty"`
+	ElapsedSeconds int64         `json:"elapsed_seconds"`
+}
+
+func (s *Server) activeResponse() ActiveResponse {
+	resp := ActiveResponse{Code: s.db.ActiveCode()}
+	task, ok := s.db.ActiveTask()
+	if !ok {
+		return resp
+	}
+	resp.Task = &task
+	if len(task.Bookings) > 0 {
+		since := task.Bookings[len(task.Bookings)-1].StartTime()
+		if since != nil {
+			resp.Since = since
+			resp.ElapsedSeconds = int64(time.Since(*since).Seconds())
+		}
+	}
+	return resp
+}
+
+func (s *Server) handleActive(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.activeResponse())
+}
+
+// ClockInRequest is the body expected by /api/clock-in.
+type ClockInRequest struct {
+	Code string `json:"code"`
+}
+
+func (s *Server) handleClockIn(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+	var req ClockInRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, found := s.db.TaskByCode(req.Code); !found {
+		writeError(w, http.StatusNotFound, fmt.Errorf("task %s not found", req.Code))
+		return
+	}
+	if err := s.db.ClockInto(req.Code); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.changed()
+	writeJSON(w, http.StatusOK, s.activeResponse())
+}
+
+func (s *Server) handleClockOut(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+	code := s.db.ActiveCode()
+	if code == "" {
+		writeError(w, http.StatusConflict, fmt.Errorf("no task is active"))
+		return
+	}
+	if err := s.db.ClockOutOf(code); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.changed()
+	writeJSON(w, http.StatusOK, s.activeResponse())
+}
+
+// BookingResponse is a single booking as returned by the API.
+type BookingResponse struct {
+	Code            string     `json:"code"`
+	Start           *time.Time `json:"start"`
+	Stop            *time.Time `json:"stop"`
+	DurationSeconds int64      `json:"duration_seconds"`
+}
+
+// SummaryResponse is a database.Summary with all durations in seconds.
+type SummaryResponse struct {
+	From         string            `json:"from"`
+	To           string            `json:"to"`
+	TotalSeconds int64             `json:"total_seconds"`
+	Totals       map[string]int64  `json:"totals"`
+	DailyTotals  map[string]int64  `json:"daily_totals"`
+	Bookings     []BookingResponse `json:"bookings"`
+}
+
+// parseRange reads either the date or the from and to parameters of the
+// request. to is exclusive and defaults to the day after from.
+func parseRange(r *http.Request) (time.Time, time.Time, error) {
+	q := r.URL.Query()
+	if d := q.Get("date"); d != "" {
+		from, err := time.ParseInLocation(dateFormat, d, time.Local)
+		if err != nil {
+			return from, from, err
+		}
+		return from, from.AddDate(0, 0, 1), nil
+	}
+	if q.Get("from") == "" {
+		from := database.StartOfDay(time.Now())
+		return from, from.AddDate(0, 0, 1), nil
+	}
+	from, err := time.ParseInLocation(dateFormat, q.Get("from"), time.Local)
+	if err != nil {
+		return from, from, err
+	}
+	to := from.AddDate(0, 0, 1)
+	if q.Get("to") != "" {
+		to, err = time.ParseInLocation(dateFormat, q.Get("to"), time.Local)
+		if err != nil {
+			return from, to, err
+		}
+	}
+	if !to.After(from) {
+		return from, to, fmt.Errorf("to has to be after from")
+	}
+	return from, to, nil
+}
+
+func toSeconds(m map[string]time.Duration) map[string]int64 {
+	result := make(map[string]int64)
+	for k, v := range m {
+		result[k] = int64(v.Seconds())
+	}
+	return result
+}
+
+func bookingResponses(bookings []database.TaskBooking) []BookingResponse {
+	result := make([]BookingResponse, 0, len(bookings))
+	for _, b := range bookings {
+		result = append(result, BookingResponse{
+			Code:            b.Code,
+			Start:           b.Start,
+			Stop:            b.Stop,
+			DurationSeconds: int64(b.Duration().Seconds()),
+		})
+	}
+	return result
+}
+
+func (s *Server) handleSummary(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	from, to, err := parseRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	summary := s.db.GenerateSummary(from, to)
+	writeJSON(w, http.StatusOK, SummaryResponse{
+		From:         from.Format(dateFormat),
+		To:           to.Format(dateFormat),
+		TotalSeconds: int64(summary.Total.Seconds()),
+		Totals:       toSeconds(summary.Totals),
+		DailyTotals:  toSeconds(summary.DailyTotals),
+		Bookings:     bookingResponses(summary.Bookings),
+	})
+}
+
+func (s *Server) handleBookings(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	from, to, err := parseRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, bookingResponses(s.db.GenerateSummary(from, to).Bookings))
+}
@@ -0,0 +1,116 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/api"
+	"github.com/zerok/clocked/internal/database"
+)
+
+func doRequest(t *testing.T, srv http.Handler, method, path, token string, body interface{}, result interface{}) int {
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if result != nil {
+		require.NoError(t, json.NewDecoder(w.Body).Decode(result))
+	}
+	return w.Code
+}
+
+func TestAPI(t *testing.T) {
+	db := database.NewInMemory()
+	changes := 0
+	srv, err := api.NewServer(db, api.Options{Token: "secret", OnChange: func() { changes++ }})
+	require.NoError(t, err)
+
+	require.Equal(t, http.StatusUnauthorized, doRequest(t, srv, http.MethodGet, "/api/tasks", "", nil, nil))
+	require.Equal(t, http.StatusUnauthorized, doRequest(t, srv, http.MethodGet, "/api/tasks", "wrong", nil, nil))
+
+	var task clocked.Task
+	require.Equal(t, http.StatusCreated, doRequest(t, srv, http.MethodPost, "/api/tasks", "secret", api.TaskRequest{Code: "ABC-1", Title: "Review"}, &task))
+	require.Equal(t, "ABC-1", task.Code)
+	require.Equal(t, http.StatusConflict, doRequest(t, srv, http.MethodPost, "/api/tasks", "secret", api.TaskRequest{Code: "ABC-1"}, nil))
+	db.AddTask(clocked.Task{Code: "XYZ-1", Title: "Meeting"})
+
+	var tasks []clocked.Task
+	require.Equal(t, http.StatusOK, doRequest(t, srv, http.MethodGet, "/api/tasks?filter=review", "secret", nil, &tasks))
+	require.Len(t, tasks, 1)
+
+	var active api.ActiveResponse
+	require.Equal(t, http.StatusNotFound, doRequest(t, srv, http.MethodPost, "/api/clock-in", "secret", api.ClockInRequest{Code: "unknown"}, nil))
+	require.Equal(t, http.StatusOK, doRequest(t, srv, http.MethodPost, "/api/clock-in", "secret", api.ClockInRequest{Code: "XYZ-1"}, &active))
+	require.Equal(t, "XYZ-1", active.Code)
+	require.NotNil(t, active.Since)
+
+	require.Equal(t, http.StatusOK, doRequest(t, srv, http.MethodPost, "/api/clock-out", "secret", nil, &active))
+	require.Equal(t, "", active.Code)
+	require.Equal(t, http.StatusConflict, doRequest(t, srv, http.MethodPost, "/api/clock-out", "secret", nil, nil))
+	require.Equal(t, 3, changes)
+
+	var summary api.SummaryResponse
+	require.Equal(t, http.StatusOK, doRequest(t, srv, http.MethodGet, "/api/summary", "secret", nil, &summary))
+	require.Len(t, summary.Bookings, 1)
+	require.Equal(t, "XYZ-1", summary.Bookings[0].Code)
+	require.Equal(t, http.StatusBadRequest, doRequest(t, srv, http.MethodGet, "/api/summary?from=yesterday", "secret", nil, nil))
+	require.Equal(t, http.StatusMethodNotAllowed, doRequest(t, srv, http.MethodDelete, "/api/tasks/ABC-1", "secret", nil, nil))
+}
+
+func TestInvalidCodes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clocked-api")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store := filepath.Join(dir, "store")
+	db, err := database.NewDatabase(store, logrus.New())
+	require.NoError(t, err)
+	srv, err := api.NewServer(db, api.Options{Token: "secret", CodePattern: regexp.MustCompile(`^[A-Z]+-[0-9]+$`)})
+	require.NoError(t, err)
+
+	for _, code := range []string{"../../x", `..\x`, "a/b", "ABC 1", "abc-1"} {
+		require.Equal(t, http.StatusBadRequest, doRequest(t, srv, http.MethodPost, "/api/tasks", "secret", api.TaskRequest{Code: code}, nil), code)
+	}
+	require.Equal(t, http.StatusCreated, doRequest(t, srv, http.MethodPost, "/api/tasks", "secret", api.TaskRequest{Code: "ABC-1"}, nil))
+	require.Equal(t, http.StatusBadRequest, doRequest(t, srv, http.MethodPut, "/api/tasks/ABC-1", "secret", api.TaskRequest{Code: "../../x"}, nil))
+
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	_, found := db.TaskByCode("ABC-1")
+	require.True(t, found)
+}
+
+func TestUpdateKeepsTags(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clocked-api")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db, err := database.NewDatabase(dir, logrus.New())
+	require.NoError(t, err)
+	srv, err := api.NewServer(db, api.Options{Token: "secret"})
+	require.NoError(t, err)
+	require.NoError(t, db.AddTask(clocked.Task{Code: "ABC-1", Title: "Review", Tags: []string{"billable"}}))
+
+	var task clocked.Task
+	require.Equal(t, http.StatusOK, doRequest(t, srv, http.MethodPut, "/api/tasks/ABC-1", "secret", map[string]interface{}{"title": "Code review"}, &task))
+	require.Equal(t, "Code review", task.Title)
+	require.Equal(t, []string{"billable"}, task.Tags)
+
+	require.Equal(t, http.StatusOK, doRequest(t, srv, http.MethodPut, "/api/tasks/ABC-1", "secret", map[string]interface{}{"title": "Code review", "tags": []string{}}, &task))
+	require.Empty(t, task.Tags)
+}
@@ -0,0 +1,455 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// archiveMarker is created inside of archive repositories to tell them
+// apart from restic repositories.
+const archiveMarker = "clocked-archive"
+
+// Suffixes of plain and encrypted archives.
+const (
+	archiveSuffix   = ".tar.gz"
+	encryptedSuffix = ".tar.gz.enc"
+)
+
+// archiveTimeFormat is used for the time (in UTC) the archive was created at
+// inside of its name. rawTimeFormat is used for Snapshot.RawTime so that
+// snapshots can be sorted by it.
+const (
+	archiveTimeFormat = "20060102-150405.000000000"
+	rawTimeFormat     = "2006-01-02T15:04:05.000000000Z07:00"
+)
+
+// saltSize is the size of the random salt used to derive the key of each
+// encrypted archive from the password.
+const saltSize = 16
+
+// archiveBackend stores every snapshot as gzip compressed tar file, which
+// is encrypted using AES-GCM if encrypt is set. The name of the file
+// contains the time and the ID of the snapshot.
+type archiveBackend struct {
+	repositoryPath string
+	passwordFile   string
+	sourcePath     string
+	encrypt        bool
+}
+
+// archive is a single snapshot inside of the repository.
+type archive struct {
+	name      string
+	id        string
+	time      time.Time
+	encrypted bool
+}
+
+// parseArchiveName returns the archive with the given file name or false if
+// the file isn't an archive.
+func parseArchiveName(name string) (archive, bool) {
+	ar := archive{name: name}
+	base := name
+	switch {
+	case strings.HasSuffix(name, encryptedSuffix):
+		base = strings.TrimSuffix(name, encryptedSuffix)
+		ar.encrypted = true
+	case strings.HasSuffix(name, archiveSuffix):
+		base = strings.TrimSuffix(name, archiveSuffix)
+	default:
+		return ar, false
+	}
+	parts := strings.SplitN(base, "_", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return ar, false
+	}
+	t, err := time.Parse(archiveTimeFormat, parts[0])
+	if err != nil {
+		return ar, false
+	}
+	ar.time = t
+	ar.id = parts[1]
+	return ar, true
+}
+
+// isArchiveRepository reports if the given folder contains snapshots of the
+// archive backend.
+func isArchiveRepository(repositoryPath string) bool {
+	_, err := os.Stat(filepath.Join(repositoryPath, archiveMarker))
+	return err == nil
+}
+
+func (a *archiveBackend) Available() bool {
+	return true
+}
+
+func (a *archiveBackend) Init() (bool, error) {
+	if isArchiveRepository(a.repositoryPath) {
+		return false, nil
+	}
+	if isResticRepository(a.repositoryPath) {
+		return false, fmt.Errorf("%s already contains a restic repository", a.repositoryPath)
+	}
+	if err := os.MkdirAll(a.repositoryPath, 0700); err != nil {
+		return false, err
+	}
+	marker := filepath.Join(a.repositoryPath, archiveMarker)
+	if err := ioutil.WriteFile(marker, []byte("This folder contains snapshots created by clocked.\n"), 0600); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (a *archiveBackend) CreateSnapshot() error {
+	var buffer bytes.Buffer
+	if err := a.writeArchive(&buffer); err != nil {
+		return err
+	}
+	data := buffer.Bytes()
+	suffix := archiveSuffix
+	if a.encrypt {
+		password, err := ioutil.ReadFile(a.passwordFile)
+		if err != nil {
+			return err
+		}
+		if data, err = encrypt(data, password); err != nil {
+			return err
+		}
+		suffix = encryptedSuffix
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s_%s%s", time.Now().UTC().Format(archiveTimeFormat), hex.EncodeToString(id), suffix)
+	// The archive is renamed once it is complete so that no partial
+	// archives are left behind.
+	tmp := filepath.Join(a.repositoryPath, "."+name)
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(a.repositoryPath, name))
+}
+
+// writeArchive writes all folders and regular files of the source folder
+// into a compressed tar archive. The repository is skipped if it is located
+// inside of the source folder.
+func (a *archiveBackend) writeArchive(w io.Writer) error {
+	repo, err := filepath.Abs(a.repositoryPath)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	err = filepath.Walk(a.sourcePath, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if abs, err := filepath.Abs(p); err == nil && abs == repo {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(a.sourcePath, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		fp, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer fp.Close()
+		_, err = io.Copy(tw, fp)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// archives returns all archives inside of the repository, the latest first.
+func (a *archiveBackend) archives() ([]archive, error) {
+	infos, err := ioutil.ReadDir(a.repositoryPath)
+	if err != nil {
+		return nil, err
+	}
+	var result []archive
+	for _, info := range infos {
+		if ar, ok := parseArchiveName(info.Name()); ok && info.Mode().IsRegular() {
+			result = append(result, ar)
+		}
+	}
+	sort.Sort(sort.Reverse(byArchiveTime(result)))
+	return result, nil
+}
+
+func (a *archiveBackend) Snapshots() ([]Snapshot, error) {
+	archives, err := a.archives()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Snapshot, 0, len(archives))
+	for _, ar := range archives {
+		result = append(result, Snapshot{
+			RawTime: ar.time.Format(rawTimeFormat),
+			ID:      ar.id,
+			Paths:   []string{a.sourcePath},
+		})
+	}
+	return result, nil
+}
+
+// Restore replaces the source folder with the snapshot whose ID starts with
+// the given one. Files that have been created after the snapshot are
+// removed.
+func (a *archiveBackend) Restore(id string) error {
+	archives, err := a.archives()
+	if err != nil {
+		return err
+	}
+	var found []archive
+	for _, ar := range archives {
+		if id != "" && strings.HasPrefix(ar.id, id) {
+			found = append(found, ar)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return fmt.Errorf("no snapshot with ID %s found", id)
+	case 1:
+	default:
+		return fmt.Errorf("the ID %s matches %d snapshots", id, len(found))
+	}
+	data, err := ioutil.ReadFile(filepath.Join(a.repositoryPath, found[0].name))
+	if err != nil {
+		return err
+	}
+	if found[0].encrypted {
+		password, err := ioutil.ReadFile(a.passwordFile)
+		if err != nil {
+			return err
+		}
+		if data, err = decrypt(data, password); err != nil {
+			return err
+		}
+	}
+	return a.replaceSource(data)
+}
+
+// replaceSource extracts the archive next to the source folder and swaps
+// it in afterwards so that the source folder is left untouched if the
+// archive can't be extracted. A repository inside of the source folder
+// isn't part of the archive and is moved over.
+func (a *archiveBackend) replaceSource(data []byte) error {
+	abs, err := filepath.Abs(a.sourcePath)
+	if err != nil {
+		return err
+	}
+	source := abs
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		source = resolved
+	}
+	tmp, err := ioutil.TempDir(filepath.Dir(source), "."+filepath.Base(source)+"-restore")
+	if err != nil {
+		return err
+	}
+	if err := extract(bytes.NewReader(data), tmp); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	old := tmp + "-old"
+	if err := os.Rename(source, old); err != nil {
+		if !os.IsNotExist(err) {
+			os.RemoveAll(tmp)
+			return err
+		}
+		return os.Rename(tmp, source)
+	}
+	if err := os.Rename(tmp, source); err != nil {
+		os.RemoveAll(tmp)
+		if restoreErr := os.Rename(old, source); restoreErr != nil {
+			return fmt.Errorf("%s (the previous files are kept in %s)", err.Error(), old)
+		}
+		return err
+	}
+	if repo, err := filepath.Abs(a.repositoryPath); err == nil {
+		if rel, err := filepath.Rel(abs, repo); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			target := filepath.Join(source, rel)
+			if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+				return err
+			}
+			if err := os.Rename(filepath.Join(old, rel), target); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to move the repository back into %s (it is kept in %s): %s", source, old, err.Error())
+			}
+		}
+	}
+	return os.RemoveAll(old)
+}
+
+// extract writes the folders and files of the archive into target.
+func extract(r io.Reader, target string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := path.Clean(hdr.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("the snapshot contains the invalid path %s", hdr.Name)
+		}
+		p := filepath.Join(target, filepath.FromSlash(name))
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(p, 0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFile(p, tr, os.FileMode(hdr.Mode)&os.ModePerm); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeFile(target string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return err
+	}
+	fp, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(fp, r); err != nil {
+		fp.Close()
+		return err
+	}
+	return fp.Close()
+}
+
+// Forget removes the archives that aren't covered by the policy. As
+// archives don't share any data, they are always removed completely.
+func (a *archiveBackend) Forget(policy Policy, prune bool) error {
+	archives, err := a.archives()
+	if err != nil {
+		return err
+	}
+	times := make([]time.Time, 0, len(archives))
+	for _, ar := range archives {
+		times = append(times, ar.time)
+	}
+	for idx, keep := range policy.keep(times) {
+		if keep {
+			continue
+		}
+		if err := os.Remove(filepath.Join(a.repositoryPath, archives[idx].name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deriveKey generates the key of an archive from the password and its
+// salt.
+func deriveKey(password, salt []byte) ([]byte, error) {
+	return scrypt.Key(bytes.TrimSpace(password), salt, 1<<15, 8, 1, 32)
+}
+
+// encrypt encrypts data using AES-GCM. The result starts with the salt of
+// the key followed by the nonce.
+func encrypt(data, password []byte) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append(salt, nonce...)
+	return gcm.Seal(out, nonce, data, nil), nil
+}
+
+// decrypt reverses encrypt.
+func decrypt(data, password []byte) ([]byte, error) {
+	if len(data) < saltSize {
+		return nil, fmt.Errorf("the snapshot is too short")
+	}
+	gcm, err := newGCM(password, data[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	data = data[saltSize:]
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("the snapshot is too short")
+	}
+	result, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the snapshot. Has the password file changed?")
+	}
+	return result, nil
+}
+
+func newGCM(password, salt []byte) (cipher.AEAD, error) {
+	key, err := deriveKey(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+type byArchiveTime []archive
+
+func (s byArchiveTime) Len() int           { return len(s) }
+func (s byArchiveTime) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byArchiveTime) Less(i, j int) bool { return s[i].time.Before(s[j].time) }
@@ -0,0 +1,131 @@
+package backup_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zerok/clocked/internal/backup"
+)
+
+func TestArchiveBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clocked-archive")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	source := filepath.Join(dir, "store")
+	repo := filepath.Join(dir, "repo")
+	task := filepath.Join(source, "tasks", "A-1.yml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(task), 0700))
+	require.NoError(t, ioutil.WriteFile(task, []byte("code: A-1\n"), 0600))
+
+	b, err := backup.New(&backup.Options{
+		SourcePath:     source,
+		RepositoryPath: repo,
+		Backend:        backup.BackendArchive,
+		Encrypt:        true,
+		Policy:         backup.Policy{Last: 1},
+	})
+	require.NoError(t, err)
+	require.True(t, b.Available(), "archives don't need any external tools")
+	require.NoError(t, b.Init())
+	require.True(t, b.Created())
+
+	require.NoError(t, b.CreateSnapshot())
+	require.NoError(t, ioutil.WriteFile(task, []byte("code: A-2\n"), 0600))
+	require.NoError(t, b.CreateSnapshot())
+	snapshots, err := b.Snapshots()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	first, err := snapshots[1].Time()
+	require.NoError(t, err)
+	second, err := snapshots[0].Time()
+	require.NoError(t, err)
+	require.True(t, second.After(first), "the latest snapshot is listed first")
+
+	files, err := filepath.Glob(filepath.Join(repo, "*.enc"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	data, err := ioutil.ReadFile(files[0])
+	require.NoError(t, err)
+	require.False(t, bytes.Contains(data, []byte("tasks")), "the archive is encrypted")
+
+	added := filepath.Join(source, "tasks", "A-3.yml")
+	require.NoError(t, ioutil.WriteFile(added, []byte("code: A-3\n"), 0600))
+	require.NoError(t, b.Restore(snapshots[1].ID[:8]))
+	data, err = ioutil.ReadFile(task)
+	require.NoError(t, err)
+	require.Equal(t, "code: A-1\n", string(data))
+	_, err = os.Stat(added)
+	require.True(t, os.IsNotExist(err), "files created after the snapshot should be removed")
+	leftovers, err := filepath.Glob(filepath.Join(dir, ".store-restore*"))
+	require.NoError(t, err)
+	require.Empty(t, leftovers)
+	require.Error(t, b.Restore("unknown"))
+
+	require.NoError(t, b.Forget(true))
+	snapshots, err = b.Snapshots()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	_, err = os.Stat(repo + ".state")
+	require.NoError(t, err, "the time of the last prune should be kept next to the repository")
+	_, err = os.Stat(filepath.Join(source, "backups.state"))
+	require.True(t, os.IsNotExist(err), "the store should not contain any backup state")
+
+	// Without an explicit backend, an existing archive repository is used
+	// even if restic is available.
+	b, err = backup.New(&backup.Options{SourcePath: source, RepositoryPath: repo, ResticPath: "/bin/false"})
+	require.NoError(t, err)
+	require.NoError(t, b.Init())
+	require.False(t, b.Created())
+	snapshots, err = b.Snapshots()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+
+	_, err = backup.New(&backup.Options{SourcePath: source, Backend: "tape"})
+	require.Error(t, err)
+}
+
+func TestDefaultBackendKeepsResticRepository(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clocked-archive")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	repo := filepath.Join(dir, "repo")
+	require.NoError(t, os.MkdirAll(repo, 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(repo, "config"), []byte{}, 0600))
+	path := os.Getenv("PATH")
+	defer os.Setenv("PATH", path)
+	os.Setenv("PATH", dir)
+
+	// Without restic, backups are turned off instead of storing archives
+	// inside of the restic repository.
+	b, err := backup.New(&backup.Options{SourcePath: filepath.Join(dir, "store"), RepositoryPath: repo})
+	require.NoError(t, err)
+	require.False(t, b.Available())
+}
+
+func TestArchiveRestoreKeepsRepositoryInsideSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clocked-archive")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	repo := filepath.Join(dir, "backups")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "config.yml"), []byte("a"), 0600))
+
+	b, err := backup.New(&backup.Options{SourcePath: dir, RepositoryPath: repo, Backend: backup.BackendArchive})
+	require.NoError(t, err)
+	require.NoError(t, b.Init())
+	require.NoError(t, b.CreateSnapshot())
+	snapshots, err := b.Snapshots()
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "config.yml"), []byte("b"), 0600))
+
+	require.NoError(t, b.Restore(snapshots[0].ID))
+	data, err := ioutil.ReadFile(filepath.Join(dir, "config.yml"))
+	require.NoError(t, err)
+	require.Equal(t, "a", string(data))
+	snapshots, err = b.Snapshots()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1, "the repository should still exist")
+}
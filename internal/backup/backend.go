@@ -0,0 +1,24 @@
+package backup
+
+// Names of the available backends.
+const (
+	BackendRestic  = "restic"
+	BackendArchive = "archive"
+)
+
+// Backend stores snapshots of the source folder in a repository.
+type Backend interface {
+	// Available reports if the backend can be used at all.
+	Available() bool
+	// Init prepares the repository and reports if it had to be created.
+	Init() (bool, error)
+	CreateSnapshot() error
+	// Snapshots returns all snapshots, the latest first.
+	Snapshots() ([]Snapshot, error)
+	// Restore writes the files of the given snapshot back into the source
+	// folder.
+	Restore(id string) error
+	// Forget removes all snapshots that aren't covered by the policy. With
+	// prune set, their data is removed from the repository as well.
+	Forget(policy Policy, prune bool) error
+}
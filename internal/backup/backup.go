@@ -1,14 +1,10 @@
 package backup
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"sort"
 
 	"github.com/satori/go.uuid"
 
@@ -19,16 +15,33 @@ type Options struct {
 	PasswordFile   string
 	RepositoryPath string
 	SourcePath     string
-	Log            *logrus.Logger
+	// Backend is the name of the backend storing the snapshots. By default,
+	// restic is used if the repository already is a restic repository or if
+	// restic is installed and the repository doesn't contain archives yet.
+	// Otherwise snapshots are stored as archives.
+	Backend string
+	// ResticPath is the restic binary to use. By default it is looked up
+	// in the PATH.
+	ResticPath string
+	// Encrypt makes the archive backend encrypt its snapshots using the
+	// password file. restic always encrypts its repository.
+	Encrypt bool
+	// StatePath is the file that remembers when old snapshots have been
+	// removed for the last time. It is kept next to the repository so that
+	// it doesn't end up in the snapshots.
+	StatePath string
+	// Policy decides which snapshots are kept by Forget.
+	Policy Policy
+	Log    *logrus.Logger
 }
 
 type Backup struct {
-	passwordFile   string
-	repositoryPath string
-	sourcePath     string
-	resticPath     string
-	log            *logrus.Logger
-	created        bool
+	passwordFile string
+	statePath    string
+	policy       Policy
+	backend      Backend
+	log          *logrus.Logger
+	created      bool
 }
 
 func New(opts *Options) (*Backup, error) {
@@ -45,11 +58,41 @@ func New(opts *Options) (*Backup, error) {
 	if o.RepositoryPath == "" {
 		o.RepositoryPath = fmt.Sprintf("%s_backups", o.SourcePath)
 	}
+	if o.StatePath == "" {
+		o.StatePath = fmt.Sprintf("%s.state", o.RepositoryPath)
+	}
 	b := Backup{
+		passwordFile: o.PasswordFile,
+		statePath:    o.StatePath,
+		policy:       o.Policy,
+		log:          o.Log,
+	}
+	restic := &resticBackend{
+		path:           o.ResticPath,
 		repositoryPath: o.RepositoryPath,
+		passwordFile:   o.PasswordFile,
 		sourcePath:     o.SourcePath,
+	}
+	archive := &archiveBackend{
+		repositoryPath: o.RepositoryPath,
 		passwordFile:   o.PasswordFile,
-		log:            o.Log,
+		sourcePath:     o.SourcePath,
+		encrypt:        o.Encrypt,
+	}
+	switch o.Backend {
+	case BackendRestic:
+		b.backend = restic
+	case BackendArchive:
+		b.backend = archive
+	case "":
+		// An existing restic repository is never turned into an archive
+		// repository. If restic is missing, backups are turned off instead.
+		b.backend = restic
+		if !isResticRepository(o.RepositoryPath) && (isArchiveRepository(o.RepositoryPath) || !restic.Available()) {
+			b.backend = archive
+		}
+	default:
+		return nil, fmt.Errorf("unknown backup backend %s. Use %s or %s", o.Backend, BackendRestic, BackendArchive)
 	}
 
 	if b.log == nil {
@@ -60,27 +103,18 @@ func New(opts *Options) (*Backup, error) {
 }
 
 func (b *Backup) Available() bool {
-	if b.resticPath != "" {
-		return true
-	}
-	path, err := exec.LookPath("restic")
-	if err != nil {
-		return false
-	}
-	if path == "" {
-		return false
-	}
-	b.resticPath = path
-	return true
+	return b.backend.Available()
 }
 
 func (b *Backup) Init() error {
 	if err := b.ensurePasswordFile(); err != nil {
 		return err
 	}
-	if err := b.ensureRepository(); err != nil {
+	created, err := b.backend.Init()
+	if err != nil {
 		return err
 	}
+	b.created = created
 	return nil
 }
 
@@ -88,23 +122,12 @@ func (b *Backup) Created() bool {
 	return b.created
 }
 
-func (b *Backup) createEnv() []string {
-	return []string{
-		fmt.Sprintf("RESTIC_REPOSITORY=%s", b.repositoryPath),
-		fmt.Sprintf("RESTIC_PASSWORD_FILE=%s", b.passwordFile),
-	}
-}
-
 func (b *Backup) CreateSnapshot() error {
-	cmd := exec.Command(b.resticPath, "backup", b.sourcePath)
-	cmd.Env = b.createEnv()
-	return cmd.Run()
+	return b.backend.CreateSnapshot()
 }
 
 func (b *Backup) Restore(id string) error {
-	cmd := exec.Command(b.resticPath, "restore", "--target", filepath.Dir(b.sourcePath), id)
-	cmd.Env = b.createEnv()
-	return cmd.Run()
+	return b.backend.Restore(id)
 }
 
 type ByTime []Snapshot
@@ -122,19 +145,7 @@ func (s ByTime) Swap(i int, j int) {
 }
 
 func (b *Backup) Snapshots() ([]Snapshot, error) {
-	cmd := exec.Command(b.resticPath, "snapshots", "--json")
-	cmd.Env = b.createEnv()
-	var buffer bytes.Buffer
-	cmd.Stdout = &buffer
-	if err := cmd.Run(); err != nil {
-		return nil, err
-	}
-	var result []Snapshot
-	if err := json.NewDecoder(&buffer).Decode(&result); err != nil {
-		return nil, err
-	}
-	sort.Sort(sort.Reverse(ByTime(result)))
-	return result, nil
+	return b.backend.Snapshots()
 }
 
 func (b *Backup) ensurePasswordFile() error {
@@ -151,34 +162,3 @@ func (b *Backup) ensurePasswordFile() error {
 	}
 	return nil
 }
-
-func (b *Backup) ensureRepository() error {
-	configFile := filepath.Join(b.repositoryPath, "config")
-	stats, err := os.Stat(b.repositoryPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return b.createRepository()
-		}
-		return err
-	}
-	if !stats.IsDir() {
-		return fmt.Errorf("%s is not a directory", b.repositoryPath)
-	}
-	if _, err := os.Stat(configFile); err != nil {
-		if os.IsNotExist(err) {
-			return b.createRepository()
-		}
-		return err
-	}
-	return nil
-}
-
-func (b *Backup) createRepository() error {
-	cmd := exec.Command(b.resticPath, "init")
-	cmd.Env = b.createEnv()
-	if err := cmd.Run(); err != nil {
-		return err
-	}
-	b.created = true
-	return nil
-}
@@ -29,6 +29,12 @@ func removeBackupFolder(t *testing.T) {
 	}
 }
 
+// cleanup removes the source and the backups folder once a test is done.
+func cleanup(t *testing.T) {
+	os.RemoveAll(sourceFolder)
+	removeBackupFolder(t)
+}
+
 func createTestRepo(t *testing.T) *backup.Backup {
 	b, err := backup.New(&backup.Options{
 		SourcePath: sourceFolder,
@@ -51,6 +57,7 @@ func createTestRepo(t *testing.T) *backup.Backup {
 func TestBackupInit(t *testing.T) {
 	removeBackupFolder(t)
 	resetSourceFolder(t)
+	defer cleanup(t)
 	opts := backup.Options{}
 	_, err := backup.New(&opts)
 
@@ -77,6 +84,7 @@ func TestBackupInit(t *testing.T) {
 func TestSnapshotCreation(t *testing.T) {
 	removeBackupFolder(t)
 	resetSourceFolder(t)
+	defer cleanup(t)
 	b := createTestRepo(t)
 
 	require.NoError(t, b.CreateSnapshot(), "creating a snapshot should not have caused an error")
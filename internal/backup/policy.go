@@ -0,0 +1,143 @@
+package backup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Policy is the number of snapshots that are kept when old snapshots are
+// forgotten. See `restic help forget` for details.
+type Policy struct {
+	Last    int
+	Hourly  int
+	Daily   int
+	Weekly  int
+	Monthly int
+}
+
+// Empty reports if no snapshots would be kept, in which case nothing is
+// forgotten at all.
+func (p Policy) Empty() bool {
+	return p == Policy{}
+}
+
+func (p Policy) args() []string {
+	var args []string
+	add := func(flag string, n int) {
+		if n > 0 {
+			args = append(args, flag, strconv.Itoa(n))
+		}
+	}
+	add("--keep-last", p.Last)
+	add("--keep-hourly", p.Hourly)
+	add("--keep-daily", p.Daily)
+	add("--keep-weekly", p.Weekly)
+	add("--keep-monthly", p.Monthly)
+	return args
+}
+
+// keep reports for each of the given times, sorted from the latest to the
+// oldest, if the snapshot taken at that time is covered by the policy. Just
+// like restic, the latest snapshot of every hour, day, week or month is
+// kept for as many periods as configured.
+func (p Policy) keep(times []time.Time) []bool {
+	result := make([]bool, len(times))
+	rules := []struct {
+		n   int
+		key func(t time.Time, idx int) string
+	}{
+		{p.Last, func(t time.Time, idx int) string { return strconv.Itoa(idx) }},
+		{p.Hourly, func(t time.Time, idx int) string { return t.Format("2006-01-02 15") }},
+		{p.Daily, func(t time.Time, idx int) string { return t.Format("2006-01-02") }},
+		{p.Weekly, func(t time.Time, idx int) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-%d", year, week)
+		}},
+		{p.Monthly, func(t time.Time, idx int) string { return t.Format("2006-01") }},
+	}
+	for _, rule := range rules {
+		n := rule.n
+		last := ""
+		for idx, t := range times {
+			if n == 0 {
+				break
+			}
+			key := rule.key(t.Local(), idx)
+			if key == last {
+				continue
+			}
+			last = key
+			result[idx] = true
+			n--
+		}
+	}
+	return result
+}
+
+// state is stored next to the repository and remembers what happened
+// across runs of clocked.
+type state struct {
+	LastPrune time.Time `yaml:"last_prune"`
+	// LastSnapshot is the time the scheduler took the last snapshot.
+	LastSnapshot time.Time `yaml:"last_snapshot"`
+	// Pending is set if changes haven't been backed up yet because the
+	// last snapshot was taken less than MinInterval ago.
+	Pending bool `yaml:"pending,omitempty"`
+}
+
+func (b *Backup) loadState() (state, error) {
+	var s state
+	data, err := ioutil.ReadFile(b.statePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return s, err
+	}
+	err = yaml.Unmarshal(data, &s)
+	return s, err
+}
+
+// updateState applies change to the stored state.
+func (b *Backup) updateState(change func(s *state)) error {
+	s, err := b.loadState()
+	if err != nil {
+		return err
+	}
+	change(&s)
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(b.statePath, data, 0600)
+}
+
+// Forget removes all snapshots that aren't covered by the policy. With
+// prune set, the data only referenced by these snapshots is deleted from
+// the repository as well.
+func (b *Backup) Forget(prune bool) error {
+	if b.policy.Empty() {
+		return fmt.Errorf("no retention policy configured")
+	}
+	if err := b.backend.Forget(b.policy, prune); err != nil {
+		return err
+	}
+	if !prune {
+		return nil
+	}
+	return b.updateState(func(s *state) {
+		s.LastPrune = time.Now()
+	})
+}
+
+// LastPrune returns the time old snapshots have been pruned for the last
+// time or the zero time if that has never happened.
+func (b *Backup) LastPrune() (time.Time, error) {
+	s, err := b.loadState()
+	return s.LastPrune, err
+}
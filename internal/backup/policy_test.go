@@ -0,0 +1,19 @@
+package backup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyKeep(t *testing.T) {
+	at := func(day, h int) time.Time {
+		return time.Date(2018, 3, day, h, 0, 0, 0, time.Local)
+	}
+	times := []time.Time{at(3, 12), at(3, 10), at(2, 18), at(2, 9), at(1, 8)}
+	require.Equal(t, []bool{true, false, false, false, false}, Policy{Last: 1}.keep(times))
+	require.Equal(t, []bool{true, false, true, false, false}, Policy{Daily: 2}.keep(times))
+	require.Equal(t, []bool{true, true, true, false, true}, Policy{Last: 2, Daily: 7}.keep(times))
+	require.Equal(t, make([]bool, 5), Policy{}.keep(times))
+}
@@ -0,0 +1,123 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// resticBackend stores snapshots using an external restic binary.
+type resticBackend struct {
+	path           string
+	repositoryPath string
+	passwordFile   string
+	sourcePath     string
+}
+
+// isResticRepository reports if the given folder has been initialized by
+// restic.
+func isResticRepository(repositoryPath string) bool {
+	_, err := os.Stat(filepath.Join(repositoryPath, "config"))
+	return err == nil
+}
+
+func (r *resticBackend) Available() bool {
+	if r.path != "" {
+		return true
+	}
+	path, err := exec.LookPath("restic")
+	if err != nil {
+		return false
+	}
+	if path == "" {
+		return false
+	}
+	r.path = path
+	return true
+}
+
+func (r *resticBackend) Init() (bool, error) {
+	configFile := filepath.Join(r.repositoryPath, "config")
+	stats, err := os.Stat(r.repositoryPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return true, r.createRepository()
+		}
+		return false, err
+	}
+	if !stats.IsDir() {
+		return false, fmt.Errorf("%s is not a directory", r.repositoryPath)
+	}
+	if _, err := os.Stat(configFile); err != nil {
+		if os.IsNotExist(err) {
+			return true, r.createRepository()
+		}
+		return false, err
+	}
+	return false, nil
+}
+
+func (r *resticBackend) createRepository() error {
+	return run(r.command("init"))
+}
+
+func (r *resticBackend) command(args ...string) *exec.Cmd {
+	cmd := exec.Command(r.path, args...)
+	cmd.Env = []string{
+		fmt.Sprintf("RESTIC_REPOSITORY=%s", r.repositoryPath),
+		fmt.Sprintf("RESTIC_PASSWORD_FILE=%s", r.passwordFile),
+	}
+	return cmd
+}
+
+func (r *resticBackend) CreateSnapshot() error {
+	return run(r.command("backup", r.sourcePath))
+}
+
+// run executes the given command and includes the last line it has written
+// to stderr in the error if it fails.
+func run(cmd *exec.Cmd) error {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err == nil {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	if msg := lines[len(lines)-1]; msg != "" {
+		return fmt.Errorf("%s: %s", err.Error(), msg)
+	}
+	return err
+}
+
+func (r *resticBackend) Restore(id string) error {
+	return run(r.command("restore", "--target", filepath.Dir(r.sourcePath), id))
+}
+
+func (r *resticBackend) Snapshots() ([]Snapshot, error) {
+	cmd := r.command("snapshots", "--json")
+	var buffer bytes.Buffer
+	cmd.Stdout = &buffer
+	if err := run(cmd); err != nil {
+		return nil, err
+	}
+	var result []Snapshot
+	if err := json.NewDecoder(&buffer).Decode(&result); err != nil {
+		return nil, err
+	}
+	sort.Sort(sort.Reverse(ByTime(result)))
+	return result, nil
+}
+
+func (r *resticBackend) Forget(policy Policy, prune bool) error {
+	args := append([]string{"forget"}, policy.args()...)
+	if prune {
+		args = append(args, "--prune")
+	}
+	return run(r.command(args...))
+}
@@ -0,0 +1,142 @@
+package backup
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultPruneInterval is used if a retention policy but no prune interval
+// is configured.
+const DefaultPruneInterval = 24 * time.Hour
+
+// Scheduler decides when snapshots are taken. Changes that happen within
+// MinInterval of the last snapshot are collected and included in the next
+// one, which is taken once the interval has passed (see Tick) or when Flush
+// is called. After every snapshot, old snapshots are forgotten and pruned
+// if PruneInterval has passed since that happened the last time.
+//
+// The time of the last snapshot and whether changes are pending are kept
+// in the backup's state file so that short-lived processes like the
+// commands of the CLI share them.
+type Scheduler struct {
+	backup        *Backup
+	MinInterval   time.Duration
+	PruneInterval time.Duration
+	last          time.Time
+	pending       bool
+}
+
+// NewScheduler creates a scheduler for the given backup.
+func NewScheduler(b *Backup, minInterval, pruneInterval time.Duration) *Scheduler {
+	if pruneInterval == 0 {
+		pruneInterval = DefaultPruneInterval
+	}
+	s := &Scheduler{
+		backup:        b,
+		MinInterval:   minInterval,
+		PruneInterval: pruneInterval,
+	}
+	if b != nil {
+		// Without state the next change is backed up right away.
+		if st, err := b.loadState(); err == nil {
+			s.last = st.LastSnapshot
+			s.pending = st.Pending
+		}
+	}
+	return s
+}
+
+// Request notes that the store has changed. The snapshot is taken right
+// away unless the last one was taken less than MinInterval ago.
+func (s *Scheduler) Request() error {
+	if !s.enabled() {
+		return nil
+	}
+	if err := s.markPending(); err != nil {
+		return err
+	}
+	return s.Tick()
+}
+
+// markPending notes that there are changes that haven't been backed up
+// yet.
+func (s *Scheduler) markPending() error {
+	if s.pending {
+		return nil
+	}
+	s.pending = true
+	return s.backup.updateState(func(st *state) {
+		st.Pending = true
+	})
+}
+
+// enabled reports if snapshots can be taken at all.
+func (s *Scheduler) enabled() bool {
+	return s.backup != nil && s.backup.Available()
+}
+
+// Pending reports if there are changes that haven't been backed up yet.
+func (s *Scheduler) Pending() bool {
+	return s.pending
+}
+
+// Due returns the time the pending snapshot will be taken at.
+func (s *Scheduler) Due() time.Time {
+	return s.last.Add(s.MinInterval)
+}
+
+// Tick takes the pending snapshot if MinInterval has passed.
+func (s *Scheduler) Tick() error {
+	if !s.pending || time.Now().Before(s.Due()) {
+		return nil
+	}
+	return s.Flush()
+}
+
+// PruneError is returned by Flush if the snapshot has been taken but old
+// snapshots couldn't be forgotten or pruned.
+type PruneError struct {
+	Err error
+}
+
+func (e *PruneError) Error() string {
+	return fmt.Sprintf("failed to remove old snapshots: %s", e.Err.Error())
+}
+
+// Flush takes the pending snapshot right away. If only removing old
+// snapshots fails, a *PruneError is returned.
+func (s *Scheduler) Flush() error {
+	if !s.pending {
+		return nil
+	}
+	if err := s.backup.CreateSnapshot(); err != nil {
+		return err
+	}
+	s.pending = false
+	s.last = time.Now()
+	if err := s.backup.updateState(func(st *state) {
+		st.LastSnapshot = s.last
+		st.Pending = false
+	}); err != nil {
+		return err
+	}
+	if err := s.maintain(); err != nil {
+		return &PruneError{Err: err}
+	}
+	return nil
+}
+
+// maintain forgets and prunes old snapshots if that is due.
+func (s *Scheduler) maintain() error {
+	if s.backup.policy.Empty() {
+		return nil
+	}
+	last, err := s.backup.LastPrune()
+	if err != nil {
+		return err
+	}
+	if time.Now().Sub(last) < s.PruneInterval {
+		return nil
+	}
+	return s.backup.Forget(true)
+}
@@ -0,0 +1,215 @@
+package backup_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zerok/clocked/internal/backup"
+)
+
+// fakeRestic records all calls inside the repository instead of creating
+// snapshots.
+const fakeRestic = `#!/bin/sh
+/bin/mkdir -p "$RESTIC_REPOSITORY"
+echo "$@" >> "$RESTIC_REPOSITORY/calls"
+if [ -e "$RESTIC_REPOSITORY/fail" ] || [ -e "$RESTIC_REPOSITORY/fail-$1" ]; then
+	echo "Fatal: unable to create lock" >&2
+	exit 1
+fi
+case "$1" in
+init) : > "$RESTIC_REPOSITORY/config" ;;
+snapshots) echo '[{"time":"2018-03-01T10:00:00Z","id":"abc","hostname":"box","paths":["/store"]}]' ;;
+esac
+`
+
+// createFakeRepo returns a backup using the fake restic, a function
+// returning all calls of restic, one that makes all further calls of the
+// given restic command (or of all commands) fail and one that removes the
+// repository.
+func createFakeRepo(t *testing.T, policy backup.Policy) (*backup.Backup, func() []string, func(command string), func()) {
+	dir, err := ioutil.TempDir("", "clocked-backup")
+	require.NoError(t, err)
+	restic := filepath.Join(dir, "restic")
+	require.NoError(t, ioutil.WriteFile(restic, []byte(fakeRestic), 0700))
+	source := filepath.Join(dir, "store")
+	require.NoError(t, os.MkdirAll(source, 0700))
+	repo := filepath.Join(dir, "repo")
+	b, err := backup.New(&backup.Options{
+		SourcePath:     source,
+		RepositoryPath: repo,
+		ResticPath:     restic,
+		Policy:         policy,
+	})
+	require.NoError(t, err)
+	require.True(t, b.Available())
+	require.NoError(t, b.Init())
+	calls := func() []string {
+		data, err := ioutil.ReadFile(filepath.Join(repo, "calls"))
+		require.NoError(t, err)
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+	fail := func(command string) {
+		name := "fail"
+		if command != "" {
+			name += "-" + command
+		}
+		require.NoError(t, ioutil.WriteFile(filepath.Join(repo, name), nil, 0600))
+	}
+	return b, calls, fail, func() { os.RemoveAll(dir) }
+}
+
+func TestScheduler(t *testing.T) {
+	b, calls, _, cleanup := createFakeRepo(t, backup.Policy{Hourly: 24, Daily: 7})
+	defer cleanup()
+	require.True(t, b.Created())
+
+	s := backup.NewScheduler(b, time.Hour, 0)
+	require.Equal(t, backup.DefaultPruneInterval, s.PruneInterval)
+	require.NoError(t, s.Request())
+	require.False(t, s.Pending())
+	require.Len(t, calls(), 3, "the first snapshot is taken right away and old ones are pruned")
+	require.Equal(t, "forget --keep-hourly 24 --keep-daily 7 --prune", calls()[2])
+	last, err := b.LastPrune()
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now(), last, time.Minute)
+
+	// Changes within the interval are collected into a single snapshot.
+	require.NoError(t, s.Request())
+	require.NoError(t, s.Request())
+	require.NoError(t, s.Tick())
+	require.True(t, s.Pending())
+	require.Len(t, calls(), 3)
+	require.NoError(t, s.Flush())
+	require.False(t, s.Pending())
+	require.Len(t, calls(), 4, "pruning isn't due yet")
+	require.True(t, strings.HasPrefix(calls()[3], "backup "))
+	require.NoError(t, s.Flush(), "nothing is pending")
+	require.Len(t, calls(), 4)
+
+	snapshots, err := b.Snapshots()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	require.Equal(t, "abc", snapshots[0].ID)
+}
+
+func TestSchedulerAcrossProcesses(t *testing.T) {
+	b, calls, _, cleanup := createFakeRepo(t, backup.Policy{})
+	defer cleanup()
+
+	// Every CLI command uses a new scheduler.
+	require.NoError(t, backup.NewScheduler(b, time.Hour, 0).Request())
+	require.NoError(t, backup.NewScheduler(b, time.Hour, 0).Request())
+	require.Len(t, calls(), 2, "only the first change is backed up right away")
+
+	s := backup.NewScheduler(b, time.Hour, 0)
+	require.True(t, s.Pending(), "the second change is still pending")
+	require.WithinDuration(t, time.Now().Add(time.Hour), s.Due(), time.Minute)
+	require.NoError(t, s.Flush())
+	require.Len(t, calls(), 3)
+	require.False(t, backup.NewScheduler(b, time.Hour, 0).Pending())
+}
+
+func TestForgetWithoutPolicy(t *testing.T) {
+	b, calls, _, cleanup := createFakeRepo(t, backup.Policy{})
+	defer cleanup()
+	require.Error(t, b.Forget(false))
+
+	s := backup.NewScheduler(b, 0, 0)
+	require.NoError(t, s.Request())
+	require.NoError(t, s.Request())
+	require.Equal(t, []string{"init"}, calls()[:1])
+	require.Len(t, calls(), 3, "every change is backed up without forgetting anything")
+}
+
+// waitFor polls cond until it is true or a second has passed.
+func waitFor(t *testing.T, cond func() bool, msg string) {
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWorker(t *testing.T) {
+	b, calls, _, cleanup := createFakeRepo(t, backup.Policy{})
+	defer cleanup()
+	changes := make(chan struct{}, 100)
+	w := backup.NewWorker(backup.NewScheduler(b, time.Hour, 0), func() {
+		changes <- struct{}{}
+	})
+	w.Request()
+	waitFor(t, func() bool { return len(w.Results()) == 1 }, "the snapshot should have been taken")
+	require.NotEmpty(t, changes, "status changes are reported")
+	status := w.Status()
+	require.False(t, status.Running)
+	require.False(t, status.Pending)
+	require.NoError(t, status.Last.Err)
+
+	// Within the interval, the snapshot is only taken when the worker is
+	// closed.
+	w.Request()
+	w.Request()
+	waitFor(t, func() bool { return w.Status().Pending }, "a snapshot should be pending")
+	w.Close()
+	require.Len(t, w.Results(), 2)
+	require.Len(t, calls(), 3)
+
+	// Requests after closing the worker are ignored.
+	w.Request()
+	w.Close()
+	require.Len(t, calls(), 3)
+}
+
+func TestWorkerFailure(t *testing.T) {
+	b, calls, fail, cleanup := createFakeRepo(t, backup.Policy{})
+	defer cleanup()
+	fail("")
+	w := backup.NewWorker(backup.NewScheduler(b, 0, 0), nil)
+	w.Request()
+	waitFor(t, func() bool { return len(w.Results()) == 1 }, "the snapshot should have been attempted")
+	status := w.Status()
+	require.EqualError(t, status.Last.Err, "exit status 1: Fatal: unable to create lock")
+	require.True(t, status.Pending, "the changes are still pending")
+
+	// Further requests wait for the retry interval.
+	w.Request()
+	time.Sleep(20 * time.Millisecond)
+	require.Len(t, w.Results(), 1)
+	require.Len(t, calls(), 2)
+	w.Flush()
+	waitFor(t, func() bool { return len(w.Results()) == 2 }, "flushing should retry right away")
+	w.Close()
+	require.Len(t, w.Results(), 3, "closing the worker makes a final attempt")
+}
+
+func TestWorkerPruneFailure(t *testing.T) {
+	b, _, fail, cleanup := createFakeRepo(t, backup.Policy{Daily: 7})
+	defer cleanup()
+	fail("forget")
+
+	w := backup.NewWorker(backup.NewScheduler(b, 0, 0), nil)
+	w.Request()
+	waitFor(t, func() bool { return len(w.Results()) == 1 }, "the snapshot should have been taken")
+	status := w.Status()
+	require.NoError(t, status.Last.Err, "the snapshot itself succeeded")
+	require.EqualError(t, status.Last.PruneErr, "exit status 1: Fatal: unable to create lock")
+	require.False(t, status.Pending, "the snapshot isn't retried")
+	w.Close()
+	require.Len(t, w.Results(), 1)
+}
+
+func TestResticErrorsIncludeStderr(t *testing.T) {
+	b, _, fail, cleanup := createFakeRepo(t, backup.Policy{})
+	defer cleanup()
+	fail("")
+	_, err := b.Snapshots()
+	require.EqualError(t, err, "exit status 1: Fatal: unable to create lock")
+	require.EqualError(t, b.Restore("abc"), "exit status 1: Fatal: unable to create lock")
+}
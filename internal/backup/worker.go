@@ -0,0 +1,202 @@
+package backup
+
+import (
+	"sync"
+	"time"
+)
+
+// RetryInterval is the time the worker waits before trying again after a
+// snapshot has failed.
+const RetryInterval = time.Minute
+
+// maxResults is the number of results kept in the worker's log.
+const maxResults = 100
+
+// Result describes a single attempt to take a snapshot.
+type Result struct {
+	Time     time.Time
+	Duration time.Duration
+	Err      error
+	// PruneErr is set if the snapshot has been taken but old snapshots
+	// couldn't be removed.
+	PruneErr error
+}
+
+// Status is the current state of a worker.
+type Status struct {
+	// Running is set while a snapshot is being taken.
+	Running bool
+	// Pending is set if there are changes that haven't been backed up yet.
+	Pending bool
+	// Last is the result of the latest attempt, if any.
+	Last *Result
+}
+
+// Worker takes the snapshots requested from a Scheduler in the background
+// so that callers don't have to wait for them. Failed snapshots are
+// retried after RetryInterval.
+type Worker struct {
+	scheduler *Scheduler
+	requests  chan struct{}
+	flushes   chan struct{}
+	done      chan struct{}
+	retryAt   time.Time
+	// onChange is called from the worker's goroutine whenever the status
+	// has changed.
+	onChange func()
+
+	mu      sync.Mutex
+	status  Status
+	results []Result
+	closed  bool
+}
+
+// NewWorker creates a worker for the given scheduler and starts it.
+func NewWorker(s *Scheduler, onChange func()) *Worker {
+	w := &Worker{
+		scheduler: s,
+		requests:  make(chan struct{}, 1),
+		flushes:   make(chan struct{}, 1),
+		done:      make(chan struct{}),
+		onChange:  onChange,
+	}
+	go w.run()
+	return w
+}
+
+// Request notes that the store has changed without waiting for the
+// snapshot. Requests that arrive while the worker is busy are coalesced.
+func (w *Worker) Request() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	select {
+	case w.requests <- struct{}{}:
+	default:
+	}
+}
+
+// Flush takes the pending snapshot right away, even if MinInterval or
+// RetryInterval haven't passed yet.
+func (w *Worker) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	select {
+	case w.flushes <- struct{}{}:
+	default:
+	}
+}
+
+// Close takes a final snapshot if changes are pending and stops the
+// worker.
+func (w *Worker) Close() {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	close(w.requests)
+	w.mu.Unlock()
+	<-w.done
+}
+
+// Status returns the current state of the worker.
+func (w *Worker) Status() Status {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+// Results returns the latest results, the newest first.
+func (w *Worker) Results() []Result {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	result := make([]Result, 0, len(w.results))
+	for idx := len(w.results) - 1; idx >= 0; idx-- {
+		result = append(result, w.results[idx])
+	}
+	return result
+}
+
+func (w *Worker) run() {
+	defer close(w.done)
+	s := w.scheduler
+	for {
+		var timer <-chan time.Time
+		if s.pending {
+			at := s.Due()
+			if w.retryAt.After(at) {
+				at = w.retryAt
+			}
+			timer = time.After(at.Sub(time.Now()))
+		}
+		select {
+		case _, ok := <-w.requests:
+			if !ok {
+				if s.pending {
+					w.snapshot()
+				}
+				return
+			}
+			if !s.enabled() {
+				continue
+			}
+			// Even if the state can't be written, the snapshot is
+			// still taken before the worker is closed.
+			s.markPending()
+			w.update(false)
+			if !time.Now().Before(s.Due()) && !time.Now().Before(w.retryAt) {
+				w.snapshot()
+			}
+		case <-w.flushes:
+			if s.pending {
+				w.snapshot()
+			}
+		case <-timer:
+			w.snapshot()
+		}
+	}
+}
+
+func (w *Worker) snapshot() {
+	w.update(true)
+	start := time.Now()
+	err := w.scheduler.Flush()
+	var pruneErr error
+	if e, ok := err.(*PruneError); ok {
+		// Pruning is retried after the next snapshot.
+		pruneErr = e.Err
+		err = nil
+	}
+	if err != nil {
+		w.retryAt = time.Now().Add(RetryInterval)
+	} else {
+		w.retryAt = time.Time{}
+	}
+	result := Result{Time: start, Duration: time.Since(start), Err: err, PruneErr: pruneErr}
+	w.mu.Lock()
+	w.results = append(w.results, result)
+	if len(w.results) > maxResults {
+		w.results = w.results[len(w.results)-maxResults:]
+	}
+	w.status.Last = &result
+	w.mu.Unlock()
+	w.update(false)
+}
+
+// update refreshes the status and notifies about the change.
+func (w *Worker) update(running bool) {
+	w.mu.Lock()
+	w.status.Running = running
+	w.status.Pending = w.scheduler.pending
+	w.mu.Unlock()
+	if w.onChange != nil {
+		w.onChange()
+	}
+}
@@ -0,0 +1,52 @@
+// Package balance compares the time actually worked with the targets
+// configured for each day in order to calculate remaining time and
+// overtime.
+package balance
+
+import (
+	"time"
+
+	"github.com/zerok/clocked/internal/config"
+	"github.com/zerok/clocked/internal/database"
+)
+
+type Balance struct {
+	Target time.Duration
+	Actual time.Duration
+}
+
+// Overtime is the time worked beyond the target. A negative value means that
+// the target hasn't been reached yet.
+func (b Balance) Overtime() time.Duration {
+	return b.Actual - b.Target
+}
+
+// Remaining is the time left until the target is reached.
+func (b Balance) Remaining() time.Duration {
+	if b.Actual >= b.Target {
+		return 0
+	}
+	return b.Target - b.Actual
+}
+
+// Calculate sums up the targets of all days within [from, to) and compares
+// them to the time booked in the given summary. Days after now are ignored
+// and a booking that is still running is counted up until now.
+func Calculate(cfg *config.Config, summary database.Summary, from, to, now time.Time) Balance {
+	var b Balance
+	for day := database.StartOfDay(from); day.Before(to) && !day.After(now); day = day.AddDate(0, 0, 1) {
+		b.Target += cfg.TargetFor(day)
+		b.Actual += summary.DailyTotals[day.Format(database.DayFormat)]
+	}
+	for _, bk := range summary.Bookings {
+		if bk.Start != nil && bk.Stop == nil && bk.Start.Before(now) {
+			b.Actual += now.Sub(*bk.Start)
+		}
+	}
+	return b
+}
+
+// ForPeriod generates the summary for [from, to) and calculates its balance.
+func ForPeriod(db database.Database, cfg *config.Config, from, to, now time.Time) Balance {
+	return Calculate(cfg, db.GenerateSummary(from, to), from, to, now)
+}
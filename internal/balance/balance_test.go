@@ -0,0 +1,39 @@
+package balance_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zerok/clocked/internal/balance"
+	"github.com/zerok/clocked/internal/config"
+	"github.com/zerok/clocked/internal/database"
+)
+
+func TestCalculate(t *testing.T) {
+	cfg := &config.Config{
+		Targets: config.Targets{
+			Monday:  8 * time.Hour,
+			Tuesday: 8 * time.Hour,
+		},
+		Holidays: []string{"2017-10-10"},
+	}
+	// 2017-10-09 is a Monday, the following Tuesday a holiday.
+	from := time.Date(2017, 10, 9, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+	now := time.Date(2017, 10, 10, 12, 0, 0, 0, time.UTC)
+	start := time.Date(2017, 10, 10, 10, 0, 0, 0, time.UTC)
+	summary := database.Summary{
+		DailyTotals: map[string]time.Duration{
+			"2017-10-09": 9 * time.Hour,
+		},
+		Bookings: []database.TaskBooking{
+			{Code: "a", Start: &start},
+		},
+	}
+	b := balance.Calculate(cfg, summary, from, to, now)
+	require.Equal(t, 8*time.Hour, b.Target, "holidays should not have a target")
+	require.Equal(t, 11*time.Hour, b.Actual, "the running booking should be counted until now")
+	require.Equal(t, 3*time.Hour, b.Overtime())
+	require.Equal(t, time.Duration(0), b.Remaining())
+}
@@ -0,0 +1,219 @@
+// Package billing turns the time booked within a period into invoices using
+// the hourly rates and clients configured in the billing section of the
+// configuration.
+package billing
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/config"
+	"github.com/zerok/clocked/internal/database"
+)
+
+const (
+	RoundUp      = "up"
+	RoundDown    = "down"
+	RoundNearest = "nearest"
+)
+
+// MissingTaskTitle is used as title of lines whose task can't be found
+// anymore.
+const MissingTaskTitle = "(task not found)"
+
+// Amount is a sum of money in cents.
+type Amount int64
+
+// AmountFromFloat converts e.g. a rate given in the configuration.
+func AmountFromFloat(f float64) Amount {
+	return Amount(math.Floor(f*100 + 0.5))
+}
+
+func (a Amount) String() string {
+	sign := ""
+	if a < 0 {
+		sign = "-"
+		a = -a
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, a/100, a%100)
+}
+
+// Line is the time booked on a single task.
+type Line struct {
+	Code  string
+	Title string
+	// Booked is the time actually booked while Duration has been rounded
+	// and is used for calculating the amount.
+	Booked   time.Duration
+	Duration time.Duration
+	// Rate is the hourly rate.
+	Rate   Amount
+	Amount Amount
+}
+
+// Invoice contains all lines of a single client. Tasks that don't belong to
+// any client are collected in an invoice with an empty client name.
+type Invoice struct {
+	Client   config.Client
+	From     time.Time
+	To       time.Time
+	Currency string
+	Lines    []Line
+	Duration time.Duration
+	Total    Amount
+}
+
+// Round rounds d to the given unit.
+func Round(d time.Duration, unit time.Duration, mode string) (time.Duration, error) {
+	if unit <= 0 {
+		return d, nil
+	}
+	rest := d % unit
+	if rest == 0 {
+		return d, nil
+	}
+	switch mode {
+	case "", RoundUp:
+		return d - rest + unit, nil
+	case RoundDown:
+		return d - rest, nil
+	case RoundNearest:
+		if rest*2 >= unit {
+			return d - rest + unit, nil
+		}
+		return d - rest, nil
+	default:
+		return d, fmt.Errorf("unsupported rounding mode %s", mode)
+	}
+}
+
+func matchesCode(pattern, code string) bool {
+	ok, err := path.Match(pattern, code)
+	return err == nil && ok
+}
+
+// ClientOf returns the first client the given task belongs to.
+func ClientOf(cfg config.Billing, t clocked.Task) (config.Client, bool) {
+	for _, c := range cfg.Clients {
+		for _, pattern := range c.Codes {
+			if matchesCode(pattern, t.Code) {
+				return c, true
+			}
+		}
+		for _, tag := range c.Tags {
+			if t.HasTag(tag) {
+				return c, true
+			}
+		}
+	}
+	return config.Client{}, false
+}
+
+// RateOf returns the hourly rate of the given task. The first matching rate
+// rule wins, followed by the rate of the task's client and the default
+// rate.
+func RateOf(cfg config.Billing, t clocked.Task) Amount {
+	for _, r := range cfg.Rates {
+		if (r.Code != "" && matchesCode(r.Code, t.Code)) || (r.Tag != "" && t.HasTag(r.Tag)) {
+			return AmountFromFloat(r.Rate)
+		}
+	}
+	if c, ok := ClientOf(cfg, t); ok && c.Rate > 0 {
+		return AmountFromFloat(c.Rate)
+	}
+	return AmountFromFloat(cfg.DefaultRate)
+}
+
+// Generate creates an invoice for every client with bookings between from
+// and to. Rounding is applied to the time booked on each task per day.
+// Running bookings are ignored.
+func Generate(db database.Database, cfg config.Billing, from, to time.Time) ([]Invoice, error) {
+	summary := db.GenerateSummary(from, to)
+	type key struct {
+		code string
+		day  string
+	}
+	daily := make(map[key]time.Duration)
+	for _, b := range summary.Bookings {
+		if b.Stop == nil {
+			continue
+		}
+		daily[key{b.Code, b.Start.In(from.Location()).Format(database.DayFormat)}] += b.Duration()
+	}
+	lines := make(map[string]*Line)
+	for k, d := range daily {
+		rounded, err := Round(d, cfg.Rounding, cfg.RoundingMode)
+		if err != nil {
+			return nil, err
+		}
+		l, found := lines[k.code]
+		if !found {
+			l = &Line{Code: k.code}
+			lines[k.code] = l
+		}
+		l.Booked += d
+		l.Duration += rounded
+	}
+
+	invoices := make(map[string]*Invoice)
+	for code, l := range lines {
+		// The time booked on tasks that have been deleted in the
+		// meantime is still billed. Only their code is known.
+		task, found := db.TaskByCode(code)
+		if !found {
+			task = clocked.Task{Code: code, Title: MissingTaskTitle}
+		}
+		client, _ := ClientOf(cfg, task)
+		inv, found := invoices[client.Name]
+		if !found {
+			inv = &Invoice{Client: client, From: from, To: to, Currency: cfg.Currency}
+			invoices[client.Name] = inv
+		}
+		l.Title = task.Title
+		l.Rate = RateOf(cfg, task)
+		l.Amount = Amount(math.Floor(l.Duration.Hours()*float64(l.Rate) + 0.5))
+		inv.Lines = append(inv.Lines, *l)
+		inv.Duration += l.Duration
+		inv.Total += l.Amount
+	}
+
+	result := make([]Invoice, 0, len(invoices))
+	for _, inv := range invoices {
+		sort.Sort(byCode(inv.Lines))
+		result = append(result, *inv)
+	}
+	sort.Sort(byClient(result))
+	return result, nil
+}
+
+type byCode []Line
+
+func (l byCode) Len() int {
+	return len(l)
+}
+
+func (l byCode) Less(i, j int) bool {
+	return l[i].Code < l[j].Code
+}
+
+func (l byCode) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+}
+
+type byClient []Invoice
+
+func (l byClient) Len() int {
+	return len(l)
+}
+
+func (l byClient) Less(i, j int) bool {
+	return l[i].Client.Name < l[j].Client.Name
+}
+
+func (l byClient) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+}
@@ -0,0 +1,178 @@
+package billing
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/config"
+	"github.com/zerok/clocked/internal/database"
+)
+
+func booking(start time.Time, d time.Duration) clocked.Booking {
+	var b clocked.Booking
+	b.SetStart(start)
+	b.SetStop(start.Add(d))
+	return b
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		d        time.Duration
+		mode     string
+		expected time.Duration
+	}{
+		{50 * time.Minute, RoundUp, time.Hour},
+		{50 * time.Minute, RoundDown, 45 * time.Minute},
+		{52 * time.Minute, RoundNearest, 45 * time.Minute},
+		{53 * time.Minute, RoundNearest, time.Hour},
+		{45 * time.Minute, RoundUp, 45 * time.Minute},
+	}
+	for _, test := range tests {
+		d, err := Round(test.d, 15*time.Minute, test.mode)
+		require.NoError(t, err)
+		require.Equal(t, test.expected, d, "%s %s", test.d, test.mode)
+	}
+	d, err := Round(7*time.Minute, 0, RoundUp)
+	require.NoError(t, err)
+	require.Equal(t, 7*time.Minute, d)
+	_, err = Round(7*time.Minute, time.Minute*15, "sideways")
+	require.Error(t, err)
+}
+
+func TestAmount(t *testing.T) {
+	require.Equal(t, Amount(9550), AmountFromFloat(95.5))
+	require.Equal(t, "95.50", Amount(9550).String())
+	require.Equal(t, "-0.05", Amount(-5).String())
+}
+
+var testConfig = config.Billing{
+	Currency:    "EUR",
+	DefaultRate: 80,
+	Rounding:    15 * time.Minute,
+	Clients: []config.Client{
+		{Name: "ACME", Rate: 100, Codes: []string{"ACME-*"}, Address: "ACME Inc.\nMain Street 1"},
+		{Name: "Globex", Tags: []string{"globex"}},
+	},
+	Rates: []config.RateConfig{
+		{Tag: "review", Rate: 50},
+	},
+}
+
+func TestRates(t *testing.T) {
+	require.Equal(t, Amount(10000), RateOf(testConfig, clocked.Task{Code: "ACME-1"}))
+	require.Equal(t, Amount(5000), RateOf(testConfig, clocked.Task{Code: "ACME-2", Tags: []string{"review"}}))
+	require.Equal(t, Amount(8000), RateOf(testConfig, clocked.Task{Code: "G-1", Tags: []string{"globex"}}))
+	c, ok := ClientOf(testConfig, clocked.Task{Code: "G-1", Tags: []string{"globex"}})
+	require.True(t, ok)
+	require.Equal(t, "Globex", c.Name)
+	_, ok = ClientOf(testConfig, clocked.Task{Code: "X-1"})
+	require.False(t, ok)
+}
+
+func generateTestInvoices(t *testing.T) []Invoice {
+	db := database.NewInMemory()
+	day := time.Date(2018, 3, 1, 9, 0, 0, 0, time.Local)
+	require.NoError(t, db.AddTask(clocked.Task{Code: "ACME-1", Title: "Fix | bug", Bookings: []clocked.Booking{
+		booking(day, 50*time.Minute),
+		booking(day.Add(2*time.Hour), 20*time.Minute),
+		booking(day.AddDate(0, 0, 1), 10*time.Minute),
+		// Outside of the period.
+		booking(day.AddDate(0, 0, 10), time.Hour),
+	}}))
+	require.NoError(t, db.AddTask(clocked.Task{Code: "ACME-2", Tags: []string{"review"}, Bookings: []clocked.Booking{booking(day, 30*time.Minute)}}))
+	require.NoError(t, db.AddTask(clocked.Task{Code: "X-1", Bookings: []clocked.Booking{booking(day, time.Hour)}}))
+	require.NoError(t, db.AddTask(clocked.Task{Code: "X-2"}))
+	from := database.StartOfDay(day)
+	invoices, err := Generate(db, testConfig, from, from.AddDate(0, 0, 7))
+	require.NoError(t, err)
+	return invoices
+}
+
+func TestGenerate(t *testing.T) {
+	invoices := generateTestInvoices(t)
+	require.Len(t, invoices, 2)
+	require.Equal(t, "", invoices[0].Client.Name)
+	require.Equal(t, []Line{{Code: "X-1", Booked: time.Hour, Duration: time.Hour, Rate: 8000, Amount: 8000}}, invoices[0].Lines)
+
+	acme := invoices[1]
+	require.Equal(t, "ACME", acme.Client.Name)
+	require.Len(t, acme.Lines, 2)
+	// 70 minutes on the first day are rounded to 75, 10 minutes on the
+	// second day to 15.
+	require.Equal(t, 80*time.Minute, acme.Lines[0].Booked)
+	require.Equal(t, 90*time.Minute, acme.Lines[0].Duration)
+	require.Equal(t, Amount(15000), acme.Lines[0].Amount)
+	require.Equal(t, Amount(2500), acme.Lines[1].Amount)
+	require.Equal(t, Amount(17500), acme.Total)
+	require.Equal(t, 2*time.Hour, acme.Duration)
+}
+
+func TestWrite(t *testing.T) {
+	invoices := generateTestInvoices(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, invoices, FormatCSV))
+	require.Equal(t, `client,code,title,hours,rate,amount,currency
+,X-1,,1.00,80.00,80.00,EUR
+ACME,ACME-1,Fix | bug,1.50,100.00,150.00,EUR
+ACME,ACME-2,,0.50,50.00,25.00,EUR
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, Write(&buf, invoices, FormatJSON))
+	var decoded []jsonInvoice
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Equal(t, "2018-03-07", decoded[1].To)
+	require.Equal(t, "175.00", decoded[1].Total)
+	require.Equal(t, int64(4800), decoded[1].Lines[0].BookedSeconds)
+
+	buf.Reset()
+	require.NoError(t, Write(&buf, invoices, FormatMarkdown))
+	require.Contains(t, buf.String(), "# Invoice for (no client)")
+	require.Contains(t, buf.String(), "| ACME-1 Fix \\| bug | 1.50 | 100.00 | 150.00 |")
+	require.Contains(t, buf.String(), "| **Total** | **2.00** | | **175.00 EUR** |")
+
+	buf.Reset()
+	require.NoError(t, Write(&buf, invoices, FormatHTML))
+	require.Contains(t, buf.String(), "<h1>Invoice for ACME</h1>")
+	require.Contains(t, buf.String(), "ACME Inc.<br>Main Street 1<br>")
+	require.Equal(t, 2, strings.Count(buf.String(), "<section>"))
+
+	require.Error(t, Write(&buf, invoices, "pdf"))
+}
+
+// forgetfulDatabase doesn't find the given task by its code anymore, as if
+// it had been deleted after its bookings were summarized.
+type forgetfulDatabase struct {
+	*database.InMemory
+	forgotten string
+}
+
+func (d forgetfulDatabase) TaskByCode(code string) (clocked.Task, bool) {
+	if code == d.forgotten {
+		return clocked.Task{}, false
+	}
+	return d.InMemory.TaskByCode(code)
+}
+
+func TestGenerateWithMissingTask(t *testing.T) {
+	db := database.NewInMemory()
+	day := time.Date(2018, 3, 1, 9, 0, 0, 0, time.Local)
+	require.NoError(t, db.AddTask(clocked.Task{Code: "ACME_1*", Title: "Fix", Bookings: []clocked.Booking{booking(day, time.Hour)}}))
+	require.NoError(t, db.AddTask(clocked.Task{Code: "ACME-9", Bookings: []clocked.Booking{booking(day, time.Hour)}}))
+	from := database.StartOfDay(day)
+	invoices, err := Generate(forgetfulDatabase{InMemory: db, forgotten: "ACME-9"}, testConfig, from, from.AddDate(0, 0, 7))
+	require.NoError(t, err)
+	require.Len(t, invoices, 2)
+	require.Equal(t, "ACME", invoices[1].Client.Name, "the client is still derived from the code")
+	require.Equal(t, MissingTaskTitle, invoices[1].Lines[0].Title)
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, invoices, FormatMarkdown))
+	require.Contains(t, buf.String(), "| ACME\\_1\\* Fix |")
+}
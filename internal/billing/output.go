@@ -0,0 +1,199 @@
+package billing
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/zerok/clocked/internal/database"
+)
+
+const (
+	FormatCSV      = "csv"
+	FormatJSON     = "json"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// Write renders the given invoices in one of the supported formats.
+func Write(w io.Writer, invoices []Invoice, format string) error {
+	switch format {
+	case FormatCSV:
+		return writeCSV(w, invoices)
+	case FormatJSON:
+		return writeJSON(w, invoices)
+	case FormatMarkdown:
+		return writeMarkdown(w, invoices)
+	case FormatHTML:
+		return htmlTemplate.Execute(w, invoices)
+	default:
+		return fmt.Errorf("unsupported format %s", format)
+	}
+}
+
+// formatHours renders a duration as decimal hours as usually found on
+// invoices.
+func formatHours(d time.Duration) string {
+	return fmt.Sprintf("%.2f", d.Hours())
+}
+
+// LastDay returns the last day of the invoice's period as To is exclusive.
+func (i Invoice) LastDay() time.Time {
+	return i.To.AddDate(0, 0, -1)
+}
+
+// ClientName returns a placeholder for tasks without client.
+func (i Invoice) ClientName() string {
+	if i.Client.Name == "" {
+		return "(no client)"
+	}
+	return i.Client.Name
+}
+
+func writeCSV(w io.Writer, invoices []Invoice) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"client", "code", "title", "hours", "rate", "amount", "currency"})
+	for _, inv := range invoices {
+		for _, l := range inv.Lines {
+			out.Write([]string{inv.Client.Name, l.Code, l.Title, formatHours(l.Duration), l.Rate.String(), l.Amount.String(), inv.Currency})
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+type jsonLine struct {
+	Code          string  `json:"code"`
+	Title         string  `json:"title"`
+	BookedSeconds int64   `json:"booked_seconds"`
+	Hours         float64 `json:"hours"`
+	Rate          string  `json:"rate"`
+	Amount        string  `json:"amount"`
+}
+
+type jsonInvoice struct {
+	Client   string     `json:"client"`
+	Address  string     `json:"address,omitempty"`
+	From     string     `json:"from"`
+	To       string     `json:"to"`
+	Currency string     `json:"currency"`
+	Hours    float64    `json:"hours"`
+	Total    string     `json:"total"`
+	Lines    []jsonLine `json:"lines"`
+}
+
+func writeJSON(w io.Writer, invoices []Invoice) error {
+	result := make([]jsonInvoice, 0, len(invoices))
+	for _, inv := range invoices {
+		ji := jsonInvoice{
+			Client:   inv.Client.Name,
+			Address:  inv.Client.Address,
+			From:     inv.From.Format(database.DayFormat),
+			To:       inv.LastDay().Format(database.DayFormat),
+			Currency: inv.Currency,
+			Hours:    inv.Duration.Hours(),
+			Total:    inv.Total.String(),
+			Lines:    make([]jsonLine, 0, len(inv.Lines)),
+		}
+		for _, l := range inv.Lines {
+			ji.Lines = append(ji.Lines, jsonLine{
+				Code:          l.Code,
+				Title:         l.Title,
+				BookedSeconds: int64(l.Booked.Seconds()),
+				Hours:         l.Duration.Hours(),
+				Rate:          l.Rate.String(),
+				Amount:        l.Amount.String(),
+			})
+		}
+		result = append(result, ji)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
+
+// escapeMarkdown makes sure that codes and titles don't break the table or
+// are rendered as formatting.
+var escapeMarkdown = strings.NewReplacer(
+	"\\", "\\\\",
+	"|", "\\|",
+	"*", "\\*",
+	"_", "\\_",
+	"`", "\\`",
+	"[", "\\[",
+	"]", "\\]",
+	"<", "\\<",
+	"\n", " ",
+)
+
+func writeMarkdown(w io.Writer, invoices []Invoice) error {
+	for idx, inv := range invoices {
+		if idx > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "# Invoice for %s\n\n", escapeMarkdown.Replace(inv.ClientName()))
+		if inv.Client.Address != "" {
+			for _, line := range strings.Split(strings.TrimSpace(inv.Client.Address), "\n") {
+				fmt.Fprintf(w, "%s  \n", line)
+			}
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "Period: %s to %s\n\n", inv.From.Format(database.DayFormat), inv.LastDay().Format(database.DayFormat))
+		fmt.Fprintln(w, "| Task | Hours | Rate | Amount |")
+		fmt.Fprintln(w, "|------|------:|-----:|-------:|")
+		for _, l := range inv.Lines {
+			fmt.Fprintf(w, "| %s %s | %s | %s | %s |\n", escapeMarkdown.Replace(l.Code), escapeMarkdown.Replace(l.Title), formatHours(l.Duration), l.Rate, l.Amount)
+		}
+		fmt.Fprintf(w, "| **Total** | **%s** | | **%s %s** |\n", formatHours(inv.Duration), inv.Total, inv.Currency)
+	}
+	return nil
+}
+
+var htmlTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"hours": formatHours,
+	"day": func(t time.Time) string {
+		return t.Format(database.DayFormat)
+	},
+	"lines": func(s string) []string {
+		return strings.Split(strings.TrimSpace(s), "\n")
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Invoices</title>
+<style>
+body { font-family: sans-serif; }
+section { page-break-after: always; margin-bottom: 3em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { padding: 0.3em 0.6em; border-bottom: 1px solid #ccc; text-align: left; }
+.number { text-align: right; }
+tfoot td { font-weight: bold; }
+</style>
+</head>
+<body>
+{{- range . }}
+<section>
+<h1>Invoice for {{ .ClientName }}</h1>
+{{- if .Client.Address }}
+<address>{{ range lines .Client.Address }}{{ . }}<br>{{ end }}</address>
+{{- end }}
+<p>Period: {{ day .From }} to {{ day .LastDay }}</p>
+<table>
+<thead><tr><th>Task</th><th class="number">Hours</th><th class="number">Rate</th><th class="number">Amount</th></tr></thead>
+<tbody>
+{{- range .Lines }}
+<tr><td>{{ .Code }} {{ .Title }}</td><td class="number">{{ hours .Duration }}</td><td class="number">{{ .Rate }}</td><td class="number">{{ .Amount }}</td></tr>
+{{- end }}
+</tbody>
+<tfoot><tr><td>Total</td><td class="number">{{ hours .Duration }}</td><td></td><td class="number">{{ .Total }} {{ .Currency }}</td></tr></tfoot>
+</table>
+</section>
+{{- end }}
+</body>
+</html>
+`))
@@ -0,0 +1,32 @@
+package config
+
+import "time"
+
+// Backups configures how often snapshots are taken and which of them are
+// kept. Zero values disable the respective feature.
+type Backups struct {
+	// Backend is either restic or archive. By default restic is used if it
+	// is installed.
+	Backend string `yaml:"backend"`
+	// Encrypt makes the archive backend encrypt its snapshots.
+	Encrypt bool `yaml:"encrypt"`
+	// ResticPath is the restic binary to use instead of the one found in
+	// the PATH.
+	ResticPath string `yaml:"restic_path"`
+	// MinInterval is the minimum time between two snapshots. Changes within
+	// that time are collected into a single snapshot.
+	MinInterval time.Duration `yaml:"min_interval"`
+	// KeepLast, KeepHourly, KeepDaily, KeepWeekly and KeepMonthly define
+	// the retention policy: how many of the most recent snapshots and of
+	// the latest snapshots per hour, day, week and month are kept when old
+	// snapshots are forgotten.
+	KeepLast    int `yaml:"keep_last"`
+	KeepHourly  int `yaml:"keep_hourly"`
+	KeepDaily   int `yaml:"keep_daily"`
+	KeepWeekly  int `yaml:"keep_weekly"`
+	KeepMonthly int `yaml:"keep_monthly"`
+	// PruneInterval is the time between removing snapshots that are no
+	// longer covered by the retention policy. It defaults to a day if any
+	// retention is configured.
+	PruneInterval time.Duration `yaml:"prune_interval"`
+}
@@ -0,0 +1,36 @@
+package config
+
+import "time"
+
+// Billing configures how booked time is turned into invoices.
+type Billing struct {
+	Currency string `yaml:"currency"`
+	// DefaultRate is the hourly rate of tasks no other rate applies to.
+	DefaultRate float64 `yaml:"default_rate"`
+	// Rounding is the unit the time booked on a task per day is rounded
+	// to, e.g. 15m. Zero disables rounding.
+	Rounding time.Duration `yaml:"rounding"`
+	// RoundingMode is either up (the default), down or nearest.
+	RoundingMode string       `yaml:"rounding_mode"`
+	Clients      []Client     `yaml:"clients"`
+	Rates        []RateConfig `yaml:"rates"`
+}
+
+// Client is a customer that gets invoiced for all tasks matching one of
+// the given code patterns or tags.
+type Client struct {
+	Name    string `yaml:"name"`
+	Address string `yaml:"address"`
+	// Rate overrides the default rate for the tasks of this client.
+	Rate  float64  `yaml:"rate"`
+	Codes []string `yaml:"codes"`
+	Tags  []string `yaml:"tags"`
+}
+
+// RateConfig assigns an hourly rate to tasks whose code matches the given
+// pattern or that have the given tag.
+type RateConfig struct {
+	Code string  `yaml:"code"`
+	Tag  string  `yaml:"tag"`
+	Rate float64 `yaml:"rate"`
+}
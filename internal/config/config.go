@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
+	"regexp"
 
 	"gopkg.in/yaml.v2"
 )
@@ -12,6 +14,36 @@ type Config struct {
 	JIRAUsername string `yaml:"jira_username"`
 	JIRAURL      string `yaml:"jira_url"`
 	JIRAPassword string
+	Storage      string          `yaml:"storage"`
+	APIToken     string          `yaml:"api_token"`
+	TeamURL      string          `yaml:"team_url"`
+	TeamToken    string          `yaml:"team_token"`
+	Targets      Targets         `yaml:"targets"`
+	Holidays     []string        `yaml:"holidays"`
+	Leave        []string        `yaml:"leave"`
+	Templates    []TaskTemplate  `yaml:"templates"`
+	Recurring    []RecurringTask `yaml:"recurring"`
+	Reminders    Reminders       `yaml:"reminders"`
+	Billing      Billing         `yaml:"billing"`
+	Backups      Backups         `yaml:"backups"`
+	// DisableMouse keeps the terminal's own handling of mouse events, e.g.
+	// for selecting text.
+	DisableMouse bool `yaml:"disable_mouse"`
+	// GitAutoSwitch makes `clocked git-switch` clock in without asking.
+	GitAutoSwitch bool `yaml:"git_auto_switch"`
+	// CodePattern is a regular expression all task codes have to match.
+	CodePattern string `yaml:"code_pattern"`
+}
+
+// defaultCodePattern only prevents codes that can't be used as filenames.
+const defaultCodePattern = `^[^\s/]+$`
+
+// CodeRegexp returns the compiled CodePattern.
+func (c *Config) CodeRegexp() *regexp.Regexp {
+	if c.CodePattern == "" {
+		return regexp.MustCompile(defaultCodePattern)
+	}
+	return regexp.MustCompile(c.CodePattern)
 }
 
 func Load(path string) (*Config, error) {
@@ -26,6 +58,9 @@ func Load(path string) (*Config, error) {
 	if err := yaml.Unmarshal(raw, &c); err != nil {
 		return nil, err
 	}
+	if _, err := regexp.Compile(c.CodePattern); err != nil {
+		return nil, fmt.Errorf("invalid code_pattern: %s", err.Error())
+	}
 	if c.JIRAURL != "" {
 		pwd, err := loadJIRAPassword(c.JIRAURL, c.JIRAUsername)
 		if err != nil {
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Reminders configures the notifications sent by `clocked remind`. Zero
+// durations and empty times disable the respective reminder.
+type Reminders struct {
+	// IdleAfter is the time after which a reminder is sent if no task is
+	// active during working hours.
+	IdleAfter time.Duration `yaml:"idle_after"`
+	// LongBooking is the duration after which a running booking is
+	// reported.
+	LongBooking time.Duration `yaml:"long_booking"`
+	// WorkStart and WorkEnd define the working hours as HH:MM.
+	WorkStart string `yaml:"work_start"`
+	WorkEnd   string `yaml:"work_end"`
+	// EndOfDay is the time (HH:MM) the daily summary is sent at.
+	EndOfDay string `yaml:"end_of_day"`
+}
+
+// AtClock returns the given HH:MM time on the day t is in.
+func AtClock(t time.Time, clock string) (time.Time, error) {
+	c, err := time.Parse("15:04", clock)
+	if err != nil {
+		return t, fmt.Errorf("invalid time %s: expected HH:MM", clock)
+	}
+	y, m, d := t.Date()
+	return time.Date(y, m, d, c.Hour(), c.Minute(), 0, 0, t.Location()), nil
+}
+
+// IsWorkingDay checks if work is expected on the day t is in. If no
+// targets are configured, Monday to Friday are working days.
+func (c *Config) IsWorkingDay(t time.Time) bool {
+	if c.IsDayOff(t) {
+		return false
+	}
+	if c.Targets == (Targets{}) {
+		return t.Weekday() != time.Saturday && t.Weekday() != time.Sunday
+	}
+	return c.Targets.Weekday(t.Weekday()) > 0
+}
+
+// WorkingHours returns the start and end of the working hours on the day t
+// is in. If no working hours are configured, ok is false.
+func (c *Config) WorkingHours(t time.Time) (start time.Time, end time.Time, ok bool, err error) {
+	r := c.Reminders
+	if r.WorkStart == "" || r.WorkEnd == "" {
+		return t, t, false, nil
+	}
+	start, err = AtClock(t, r.WorkStart)
+	if err != nil {
+		return t, t, false, err
+	}
+	end, err = AtClock(t, r.WorkEnd)
+	if err != nil {
+		return t, t, false, err
+	}
+	return start, end, true, nil
+}
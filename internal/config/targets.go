@@ -0,0 +1,60 @@
+package config
+
+import "time"
+
+// Targets holds the contractual working time per weekday.
+type Targets struct {
+	Monday    time.Duration `yaml:"monday"`
+	Tuesday   time.Duration `yaml:"tuesday"`
+	Wednesday time.Duration `yaml:"wednesday"`
+	Thursday  time.Duration `yaml:"thursday"`
+	Friday    time.Duration `yaml:"friday"`
+	Saturday  time.Duration `yaml:"saturday"`
+	Sunday    time.Duration `yaml:"sunday"`
+}
+
+// Weekday returns the target for the given weekday.
+func (t Targets) Weekday(d time.Weekday) time.Duration {
+	switch d {
+	case time.Monday:
+		return t.Monday
+	case time.Tuesday:
+		return t.Tuesday
+	case time.Wednesday:
+		return t.Wednesday
+	case time.Thursday:
+		return t.Thursday
+	case time.Friday:
+		return t.Friday
+	case time.Saturday:
+		return t.Saturday
+	default:
+		return t.Sunday
+	}
+}
+
+// TargetFor returns the time that should be worked on the day t is in.
+// Holidays and leave days have no target at all.
+func (c *Config) TargetFor(t time.Time) time.Duration {
+	if c.IsDayOff(t) {
+		return 0
+	}
+	return c.Targets.Weekday(t.Weekday())
+}
+
+// IsDayOff checks if the day t is in is listed as either holiday or leave
+// day.
+func (c *Config) IsDayOff(t time.Time) bool {
+	day := t.Format("2006-01-02")
+	for _, d := range c.Holidays {
+		if d == day {
+			return true
+		}
+	}
+	for _, d := range c.Leave {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package config
+
+// TaskTemplate describes a task that can be created again and again. Code
+// and Title may contain placeholders like {isoweek} that are expanded when
+// the task is created.
+type TaskTemplate struct {
+	Name  string   `yaml:"name"`
+	Code  string   `yaml:"code"`
+	Title string   `yaml:"title"`
+	Tags  []string `yaml:"tags"`
+}
+
+// RecurringTask references a template that should be used to generate a
+// new task automatically according to the given schedule (daily, weekdays,
+// weekly or monthly).
+type RecurringTask struct {
+	Template string `yaml:"template"`
+	Schedule string `yaml:"schedule"`
+}
+
+// Template returns the template with the given name.
+func (c *Config) Template(name string) (TaskTemplate, bool) {
+	for _, t := range c.Templates {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return TaskTemplate{}, false
+}
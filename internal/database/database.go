@@ -1,8 +1,12 @@
 package database
 
 import (
+	"fmt"
+	"strings"
 	"time"
+	"unicode"
 
+	"github.com/Sirupsen/logrus"
 	"github.com/zerok/clocked"
 )
 
@@ -15,11 +19,86 @@ type Database interface {
 	LoadState() error
 	AddTask(clocked.Task) error
 	UpdateTask(string, clocked.Task) error
+	UpdateBooking(code string, index int, booking clocked.Booking) error
 	ClockInto(code string) error
+	// ClockIntoRepository clocks into the task like ClockInto and records
+	// the repository the new booking is worked on in.
+	ClockIntoRepository(code, repository string) error
 	ClockOutOf(code string) error
 	AllTasks() ([]clocked.Task, error)
 	FilteredTasks(f string) ([]clocked.Task, error)
 	GenerateDailySummary(time.Time) Summary
+	GenerateSummary(from, to time.Time) Summary
 	Empty() bool
 	TaskByCode(string) (clocked.Task, bool)
 }
+
+// BatchUpdater is implemented by databases that can update several tasks as
+// a single operation, e.g. so that the change can be undone at once.
+type BatchUpdater interface {
+	UpdateTasks(description string, tasks []clocked.Task) error
+}
+
+// Open creates a database for the given storage format. "folder" (the
+// default) stores every task in its own YAML file while "eventlog" appends
+// all changes to a single log file.
+func Open(format string, path string, log *logrus.Logger) (Database, error) {
+	switch format {
+	case "", "folder":
+		return NewDatabase(path, log)
+	case "eventlog":
+		return NewEventLogDatabase(path, log)
+	default:
+		return nil, fmt.Errorf("unsupported storage format %s", format)
+	}
+}
+
+// ValidateCode checks that code can be used as task code. As the
+// folder-based store uses codes as filenames, codes must not be empty and
+// must not contain whitespace, path separators or "..".
+func ValidateCode(code string) error {
+	if code == "" {
+		return fmt.Errorf("a code is required")
+	}
+	if strings.IndexFunc(code, unicode.IsSpace) != -1 {
+		return fmt.Errorf("the code %q must not contain whitespace", code)
+	}
+	if strings.ContainsAny(code, `/\`) || strings.Contains(code, "..") {
+		return fmt.Errorf("the code %q must not contain path separators or \"..\"", code)
+	}
+	return nil
+}
+
+// validateBooking checks if the booking with the given index of task can be
+// replaced by b. Only the running booking, i.e. the last booking of the
+// active task, is open and it can only be stopped by clocking out.
+func validateBooking(task clocked.Task, activeCode string, index int, b clocked.Booking) error {
+	if index < 0 || index >= len(task.Bookings) {
+		return fmt.Errorf("task %s has no booking %d", task.Code, index)
+	}
+	running := task.Code == activeCode && index == len(task.Bookings)-1
+	if running && b.Stop != "" {
+		return fmt.Errorf("the running booking can only be stopped by clocking out")
+	}
+	if !running && b.Stop == "" {
+		return fmt.Errorf("a booking requires a stop time")
+	}
+	start := b.StartTime()
+	if start == nil {
+		return fmt.Errorf("a booking requires a start time")
+	}
+	if _, err := time.Parse(time.RFC3339, b.Start); err != nil {
+		return fmt.Errorf("invalid start time: %s", err.Error())
+	}
+	if b.Stop == "" {
+		return nil
+	}
+	stop, err := time.Parse(time.RFC3339, b.Stop)
+	if err != nil {
+		return fmt.Errorf("invalid stop time: %s", err.Error())
+	}
+	if stop.Before(*start) {
+		return fmt.Errorf("a booking cannot stop before it started")
+	}
+	return nil
+}
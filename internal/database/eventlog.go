@@ -0,0 +1,458 @@
+package database
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/satori/go.uuid"
+	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/query"
+)
+
+const EventLogFilename = "events.jsonl"
+const EventSnapshotFilename = "events.snapshot.json"
+
+// compactionInterval is the number of events after which a new snapshot is
+// written and the log is truncated.
+const compactionInterval = 500
+
+const (
+	EventTaskCreated   = "task_created"
+	EventTaskUpdated   = "task_updated"
+	EventClockedIn     = "clocked_in"
+	EventClockedOut    = "clocked_out"
+	EventBookingEdited = "booking_edited"
+)
+
+// Event is a single change of the database as stored in the event log.
+type Event struct {
+	Seq     int64    `json:"seq"`
+	Time    string   `json:"time"`
+	Type    string   `json:"type"`
+	Code    string   `json:"code"`
+	NewCode string   `json:"new_code,omitempty"`
+	Title   string   `json:"title,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	Index   int      `json:"index,omitempty"`
+	Start   string   `json:"start,omitempty"`
+	Stop    string   `json:"stop,omitempty"`
+	// ID is the ID of the task created by a task_created event.
+	ID string `json:"id,omitempty"`
+	// Bookings are the bookings a task already had when it was created.
+	Bookings []clocked.Booking `json:"bookings,omitempty"`
+	// BookingID is the ID of the booking started by a clocked_in event.
+	BookingID string `json:"booking_id,omitempty"`
+	// Repository is the repository the booking started by a clocked_in
+	// event is worked on in.
+	Repository string `json:"repository,omitempty"`
+}
+
+// eventSnapshot is the state of the database after the event with the
+// sequence number Seq has been applied.
+type eventSnapshot struct {
+	Seq        int64          `json:"seq"`
+	ActiveCode string         `json:"active_code"`
+	Tasks      []clocked.Task `json:"tasks"`
+}
+
+// EventLogDatabase never rewrites existing data but appends every change
+// as event to a JSONL file. The current state is rebuilt by replaying these
+// events on top of the latest snapshot.
+type EventLogDatabase struct {
+	tasks               []clocked.Task
+	activeCode          string
+	seq                 int64
+	eventsSinceSnapshot int
+	rootFolder          string
+	log                 *logrus.Logger
+	now                 func() time.Time
+}
+
+func NewEventLogDatabase(path string, log *logrus.Logger) (*EventLogDatabase, error) {
+	return &EventLogDatabase{
+		rootFolder: path,
+		log:        log,
+		tasks:      make([]clocked.Task, 0, 10),
+		now:        time.Now,
+	}, nil
+}
+
+func (d *EventLogDatabase) logPath() string {
+	return filepath.Join(d.rootFolder, EventLogFilename)
+}
+
+func (d *EventLogDatabase) snapshotPath() string {
+	return filepath.Join(d.rootFolder, EventSnapshotFilename)
+}
+
+func (d *EventLogDatabase) LoadState() error {
+	d.log.Infof("Loading state from event log")
+	d.tasks = make([]clocked.Task, 0, 20)
+	d.activeCode = ""
+	d.seq = 0
+	d.eventsSinceSnapshot = 0
+
+	snpData, err := ioutil.ReadFile(d.snapshotPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		if _, err := os.Stat(d.logPath()); os.IsNotExist(err) {
+			return d.importFolder()
+		}
+	} else {
+		var snp eventSnapshot
+		if err := json.Unmarshal(snpData, &snp); err != nil {
+			return fmt.Errorf("failed to decode snapshot: %s", err.Error())
+		}
+		d.tasks = snp.Tasks
+		d.activeCode = snp.ActiveCode
+		d.seq = snp.Seq
+	}
+
+	fp, err := os.Open(d.logPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer fp.Close()
+	scanner := bufio.NewScanner(fp)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	var offset int64
+	var broken error
+	var brokenOffset int64
+	for scanner.Scan() {
+		line++
+		start := offset
+		offset += int64(len(scanner.Bytes())) + 1
+		if broken != nil {
+			return broken
+		}
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var evt Event
+		if err := json.Unmarshal(scanner.Bytes(), &evt); err != nil {
+			broken = fmt.Errorf("failed to decode event in line %d: %s", line, err.Error())
+			brokenOffset = start
+			continue
+		}
+		// Events already contained in the snapshot are skipped. They
+		// are left over if the log could not be truncated after the
+		// last compaction.
+		if evt.Seq <= d.seq {
+			continue
+		}
+		if err := d.apply(evt); err != nil {
+			return fmt.Errorf("failed to replay event %d: %s", evt.Seq, err.Error())
+		}
+		d.seq = evt.Seq
+		d.eventsSinceSnapshot++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	// Only the last event can be incomplete, e.g. if the log has been
+	// restored from a snapshot taken while it was written. It is dropped
+	// so that new events don't end up in the same line.
+	if broken != nil {
+		d.log.WithError(broken).Warn("Dropping incomplete event at the end of the log")
+		return os.Truncate(d.logPath(), brokenOffset)
+	}
+	return nil
+}
+
+// importFolder bootstraps an empty event log with the data of a
+// folder-based store inside the same root folder.
+func (d *EventLogDatabase) importFolder() error {
+	if _, err := os.Stat(filepath.Join(d.rootFolder, TasksFolder)); err != nil {
+		return nil
+	}
+	d.log.Infof("Importing tasks from %s", d.rootFolder)
+	folder, _ := NewDatabase(d.rootFolder, d.log)
+	if err := folder.LoadState(); err != nil {
+		return err
+	}
+	tasks, _ := folder.AllTasks()
+	for _, t := range tasks {
+		d.tasks = append(d.tasks, t.Copy())
+	}
+	d.activeCode = folder.ActiveCode()
+	return d.writeSnapshot()
+}
+
+func (d *EventLogDatabase) findTask(code string) int {
+	for idx, t := range d.tasks {
+		if t.Code == code {
+			return idx
+		}
+	}
+	return -1
+}
+
+// apply changes the in-memory state according to the given event. Invalid
+// events result in an error and leave the state untouched.
+func (d *EventLogDatabase) apply(evt Event) error {
+	tm, err := time.Parse(time.RFC3339, evt.Time)
+	if err != nil {
+		return err
+	}
+	idx := d.findTask(evt.Code)
+	if evt.Type != EventTaskCreated && idx == -1 {
+		return fmt.Errorf("task %s not found", evt.Code)
+	}
+	switch evt.Type {
+	case EventTaskCreated:
+		if idx != -1 {
+			return fmt.Errorf("a task with the code %s already exists", evt.Code)
+		}
+		t := clocked.Task{ID: evt.ID, Code: evt.Code, Title: evt.Title, Tags: evt.Tags, Bookings: evt.Bookings, Updated: evt.Time}
+		d.tasks = append(d.tasks, t.Copy())
+	case EventTaskUpdated:
+		if evt.NewCode != evt.Code && d.findTask(evt.NewCode) != -1 {
+			return fmt.Errorf("a task with the code %s already exists", evt.NewCode)
+		}
+		t := &d.tasks[idx]
+		t.Code = evt.NewCode
+		t.Title = evt.Title
+		t.Tags = evt.Tags
+		t.Updated = evt.Time
+		if d.activeCode == evt.Code {
+			d.activeCode = evt.NewCode
+		}
+	case EventClockedIn:
+		if d.activeCode != "" {
+			return fmt.Errorf("task %s is still active", d.activeCode)
+		}
+		t := &d.tasks[idx]
+		t.Start(tm)
+		// The ID has to be stable across replays.
+		t.Bookings[len(t.Bookings)-1].ID = evt.BookingID
+		t.Bookings[len(t.Bookings)-1].Repository = evt.Repository
+		d.activeCode = evt.Code
+	case EventClockedOut:
+		if d.activeCode != evt.Code {
+			return fmt.Errorf("task %s is not active", evt.Code)
+		}
+		d.tasks[idx].Stop(tm)
+		d.activeCode = ""
+	case EventBookingEdited:
+		t := &d.tasks[idx]
+		if evt.Index < 0 || evt.Index >= len(t.Bookings) {
+			return fmt.Errorf("task %s has no booking %d", evt.Code, evt.Index)
+		}
+		t.Bookings[evt.Index].Start = evt.Start
+		t.Bookings[evt.Index].Stop = evt.Stop
+		t.Bookings[evt.Index].Updated = evt.Time
+	default:
+		return fmt.Errorf("unsupported event type %s", evt.Type)
+	}
+	return nil
+}
+
+// record applies the given events and appends them to the log. Either all
+// of them are recorded or none.
+func (d *EventLogDatabase) record(events ...Event) error {
+	seq := d.seq
+	for idx := range events {
+		evt := &events[idx]
+		seq++
+		evt.Seq = seq
+		if evt.Time == "" {
+			evt.Time = d.now().Format(time.RFC3339)
+		}
+		if err := d.apply(*evt); err != nil {
+			if idx > 0 {
+				d.reload()
+			}
+			return err
+		}
+	}
+	if err := d.appendEvents(events); err != nil {
+		d.reload()
+		return err
+	}
+	d.seq = seq
+	d.eventsSinceSnapshot += len(events)
+	if d.eventsSinceSnapshot >= compactionInterval {
+		return d.Compact()
+	}
+	return nil
+}
+
+// reload makes sure that the in-memory state doesn't contain changes that
+// haven't been persisted.
+func (d *EventLogDatabase) reload() {
+	if err := d.LoadState(); err != nil {
+		d.log.WithError(err).Error("Failed to reload state")
+	}
+}
+
+// appendEvents writes all events using a single write.
+func (d *EventLogDatabase) appendEvents(events []Event) error {
+	if err := os.MkdirAll(d.rootFolder, 0700); err != nil {
+		return err
+	}
+	var data []byte
+	for _, evt := range events {
+		line, err := json.Marshal(evt)
+		if err != nil {
+			return err
+		}
+		data = append(data, line...)
+		data = append(data, '\n')
+	}
+	fp, err := os.OpenFile(d.logPath(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := fp.Write(data); err != nil {
+		fp.Close()
+		return err
+	}
+	return fp.Close()
+}
+
+func (d *EventLogDatabase) writeSnapshot() error {
+	if err := os.MkdirAll(d.rootFolder, 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(eventSnapshot{
+		Seq:        d.seq,
+		ActiveCode: d.activeCode,
+		Tasks:      d.tasks,
+	})
+	if err != nil {
+		return err
+	}
+	return WriteFile(d.snapshotPath(), data)
+}
+
+// Compact writes the current state into a snapshot and truncates the event
+// log afterwards.
+func (d *EventLogDatabase) Compact() error {
+	if err := d.writeSnapshot(); err != nil {
+		return err
+	}
+	if err := os.Truncate(d.logPath(), 0); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	d.eventsSinceSnapshot = 0
+	return nil
+}
+
+func (d *EventLogDatabase) ActiveCode() string {
+	return d.activeCode
+}
+
+func (d *EventLogDatabase) ActiveTask() (clocked.Task, bool) {
+	if d.activeCode == "" {
+		return clocked.Task{}, false
+	}
+	return d.TaskByCode(d.activeCode)
+}
+
+func (d *EventLogDatabase) TaskByCode(code string) (clocked.Task, bool) {
+	idx := d.findTask(code)
+	if idx == -1 {
+		return clocked.Task{}, false
+	}
+	return d.tasks[idx], true
+}
+
+func (d *EventLogDatabase) AddTask(t clocked.Task) error {
+	if err := ValidateCode(t.Code); err != nil {
+		return err
+	}
+	if t.ID == "" {
+		t.ID = uuid.NewV4().String()
+	}
+	return d.record(Event{Type: EventTaskCreated, Code: t.Code, ID: t.ID, Title: t.Title, Tags: t.Tags, Bookings: t.Bookings})
+}
+
+func (d *EventLogDatabase) UpdateTask(oldCode string, task clocked.Task) error {
+	if err := ValidateCode(task.Code); err != nil {
+		return err
+	}
+	return d.record(Event{Type: EventTaskUpdated, Code: oldCode, NewCode: task.Code, Title: task.Title, Tags: task.Tags})
+}
+
+// UpdateTasks updates all given tasks at once. The tasks are identified by
+// their code which therefore can't be changed.
+func (d *EventLogDatabase) UpdateTasks(description string, tasks []clocked.Task) error {
+	events := make([]Event, 0, len(tasks))
+	for _, t := range tasks {
+		events = append(events, Event{Type: EventTaskUpdated, Code: t.Code, NewCode: t.Code, Title: t.Title, Tags: t.Tags})
+	}
+	return d.record(events...)
+}
+
+func (d *EventLogDatabase) UpdateBooking(code string, index int, booking clocked.Booking) error {
+	idx := d.findTask(code)
+	if idx == -1 {
+		return fmt.Errorf("Task %s not found", code)
+	}
+	if err := validateBooking(d.tasks[idx], d.activeCode, index, booking); err != nil {
+		return err
+	}
+	return d.record(Event{Type: EventBookingEdited, Code: code, Index: index, Start: booking.Start, Stop: booking.Stop})
+}
+
+func (d *EventLogDatabase) ClockInto(code string) error {
+	return d.ClockIntoRepository(code, "")
+}
+
+func (d *EventLogDatabase) ClockIntoRepository(code, repository string) error {
+	if d.findTask(code) == -1 {
+		return fmt.Errorf("Task %s not found", code)
+	}
+	// Clocking out of the active task is recorded together with clocking
+	// in so that the log never contains only one of them.
+	var events []Event
+	if d.activeCode != "" {
+		events = append(events, Event{Type: EventClockedOut, Code: d.activeCode})
+	}
+	events = append(events, Event{Type: EventClockedIn, Code: code, BookingID: uuid.NewV4().String(), Repository: repository})
+	return d.record(events...)
+}
+
+func (d *EventLogDatabase) ClockOutOf(code string) error {
+	return d.record(Event{Type: EventClockedOut, Code: code})
+}
+
+func (d *EventLogDatabase) AllTasks() ([]clocked.Task, error) {
+	return d.tasks, nil
+}
+
+func (d *EventLogDatabase) FilteredTasks(f string) ([]clocked.Task, error) {
+	q := query.Parse(f)
+	if q.Empty() {
+		return d.AllTasks()
+	}
+	return query.Filter(d.tasks, q, query.Context{
+		Now:        d.now(),
+		ActiveCode: d.activeCode,
+	}), nil
+}
+
+func (d *EventLogDatabase) GenerateDailySummary(t time.Time) Summary {
+	from := StartOfDay(t)
+	return d.GenerateSummary(from, from.AddDate(0, 0, 1))
+}
+
+func (d *EventLogDatabase) GenerateSummary(from, to time.Time) Summary {
+	return summarize(d.tasks, from, to)
+}
+
+func (d *EventLogDatabase) Empty() bool {
+	return len(d.tasks) == 0
+}
@@ -0,0 +1,195 @@
+package database
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/zerok/clocked"
+)
+
+func newTestEventLogDatabase(t *testing.T, dir string) *EventLogDatabase {
+	log := logrus.New()
+	log.Out = ioutil.Discard
+	db, err := NewEventLogDatabase(dir, log)
+	require.NoError(t, err)
+	require.NoError(t, db.LoadState())
+	return db
+}
+
+func TestEventLogReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clocked")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db := newTestEventLogDatabase(t, dir)
+	require.NoError(t, db.AddTask(clocked.Task{Code: "a", Title: "A"}))
+	require.NoError(t, db.AddTask(clocked.Task{Code: "b"}))
+	require.Error(t, db.AddTask(clocked.Task{Code: "a"}), "codes have to be unique")
+	require.NoError(t, db.ClockInto("a"))
+	require.NoError(t, db.ClockInto("b"))
+	require.NoError(t, db.UpdateTask("a", clocked.Task{Code: "c", Title: "C", Tags: []string{"offline"}}))
+	start := time.Date(2017, 10, 9, 8, 0, 0, 0, time.UTC)
+	require.NoError(t, db.UpdateBooking("c", 0, clocked.Booking{
+		Start: start.Format(time.RFC3339),
+		Stop:  start.Add(time.Hour).Format(time.RFC3339),
+	}))
+
+	replayed := newTestEventLogDatabase(t, dir)
+	require.Equal(t, "b", replayed.ActiveCode())
+	c, found := replayed.TaskByCode("c")
+	require.True(t, found, "the renamed task should exist after replaying")
+	require.True(t, c.HasTag("offline"))
+	require.Len(t, c.Bookings, 1)
+	require.Equal(t, time.Hour, replayed.GenerateDailySummary(start).Totals["c"])
+
+	// After a compaction the state is restored from the snapshot alone.
+	require.NoError(t, replayed.Compact())
+	info, err := os.Stat(filepath.Join(dir, EventLogFilename))
+	require.NoError(t, err)
+	require.Equal(t, int64(0), info.Size(), "the log should have been truncated")
+	require.NoError(t, replayed.ClockOutOf("b"))
+
+	compacted := newTestEventLogDatabase(t, dir)
+	require.Equal(t, "", compacted.ActiveCode())
+	tasks, _ := compacted.AllTasks()
+	require.Len(t, tasks, 2)
+}
+
+func TestEventLogImportsFolder(t *testing.T) {
+	folder, cleanup := newTestDatabase(t)
+	defer cleanup()
+	require.NoError(t, folder.AddTask(clocked.Task{Code: "a"}))
+	require.NoError(t, folder.ClockInto("a"))
+
+	db := newTestEventLogDatabase(t, folder.rootFolder)
+	require.Equal(t, "a", db.ActiveCode(), "the active code should have been imported")
+	require.False(t, db.Empty())
+}
+
+func TestEventLogUpdateTasks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clocked")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db := newTestEventLogDatabase(t, dir)
+	require.NoError(t, db.AddTask(clocked.Task{Code: "a", Tags: []string{"x"}}))
+	require.NoError(t, db.AddTask(clocked.Task{Code: "b", Tags: []string{"x"}}))
+	require.Error(t, db.UpdateTasks("Rename tag x", []clocked.Task{
+		{Code: "a", Tags: []string{"y"}},
+		{Code: "c", Tags: []string{"y"}},
+	}))
+	a, _ := db.TaskByCode("a")
+	require.Equal(t, []string{"x"}, a.Tags, "no task should be changed if one of them is unknown")
+
+	require.NoError(t, db.UpdateTasks("Rename tag x", []clocked.Task{
+		{Code: "a", Tags: []string{"y"}},
+		{Code: "b", Tags: []string{"y"}},
+	}))
+	replayed := newTestEventLogDatabase(t, dir)
+	a, _ = replayed.TaskByCode("a")
+	b, _ := replayed.TaskByCode("b")
+	require.Equal(t, []string{"y"}, a.Tags)
+	require.Equal(t, []string{"y"}, b.Tags)
+}
+
+func TestEventLogDropsIncompleteEvent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clocked")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db := newTestEventLogDatabase(t, dir)
+	require.NoError(t, db.AddTask(clocked.Task{Code: "a"}))
+	fp, err := os.OpenFile(db.logPath(), os.O_WRONLY|os.O_APPEND, 0600)
+	require.NoError(t, err)
+	_, err = fp.WriteString(`{"seq":2,"time":"2018-`)
+	require.NoError(t, err)
+	require.NoError(t, fp.Close())
+
+	db = newTestEventLogDatabase(t, dir)
+	require.NoError(t, db.AddTask(clocked.Task{Code: "b"}))
+	replayed := newTestEventLogDatabase(t, dir)
+	tasks, _ := replayed.AllTasks()
+	require.Len(t, tasks, 2)
+
+	// Broken events in the middle of the log are still reported.
+	data, err := ioutil.ReadFile(db.logPath())
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(db.logPath(), append([]byte("{\n"), data...), 0600))
+	require.Error(t, db.LoadState())
+}
+
+func TestEventLogRecordsRepository(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clocked")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db := newTestEventLogDatabase(t, dir)
+	require.NoError(t, db.AddTask(clocked.Task{Code: "a"}))
+	require.NoError(t, db.ClockIntoRepository("a", "api"))
+	require.NoError(t, db.ClockIntoRepository("a", "web"))
+	replayed := newTestEventLogDatabase(t, dir)
+	a, _ := replayed.TaskByCode("a")
+	require.Len(t, a.Bookings, 2)
+	require.Equal(t, "api", a.Bookings[0].Repository)
+	require.Equal(t, "web", a.Bookings[1].Repository)
+}
+
+func TestEventLogKeepsIDsAndBookings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clocked")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	start := time.Date(2017, 10, 9, 8, 0, 0, 0, time.UTC)
+	b := clocked.Booking{ID: "booking-1", Repository: "api"}
+	b.SetStart(start)
+	b.SetStop(start.Add(time.Hour))
+	db := newTestEventLogDatabase(t, dir)
+	require.NoError(t, db.AddTask(clocked.Task{ID: "task-1", Code: "a", Bookings: []clocked.Booking{b}}))
+	require.NoError(t, db.AddTask(clocked.Task{Code: "b"}))
+	require.NoError(t, db.UpdateTask("a", clocked.Task{Code: "c", Title: "C"}))
+
+	replayed := newTestEventLogDatabase(t, dir)
+	c, found := replayed.TaskByCode("c")
+	require.True(t, found)
+	require.Equal(t, "task-1", c.ID)
+	require.Equal(t, []clocked.Booking{b}, c.Bookings)
+	other, _ := replayed.TaskByCode("b")
+	require.NotEmpty(t, other.ID, "new tasks get an ID")
+	again := newTestEventLogDatabase(t, dir)
+	otherAgain, _ := again.TaskByCode("b")
+	require.Equal(t, other.ID, otherAgain.ID, "IDs are stable across replays")
+}
+
+// Clocking out of the active task is recorded together with clocking into
+// the next one.
+func TestEventLogClockIntoRecordsBothEvents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clocked")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db := newTestEventLogDatabase(t, dir)
+	require.NoError(t, db.AddTask(clocked.Task{Code: "a"}))
+	require.NoError(t, db.AddTask(clocked.Task{Code: "b"}))
+	require.NoError(t, db.ClockInto("a"))
+	require.NoError(t, db.ClockInto("b"))
+
+	data, err := ioutil.ReadFile(db.logPath())
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 5)
+	var out, in Event
+	require.NoError(t, json.Unmarshal([]byte(lines[3]), &out))
+	require.NoError(t, json.Unmarshal([]byte(lines[4]), &in))
+	require.Equal(t, EventClockedOut, out.Type)
+	require.Equal(t, "a", out.Code)
+	require.Equal(t, EventClockedIn, in.Type)
+	require.Equal(t, "b", in.Code)
+	require.Equal(t, "b", newTestEventLogDatabase(t, dir).ActiveCode())
+}
@@ -0,0 +1,18 @@
+package database
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+// WriteFile writes data into a temporary file next to path and renames it
+// afterwards. Readers like a snapshot taken in the background therefore
+// either see the old or the new content but never a partially written file.
+func WriteFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -5,12 +5,13 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 	"time"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/satori/go.uuid"
 	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/query"
 	"gopkg.in/yaml.v2"
 )
 
@@ -30,6 +31,8 @@ type FolderBasedDatabase struct {
 	activeCode    string
 	rootFolder    string
 	log           *logrus.Logger
+	journal       *Journal
+	deleted       Tombstones
 }
 
 func (d *FolderBasedDatabase) ActiveCode() string {
@@ -53,6 +56,8 @@ func NewDatabase(path string, log *logrus.Logger) (Database, error) {
 		rootFolder:    path,
 		log:           log,
 		taskCodeIndex: make(map[string]struct{}),
+		journal:       &Journal{},
+		deleted:       Tombstones{},
 	}
 	return &d, nil
 }
@@ -84,6 +89,18 @@ func (d *FolderBasedDatabase) LoadState() error {
 		d.activeCode = strings.TrimSpace(string(activeCodeData))
 	}
 
+	journal, err := loadJournal(filepath.Join(d.rootFolder, JournalFilename))
+	if err != nil {
+		return err
+	}
+	d.journal = journal
+
+	deleted, err := loadTombstones(filepath.Join(d.rootFolder, DeletedFilename))
+	if err != nil {
+		return err
+	}
+	d.deleted = deleted
+
 	files, err := filepath.Glob(filepath.Join(tasksFolder, "*.yml"))
 	if err != nil {
 		return err
@@ -129,10 +146,25 @@ func (d *FolderBasedDatabase) saveTask(tsk *clocked.Task) error {
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(path, data, 0600)
+	return WriteFile(path, data)
 }
 
 func (d *FolderBasedDatabase) AddTask(t clocked.Task) error {
+	if err := ValidateCode(t.Code); err != nil {
+		return err
+	}
+	op := d.beginOperation(fmt.Sprintf("Add task %s", t.Code), t.Code)
+	if t.ID == "" {
+		t.ID = uuid.NewV4().String()
+	}
+	t.Touch(time.Now())
+	if err := d.addTask(t); err != nil {
+		return err
+	}
+	return d.commitOperation(op)
+}
+
+func (d *FolderBasedDatabase) addTask(t clocked.Task) error {
 	if d.taskCodeIndex == nil {
 		d.taskCodeIndex = make(map[string]struct{})
 	}
@@ -150,6 +182,49 @@ func (d *FolderBasedDatabase) deleteTaskByCode(code string) error {
 }
 
 func (d *FolderBasedDatabase) UpdateTask(oldCode string, task clocked.Task) error {
+	if err := ValidateCode(task.Code); err != nil {
+		return err
+	}
+	op := d.beginOperation(fmt.Sprintf("Update task %s", oldCode), oldCode, task.Code)
+	task.Touch(time.Now())
+	if err := d.updateTask(oldCode, task); err != nil {
+		return err
+	}
+	return d.commitOperation(op)
+}
+
+// UpdateTasks updates all given tasks as a single operation. The tasks are
+// identified by their code which therefore can't be changed. If any of the
+// tasks can't be written, the tasks that have already been written are
+// restored so that either all or none of them are changed.
+func (d *FolderBasedDatabase) UpdateTasks(description string, tasks []clocked.Task) error {
+	codes := make([]string, 0, len(tasks))
+	seen := make(map[string]struct{})
+	for _, t := range tasks {
+		if _, found := d.taskCodeIndex[t.Code]; !found {
+			return fmt.Errorf("Task %s not found", t.Code)
+		}
+		if _, found := seen[t.Code]; found {
+			return fmt.Errorf("Task %s is updated more than once", t.Code)
+		}
+		seen[t.Code] = struct{}{}
+		codes = append(codes, t.Code)
+	}
+	op := d.beginOperation(description, codes...)
+	now := time.Now()
+	for _, t := range tasks {
+		t.Touch(now)
+		if err := d.updateTask(t.Code, t); err != nil {
+			if restoreErr := d.restoreState(op.Before, op.ActiveCodeBefore); restoreErr != nil {
+				d.log.WithError(restoreErr).Error("Failed to restore tasks")
+			}
+			return err
+		}
+	}
+	return d.commitOperation(op)
+}
+
+func (d *FolderBasedDatabase) updateTask(oldCode string, task clocked.Task) error {
 	// If the code changes, make sure that the new code isn't already taken.
 	if oldCode != task.Code {
 		if _, exists := d.taskCodeIndex[task.Code]; exists {
@@ -160,6 +235,9 @@ func (d *FolderBasedDatabase) UpdateTask(oldCode string, task clocked.Task) erro
 	for idx, t := range d.taskIndex {
 		if t.Code == oldCode {
 			task.Bookings = t.Bookings
+			if task.ID == "" {
+				task.ID = t.ID
+			}
 			if err := d.saveTask(&task); err != nil {
 				return err
 			}
@@ -183,10 +261,50 @@ func (d *FolderBasedDatabase) UpdateTask(oldCode string, task clocked.Task) erro
 	return nil
 }
 
+func (d *FolderBasedDatabase) UpdateBooking(code string, index int, booking clocked.Booking) error {
+	idx := -1
+	for i := range d.taskIndex {
+		if d.taskIndex[i].Code == code {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		return fmt.Errorf("Task %s not found", code)
+	}
+	task := &d.taskIndex[idx]
+	if err := validateBooking(*task, d.activeCode, index, booking); err != nil {
+		return err
+	}
+	op := d.beginOperation(fmt.Sprintf("Edit booking of %s", code), code)
+	task.Bookings[index].Start = booking.Start
+	task.Bookings[index].Stop = booking.Stop
+	task.Bookings[index].Touch(time.Now())
+	if err := d.saveTask(task); err != nil {
+		if restoreErr := d.restoreState(op.Before, op.ActiveCodeBefore); restoreErr != nil {
+			d.log.WithError(restoreErr).Error("Failed to restore task")
+		}
+		return err
+	}
+	return d.commitOperation(op)
+}
+
 func (d *FolderBasedDatabase) ClockInto(code string) error {
+	return d.ClockIntoRepository(code, "")
+}
+
+func (d *FolderBasedDatabase) ClockIntoRepository(code, repository string) error {
+	op := d.beginOperation(fmt.Sprintf("Clock into %s", code), code, d.activeCode)
+	if err := d.clockInto(code, repository); err != nil {
+		return err
+	}
+	return d.commitOperation(op)
+}
+
+func (d *FolderBasedDatabase) clockInto(code, repository string) error {
 	// If another task is active, clock out of that first
 	if d.activeCode != "" {
-		if err := d.ClockOutOf(d.activeCode); err != nil {
+		if err := d.clockOutOf(d.activeCode); err != nil {
 			return err
 		}
 	}
@@ -204,21 +322,27 @@ func (d *FolderBasedDatabase) ClockInto(code string) error {
 		if err := task.Start(time.Now()); err != nil {
 			return err
 		}
+		task.Bookings[len(task.Bookings)-1].Repository = repository
 		return d.saveTask(task)
 	}
 	return nil
 }
 
 type Summary struct {
-	Bookings []TaskBooking
-	Totals   map[string]time.Duration
-	Total    time.Duration
+	Bookings    []TaskBooking
+	Totals      map[string]time.Duration
+	DailyTotals map[string]time.Duration
+	// RepositoryTotals contains the time of all bookings that have been
+	// worked on in a git repository per repository.
+	RepositoryTotals map[string]time.Duration
+	Total            time.Duration
 }
 
 type TaskBooking struct {
 	Code             string
 	Start            *time.Time
 	Stop             *time.Time
+	Repository       string
 	SubmissionStatus int
 }
 
@@ -258,43 +382,23 @@ func (d *FolderBasedDatabase) Empty() bool {
 }
 
 func (d *FolderBasedDatabase) GenerateDailySummary(t time.Time) Summary {
-	summary := Summary{}
-	summary.Totals = make(map[string]time.Duration)
-	summary.Bookings = make([]TaskBooking, 0, 10)
-	for _, tsk := range d.taskIndex {
-		if tsk.Bookings != nil {
-			for _, b := range tsk.Bookings {
-				start := b.StartTime()
-				if start != nil {
-					if isSameDay(*start, t) {
-						summary.Bookings = append(summary.Bookings, TaskBooking{
-							Code:  tsk.Code,
-							Start: start,
-							Stop:  b.StopTime(),
-						})
-						stop := b.StopTime()
-						if stop != nil {
-							prev := summary.Totals[tsk.Code]
-							dur := stop.Sub(*start)
-							summary.Totals[tsk.Code] = prev + dur
-							summary.Total += dur
-						}
-					}
-				}
-			}
-		}
-	}
-	sort.Sort(ByStart(summary.Bookings))
-	return summary
+	from := StartOfDay(t)
+	return d.GenerateSummary(from, from.AddDate(0, 0, 1))
 }
 
-func isSameDay(a time.Time, b time.Time) bool {
-	aYear, aMonth, aDay := a.Date()
-	bYear, bMonth, bDay := b.Date()
-	return aYear == bYear && aMonth == bMonth && aDay == bDay
+func (d *FolderBasedDatabase) GenerateSummary(from, to time.Time) Summary {
+	return summarize(d.taskIndex, from, to)
 }
 
 func (d *FolderBasedDatabase) ClockOutOf(code string) error {
+	op := d.beginOperation(fmt.Sprintf("Clock out of %s", code), code)
+	if err := d.clockOutOf(code); err != nil {
+		return err
+	}
+	return d.commitOperation(op)
+}
+
+func (d *FolderBasedDatabase) clockOutOf(code string) error {
 	if _, ok := d.taskCodeIndex[code]; !ok {
 		return fmt.Errorf("Task %s not found", code)
 	}
@@ -319,18 +423,103 @@ func (d *FolderBasedDatabase) AllTasks() ([]clocked.Task, error) {
 }
 
 func (d *FolderBasedDatabase) FilteredTasks(f string) ([]clocked.Task, error) {
-	if f == "" {
+	q := query.Parse(f)
+	if q.Empty() {
 		return d.AllTasks()
 	}
-	q := strings.ToLower(f)
-	result := make([]clocked.Task, 0, 5)
+	return query.Filter(d.taskIndex, q, query.Context{
+		Now:        time.Now(),
+		ActiveCode: d.activeCode,
+	}), nil
+}
+
+// Tombstones returns the deleted tasks.
+func (d *FolderBasedDatabase) Tombstones() Tombstones {
+	return d.deleted.Copy()
+}
+
+// updateTombstones records the deletion of the tasks with the given keys
+// unless they still exist and forgets about the deletion of tasks that
+// exist again, e.g. because the deletion has been undone.
+func (d *FolderBasedDatabase) updateTombstones(removed map[string]struct{}) error {
+	changed := false
+	present := make(map[string]struct{})
+	for _, t := range d.taskIndex {
+		key := t.Key()
+		present[key] = struct{}{}
+		if _, found := d.deleted[key]; found {
+			delete(d.deleted, key)
+			changed = true
+		}
+	}
+	now := time.Now()
+	for key := range removed {
+		if _, found := present[key]; !found {
+			d.deleted[key] = now
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return saveTombstones(filepath.Join(d.rootFolder, DeletedFilename), d.deleted)
+}
+
+// ReplaceState replaces all tasks, the active code and the tombstones with
+// the given ones. Tasks not contained in tasks are removed. The replacement
+// of the tasks is recorded in the journal and can therefore be undone.
+func (d *FolderBasedDatabase) ReplaceState(description string, tasks []clocked.Task, activeCode string, deleted Tombstones) error {
+	// Only the tasks that actually change are written and journaled.
+	codes := make([]string, 0, len(tasks)+len(d.taskIndex))
+	states := make([]TaskState, 0, len(tasks)+len(d.taskIndex))
+	replaced := make(map[string]struct{})
+	for _, t := range tasks {
+		replaced[t.Code] = struct{}{}
+		if existing, found := d.TaskByCode(t.Code); found && sameTask(existing, t) {
+			continue
+		}
+		codes = append(codes, t.Code)
+		c := t.Copy()
+		states = append(states, TaskState{Code: t.Code, Task: &c})
+	}
 	for _, t := range d.taskIndex {
-		l := strings.ToLower(t.Label())
-		if strings.Contains(l, q) {
-			result = append(result, t)
+		if _, found := replaced[t.Code]; !found {
+			codes = append(codes, t.Code)
+			states = append(states, TaskState{Code: t.Code})
+		}
+	}
+	if len(states) > 0 || activeCode != d.activeCode {
+		op := d.beginOperation(description, codes...)
+		if err := d.restoreState(states, activeCode); err != nil {
+			return err
+		}
+		if err := d.commitOperation(op); err != nil {
+			return err
+		}
+	}
+	d.deleted = deleted.Copy()
+	return saveTombstones(filepath.Join(d.rootFolder, DeletedFilename), d.deleted)
+}
+
+// sameTask reports if both tasks contain the same data.
+func sameTask(a, b clocked.Task) bool {
+	if a.ID != b.ID || a.Code != b.Code || a.Title != b.Title || a.Updated != b.Updated {
+		return false
+	}
+	if len(a.Tags) != len(b.Tags) || len(a.Bookings) != len(b.Bookings) {
+		return false
+	}
+	for idx := range a.Tags {
+		if a.Tags[idx] != b.Tags[idx] {
+			return false
+		}
+	}
+	for idx := range a.Bookings {
+		if a.Bookings[idx] != b.Bookings[idx] {
+			return false
 		}
 	}
-	return result, nil
+	return true
 }
 
 func (d *FolderBasedDatabase) setActiveCode(code string) error {
@@ -339,5 +528,5 @@ func (d *FolderBasedDatabase) setActiveCode(code string) error {
 		return err
 	}
 	d.activeCode = code
-	return ioutil.WriteFile(path, []byte(code), 0600)
+	return WriteFile(path, []byte(code))
 }
@@ -2,10 +2,10 @@ package database
 
 import (
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/query"
 )
 
 type InMemory struct {
@@ -27,7 +27,7 @@ func (d *InMemory) TaskByCode(code string) (clocked.Task, bool) {
 	if !found {
 		return clocked.Task{}, false
 	}
-	return d.tasks[idx], false
+	return d.tasks[idx], true
 }
 
 func (d *InMemory) ActiveCode() string {
@@ -47,6 +47,9 @@ func (d *InMemory) ActiveTask() (clocked.Task, bool) {
 }
 
 func (d *InMemory) AddTask(t clocked.Task) error {
+	if err := ValidateCode(t.Code); err != nil {
+		return err
+	}
 	_, exists := d.taskmap[t.Code]
 	if exists {
 		return fmt.Errorf("a task with this code already exists")
@@ -60,6 +63,21 @@ func (d *InMemory) UpdateTask(oldCode string, task clocked.Task) error {
 	return fmt.Errorf("not implemented")
 }
 
+func (d *InMemory) UpdateBooking(code string, index int, booking clocked.Booking) error {
+	taskIdx, exists := d.taskmap[code]
+	if !exists {
+		return fmt.Errorf("the requested task does not exist")
+	}
+	task := &d.tasks[taskIdx]
+	if err := validateBooking(*task, d.activeCode, index, booking); err != nil {
+		return err
+	}
+	task.Bookings[index].Start = booking.Start
+	task.Bookings[index].Stop = booking.Stop
+	task.Bookings[index].Touch(time.Now())
+	return nil
+}
+
 func (d *InMemory) AllTasks() ([]clocked.Task, error) {
 	return d.tasks, nil
 }
@@ -69,16 +87,22 @@ func (d *InMemory) Empty() bool {
 }
 
 func (d *InMemory) ClockInto(code string) error {
+	return d.ClockIntoRepository(code, "")
+}
+
+func (d *InMemory) ClockIntoRepository(code, repository string) error {
 	taskIdx, exists := d.taskmap[code]
 	if !exists {
 		return fmt.Errorf("the requested task does not exist")
 	}
 	if d.activeCode != "" {
-		if err := d.ClockOutOf(code); err != nil {
+		if err := d.ClockOutOf(d.activeCode); err != nil {
 			return err
 		}
 	}
-	(&d.tasks[taskIdx]).Start(time.Now())
+	task := &d.tasks[taskIdx]
+	task.Start(time.Now())
+	task.Bookings[len(task.Bookings)-1].Repository = repository
 	d.activeCode = code
 	return nil
 }
@@ -98,16 +122,17 @@ func (d *InMemory) LoadState() error {
 }
 
 func (d *InMemory) GenerateDailySummary(t time.Time) Summary {
-	return Summary{}
+	from := StartOfDay(t)
+	return d.GenerateSummary(from, from.AddDate(0, 0, 1))
+}
+
+func (d *InMemory) GenerateSummary(from, to time.Time) Summary {
+	return summarize(d.tasks, from, to)
 }
 
 func (d *InMemory) FilteredTasks(filter string) ([]clocked.Task, error) {
-	res := make([]clocked.Task, 0, 5)
-	f := strings.ToLower(filter)
-	for _, t := range d.tasks {
-		if strings.Contains(strings.ToLower(t.Label()), f) {
-			res = append(res, t)
-		}
-	}
-	return res, nil
+	return query.Filter(d.tasks, query.Parse(filter), query.Context{
+		Now:        time.Now(),
+		ActiveCode: d.activeCode,
+	}), nil
 }
@@ -0,0 +1,18 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zerok/clocked"
+)
+
+func TestInMemoryTaskByCode(t *testing.T) {
+	db := NewInMemory()
+	_, found := db.TaskByCode("a")
+	require.False(t, found)
+	require.NoError(t, db.AddTask(clocked.Task{Code: "a", Title: "A"}))
+	task, found := db.TaskByCode("a")
+	require.True(t, found, "existing tasks have to be reported as found")
+	require.Equal(t, "A", task.Title)
+}
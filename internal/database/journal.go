@@ -0,0 +1,269 @@
+package database
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/zerok/clocked"
+	"gopkg.in/yaml.v2"
+)
+
+const JournalFilename = "journal.yml"
+
+// maxJournalSize is the number of operations kept in the journal.
+const maxJournalSize = 100
+
+// ErrNothingToUndo is returned by Undo if the journal contains no operation
+// that could be reverted.
+var ErrNothingToUndo = fmt.Errorf("nothing to undo")
+
+// ErrNothingToRedo is returned by Redo if no operation has been undone.
+var ErrNothingToRedo = fmt.Errorf("nothing to redo")
+
+// Undoer is implemented by databases that record their mutations in a
+// journal so that they can be reverted again.
+type Undoer interface {
+	// Undo reverts the last operation and returns its description.
+	Undo() (string, error)
+	// Redo applies the last reverted operation again and returns its
+	// description.
+	Redo() (string, error)
+	CanUndo() bool
+	CanRedo() bool
+}
+
+// TaskState is the state of a task at a given point in time. If Task is nil,
+// the task with the given code didn't exist. To keep the journal small,
+// bookings that an operation hasn't changed aren't recorded: Task only
+// contains the bookings starting at BookingsFrom.
+type TaskState struct {
+	Code         string        `yaml:"code"`
+	Task         *clocked.Task `yaml:"task,omitempty"`
+	BookingsFrom int           `yaml:"bookings_from,omitempty"`
+}
+
+// Operation is a reversible change of the database. It contains the state
+// of all affected tasks before and after the change.
+type Operation struct {
+	Description      string      `yaml:"description"`
+	Time             string      `yaml:"time"`
+	Before           []TaskState `yaml:"before"`
+	After            []TaskState `yaml:"after"`
+	ActiveCodeBefore string      `yaml:"active_code_before"`
+	ActiveCodeAfter  string      `yaml:"active_code_after"`
+}
+
+// Journal is the list of operations applied to a database. Operations
+// beyond Position have been undone and can be redone.
+type Journal struct {
+	Operations []Operation `yaml:"operations"`
+	Position   int         `yaml:"position"`
+}
+
+// Record appends the given operation and drops all operations that could
+// have been redone.
+func (j *Journal) Record(op Operation) {
+	j.Operations = append(j.Operations[:j.Position], op)
+	if len(j.Operations) > maxJournalSize {
+		j.Operations = j.Operations[len(j.Operations)-maxJournalSize:]
+	}
+	j.Position = len(j.Operations)
+}
+
+func (j *Journal) CanUndo() bool {
+	return j.Position > 0
+}
+
+func (j *Journal) CanRedo() bool {
+	return j.Position < len(j.Operations)
+}
+
+func loadJournal(path string) (*Journal, error) {
+	var j Journal
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &j, nil
+		}
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+	if j.Position > len(j.Operations) || j.Position < 0 {
+		j.Position = len(j.Operations)
+	}
+	return &j, nil
+}
+
+func saveJournal(path string, j *Journal) error {
+	data, err := yaml.Marshal(j)
+	if err != nil {
+		return err
+	}
+	return WriteFile(path, data)
+}
+
+// captureState returns the current state of all tasks with the given codes.
+func (d *FolderBasedDatabase) captureState(codes []string) []TaskState {
+	result := make([]TaskState, 0, len(codes))
+	seen := make(map[string]struct{})
+	for _, code := range codes {
+		if _, found := seen[code]; found || code == "" {
+			continue
+		}
+		seen[code] = struct{}{}
+		state := TaskState{Code: code}
+		if t, found := d.TaskByCode(code); found {
+			c := t.Copy()
+			state.Task = &c
+		}
+		result = append(result, state)
+	}
+	return result
+}
+
+// beginOperation records the state of all tasks that might be affected by
+// the upcoming operation.
+func (d *FolderBasedDatabase) beginOperation(description string, codes ...string) *Operation {
+	return &Operation{
+		Description:      description,
+		Time:             time.Now().Format(time.RFC3339),
+		Before:           d.captureState(codes),
+		ActiveCodeBefore: d.activeCode,
+	}
+}
+
+// commitOperation records the state after the given operation and adds it
+// to the journal.
+func (d *FolderBasedDatabase) commitOperation(op *Operation) error {
+	codes := make([]string, 0, len(op.Before))
+	for _, s := range op.Before {
+		codes = append(codes, s.Code)
+	}
+	op.After = d.captureState(codes)
+	op.ActiveCodeAfter = d.activeCode
+	trimStates(op.Before, op.After)
+	d.journal.Record(*op)
+	return saveJournal(filepath.Join(d.rootFolder, JournalFilename), d.journal)
+}
+
+// trimStates removes the bookings that are the same before and after an
+// operation from both states. before and after have to contain the same
+// tasks in the same order.
+func trimStates(before, after []TaskState) {
+	for idx := range before {
+		b, a := before[idx].Task, after[idx].Task
+		if b == nil || a == nil {
+			continue
+		}
+		from := 0
+		for from < len(b.Bookings) && from < len(a.Bookings) && b.Bookings[from] == a.Bookings[from] {
+			from++
+		}
+		b.Bookings = b.Bookings[from:]
+		a.Bookings = a.Bookings[from:]
+		before[idx].BookingsFrom = from
+		after[idx].BookingsFrom = from
+	}
+}
+
+// restoreState brings the given tasks and the active code back into the
+// given state.
+func (d *FolderBasedDatabase) restoreState(states []TaskState, activeCode string) error {
+	// Check that the codes are valid and that the unchanged bookings
+	// still exist before anything is modified.
+	for _, s := range states {
+		if s.Task == nil {
+			continue
+		}
+		if err := ValidateCode(s.Task.Code); err != nil {
+			return err
+		}
+		if s.BookingsFrom == 0 {
+			continue
+		}
+		t, found := d.TaskByCode(s.Code)
+		if !found || len(t.Bookings) < s.BookingsFrom {
+			return fmt.Errorf("the journal doesn't match the bookings of %s", s.Code)
+		}
+	}
+	removed := make(map[string]struct{})
+	for _, s := range states {
+		idx := -1
+		for i, t := range d.taskIndex {
+			if t.Code == s.Code {
+				idx = i
+				break
+			}
+		}
+		if s.Task == nil {
+			if idx == -1 {
+				continue
+			}
+			removed[d.taskIndex[idx].Key()] = struct{}{}
+			d.taskIndex = append(d.taskIndex[:idx], d.taskIndex[idx+1:]...)
+			if err := d.deleteTaskByCode(s.Code); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		c := s.Task.Copy()
+		task := &c
+		if s.BookingsFrom > 0 {
+			unchanged := d.taskIndex[idx].Bookings[:s.BookingsFrom]
+			task.Bookings = append(append([]clocked.Booking{}, unchanged...), task.Bookings...)
+		}
+		if idx == -1 {
+			d.taskIndex = append(d.taskIndex, *task)
+		} else {
+			d.taskIndex[idx] = *task
+		}
+		d.taskCodeIndex[task.Code] = struct{}{}
+		if err := d.saveTask(task); err != nil {
+			return err
+		}
+	}
+	if err := d.updateTombstones(removed); err != nil {
+		return err
+	}
+	if activeCode == d.activeCode {
+		return nil
+	}
+	return d.setActiveCode(activeCode)
+}
+
+func (d *FolderBasedDatabase) CanUndo() bool {
+	return d.journal.CanUndo()
+}
+
+func (d *FolderBasedDatabase) CanRedo() bool {
+	return d.journal.CanRedo()
+}
+
+func (d *FolderBasedDatabase) Undo() (string, error) {
+	if !d.journal.CanUndo() {
+		return "", ErrNothingToUndo
+	}
+	op := d.journal.Operations[d.journal.Position-1]
+	if err := d.restoreState(op.Before, op.ActiveCodeBefore); err != nil {
+		return "", err
+	}
+	d.journal.Position--
+	return op.Description, saveJournal(filepath.Join(d.rootFolder, JournalFilename), d.journal)
+}
+
+func (d *FolderBasedDatabase) Redo() (string, error) {
+	if !d.journal.CanRedo() {
+		return "", ErrNothingToRedo
+	}
+	op := d.journal.Operations[d.journal.Position]
+	if err := d.restoreState(op.After, op.ActiveCodeAfter); err != nil {
+		return "", err
+	}
+	d.journal.Position++
+	return op.Description, saveJournal(filepath.Join(d.rootFolder, JournalFilename), d.journal)
+}
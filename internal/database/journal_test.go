@@ -0,0 +1,216 @@
+package database
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/zerok/clocked"
+)
+
+func newTestDatabase(t *testing.T) (*FolderBasedDatabase, func()) {
+	dir, err := ioutil.TempDir("", "clocked")
+	require.NoError(t, err)
+	log := logrus.New()
+	log.Out = ioutil.Discard
+	db, err := NewDatabase(dir, log)
+	require.NoError(t, err)
+	require.NoError(t, db.LoadState())
+	return db.(*FolderBasedDatabase), func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestUndoRedo(t *testing.T) {
+	db, cleanup := newTestDatabase(t)
+	defer cleanup()
+	require.False(t, db.CanUndo(), "a new database should have nothing to undo")
+
+	require.NoError(t, db.AddTask(clocked.Task{Code: "a"}))
+	require.NoError(t, db.AddTask(clocked.Task{Code: "b"}))
+	require.NoError(t, db.ClockInto("a"))
+	require.NoError(t, db.ClockInto("b"))
+
+	desc, err := db.Undo()
+	require.NoError(t, err)
+	require.Equal(t, "Clock into b", desc)
+	require.Equal(t, "a", db.ActiveCode(), "a should be active again")
+	b, _ := db.TaskByCode("b")
+	require.Len(t, b.Bookings, 0, "the booking of b should have been removed")
+	a, _ := db.TaskByCode("a")
+	require.Equal(t, "", a.Bookings[0].Stop, "a should no longer be clocked out")
+
+	// The journal has to survive a restart.
+	require.NoError(t, db.LoadState())
+	require.True(t, db.CanRedo())
+	_, err = db.Redo()
+	require.NoError(t, err)
+	require.Equal(t, "b", db.ActiveCode())
+
+	require.NoError(t, db.UpdateTask("a", clocked.Task{Code: "c"}))
+	_, err = db.Undo()
+	require.NoError(t, err)
+	require.NoError(t, db.LoadState())
+	_, found := db.TaskByCode("c")
+	require.False(t, found, "the renamed task should be gone after undoing the rename")
+	_, found = db.TaskByCode("a")
+	require.True(t, found, "the original task should be back after undoing the rename")
+
+	// Recording a new operation discards everything that could be redone.
+	require.NoError(t, db.ClockOutOf("b"))
+	require.False(t, db.CanRedo())
+	_, err = db.Redo()
+	require.Equal(t, ErrNothingToRedo, err)
+}
+
+func TestUpdateTasksIsUndoneAtOnce(t *testing.T) {
+	db, cleanup := newTestDatabase(t)
+	defer cleanup()
+	require.NoError(t, db.AddTask(clocked.Task{Code: "a", Tags: []string{"x"}}))
+	require.NoError(t, db.AddTask(clocked.Task{Code: "b", Tags: []string{"x"}}))
+	require.NoError(t, db.UpdateTasks("Rename tag x", []clocked.Task{
+		{Code: "a", Tags: []string{"y"}},
+		{Code: "b", Tags: []string{"y"}},
+	}))
+	a, _ := db.TaskByCode("a")
+	require.Equal(t, []string{"y"}, a.Tags)
+
+	desc, err := db.Undo()
+	require.NoError(t, err)
+	require.Equal(t, "Rename tag x", desc)
+	a, _ = db.TaskByCode("a")
+	b, _ := db.TaskByCode("b")
+	require.Equal(t, []string{"x"}, a.Tags)
+	require.Equal(t, []string{"x"}, b.Tags)
+
+	require.Error(t, db.UpdateTasks("Unknown", []clocked.Task{{Code: "c"}}))
+}
+
+func TestUpdateTasksRestoresTasksOnFailure(t *testing.T) {
+	db, cleanup := newTestDatabase(t)
+	defer cleanup()
+	require.NoError(t, db.AddTask(clocked.Task{Code: "a", Tags: []string{"x"}}))
+	require.NoError(t, db.AddTask(clocked.Task{Code: "b", Tags: []string{"x"}}))
+	require.Error(t, db.UpdateTasks("Duplicate", []clocked.Task{{Code: "a"}, {Code: "a"}}))
+
+	// Writing b fails as its file has been replaced by a folder.
+	path := filepath.Join(db.rootFolder, TasksFolder, "b.yml")
+	require.NoError(t, os.Remove(path))
+	require.NoError(t, os.Mkdir(path, 0700))
+	require.Error(t, db.UpdateTasks("Rename tag x", []clocked.Task{
+		{Code: "a", Tags: []string{"y"}},
+		{Code: "b", Tags: []string{"y"}},
+	}))
+	a, _ := db.TaskByCode("a")
+	require.Equal(t, []string{"x"}, a.Tags)
+	loaded, err := db.loadTask(filepath.Join(db.rootFolder, TasksFolder, "a.yml"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"x"}, loaded.Tags, "a should have been restored on disk")
+	desc, err := db.Undo()
+	require.NoError(t, err)
+	require.Equal(t, "Add task b", desc, "the failed update should not be recorded")
+}
+
+func TestUpdateBookingValidation(t *testing.T) {
+	db, cleanup := newTestDatabase(t)
+	defer cleanup()
+	start := time.Date(2018, 3, 1, 9, 0, 0, 0, time.UTC)
+	open := clocked.Booking{Start: start.Format(time.RFC3339)}
+	closed := clocked.Booking{Start: start.Format(time.RFC3339), Stop: start.Add(time.Hour).Format(time.RFC3339)}
+	require.NoError(t, db.AddTask(clocked.Task{Code: "a"}))
+	require.NoError(t, db.ClockInto("a"))
+	require.NoError(t, db.ClockInto("a"))
+
+	require.Error(t, db.UpdateBooking("a", 0, open), "only the running booking may be open")
+	require.Error(t, db.UpdateBooking("a", 1, closed), "the running booking is stopped by clocking out")
+	require.Error(t, db.UpdateBooking("a", 2, closed))
+	require.NoError(t, db.UpdateBooking("a", 1, open))
+	require.NoError(t, db.UpdateBooking("a", 0, closed))
+
+	// Writing the task fails as its file has been replaced by a folder.
+	path := filepath.Join(db.rootFolder, TasksFolder, "a.yml")
+	require.NoError(t, os.Remove(path))
+	require.NoError(t, os.Mkdir(path, 0700))
+	closed.Stop = start.Add(2 * time.Hour).Format(time.RFC3339)
+	require.Error(t, db.UpdateBooking("a", 0, closed))
+	a, _ := db.TaskByCode("a")
+	require.True(t, start.Add(time.Hour).Equal(*a.Bookings[0].StopTime()), "the booking should have been restored")
+	require.Equal(t, "Edit booking of a", db.journal.Operations[db.journal.Position-1].Description)
+	require.Equal(t, 5, db.journal.Position, "the failed edit should not be recorded")
+}
+
+func TestJournalOnlyKeepsChangedBookings(t *testing.T) {
+	db, cleanup := newTestDatabase(t)
+	defer cleanup()
+	require.NoError(t, db.AddTask(clocked.Task{Code: "a"}))
+	for i := 0; i < 5; i++ {
+		require.NoError(t, db.ClockInto("a"))
+		require.NoError(t, db.ClockOutOf("a"))
+	}
+	require.NoError(t, db.ClockInto("a"))
+
+	journal, err := loadJournal(filepath.Join(db.rootFolder, JournalFilename))
+	require.NoError(t, err)
+	op := journal.Operations[journal.Position-1]
+	require.Equal(t, 5, op.Before[0].BookingsFrom)
+	require.Len(t, op.Before[0].Task.Bookings, 0)
+	require.Len(t, op.After[0].Task.Bookings, 1)
+
+	_, err = db.Undo()
+	require.NoError(t, err)
+	a, _ := db.TaskByCode("a")
+	require.Len(t, a.Bookings, 5)
+	_, err = db.Undo()
+	require.NoError(t, err)
+	a, _ = db.TaskByCode("a")
+	require.Len(t, a.Bookings, 5)
+	require.Nil(t, a.Bookings[4].StopTime(), "the last booking should be running again")
+	_, err = db.Redo()
+	require.NoError(t, err)
+	_, err = db.Redo()
+	require.NoError(t, err)
+	a, _ = db.TaskByCode("a")
+	require.Len(t, a.Bookings, 6)
+	require.Equal(t, "a", db.ActiveCode())
+}
+
+func TestInvalidCodesAreRejected(t *testing.T) {
+	db, cleanup := newTestDatabase(t)
+	defer cleanup()
+	eventLog := newTestEventLogDatabase(t, db.rootFolder)
+	require.NoError(t, db.AddTask(clocked.Task{Code: "a"}))
+	require.NoError(t, eventLog.AddTask(clocked.Task{Code: "a"}))
+	for _, code := range []string{"", "../x", `..\x`, "a/b", "a b"} {
+		require.Error(t, db.AddTask(clocked.Task{Code: code}), code)
+		require.Error(t, db.UpdateTask("a", clocked.Task{Code: code}), code)
+		require.Error(t, eventLog.AddTask(clocked.Task{Code: code}), code)
+		require.Error(t, eventLog.UpdateTask("a", clocked.Task{Code: code}), code)
+		require.Error(t, NewInMemory().AddTask(clocked.Task{Code: code}), code)
+	}
+	_, err := os.Stat(filepath.Join(db.rootFolder, "x.yml"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestReplaceStateOnlyJournalsChangedTasks(t *testing.T) {
+	db, cleanup := newTestDatabase(t)
+	defer cleanup()
+	require.NoError(t, db.AddTask(clocked.Task{Code: "a"}))
+	require.NoError(t, db.AddTask(clocked.Task{Code: "b"}))
+	tasks, _ := db.AllTasks()
+	merged := []clocked.Task{tasks[0].Copy(), tasks[1].Copy()}
+	merged[1].Title = "B"
+	operations := len(db.journal.Operations)
+
+	require.NoError(t, db.ReplaceState("Sync", merged, "", nil))
+	require.Len(t, db.journal.Operations, operations+1)
+	op := db.journal.Operations[operations]
+	require.Len(t, op.Before, 1)
+	require.Equal(t, "b", op.Before[0].Code)
+
+	require.NoError(t, db.ReplaceState("Sync", merged, "", nil))
+	require.Len(t, db.journal.Operations, operations+1, "nothing changed")
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/zerok/clocked"
+)
+
+// DayFormat is the format used for the keys of Summary.DailyTotals.
+const DayFormat = "2006-01-02"
+
+// summarize collects all bookings of the given tasks that started within
+// [from, to) and aggregates their durations per task and per day.
+func summarize(tasks []clocked.Task, from, to time.Time) Summary {
+	summary := Summary{}
+	summary.Totals = make(map[string]time.Duration)
+	summary.DailyTotals = make(map[string]time.Duration)
+	summary.RepositoryTotals = make(map[string]time.Duration)
+	summary.Bookings = make([]TaskBooking, 0, 10)
+	for _, tsk := range tasks {
+		for _, b := range tsk.Bookings {
+			start := b.StartTime()
+			if start == nil || start.Before(from) || !start.Before(to) {
+				continue
+			}
+			stop := b.StopTime()
+			summary.Bookings = append(summary.Bookings, TaskBooking{
+				Code:       tsk.Code,
+				Start:      start,
+				Stop:       stop,
+				Repository: b.Repository,
+			})
+			if stop != nil {
+				dur := stop.Sub(*start)
+				summary.Totals[tsk.Code] += dur
+				summary.DailyTotals[start.In(from.Location()).Format(DayFormat)] += dur
+				if b.Repository != "" {
+					summary.RepositoryTotals[b.Repository] += dur
+				}
+				summary.Total += dur
+			}
+		}
+	}
+	sort.Sort(ByStart(summary.Bookings))
+	return summary
+}
+
+// StartOfDay returns midnight of the day t is in.
+func StartOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// SameDay reports if a and b are on the same calendar day.
+func SameDay(a, b time.Time) bool {
+	aYear, aMonth, aDay := a.Date()
+	bYear, bMonth, bDay := b.Date()
+	return aYear == bYear && aMonth == bMonth && aDay == bDay
+}
+
+// FormatHours renders a duration with hour and minute precision, e.g.
+// 1h05m.
+func FormatHours(d time.Duration) string {
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// StartOfWeek returns midnight of the Monday of the week t is in.
+func StartOfWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return StartOfDay(t).AddDate(0, 0, -offset)
+}
+
+// StartOfMonth returns midnight of the first day of the month t is in.
+func StartOfMonth(t time.Time) time.Time {
+	y, m, _ := t.Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+}
+
+// StartOfYear returns midnight of the first day of the year t is in.
+func StartOfYear(t time.Time) time.Time {
+	return time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"io/ioutil"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DeletedFilename is the file inside of a folder-based store that records
+// which tasks have been deleted so that the deletion can be synchronized
+// with other stores.
+const DeletedFilename = "deleted.yml"
+
+// Tombstones maps the keys of deleted tasks (see clocked.Task.Key) to the
+// time they were deleted at.
+type Tombstones map[string]time.Time
+
+// Copy returns a copy that can be modified independently.
+func (t Tombstones) Copy() Tombstones {
+	c := make(Tombstones, len(t))
+	for key, tm := range t {
+		c[key] = tm
+	}
+	return c
+}
+
+func loadTombstones(path string) (Tombstones, error) {
+	t := Tombstones{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return t, nil
+		}
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func saveTombstones(path string, t Tombstones) error {
+	data, err := yaml.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return WriteFile(path, data)
+}
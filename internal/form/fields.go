@@ -0,0 +1,134 @@
+package form
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// FieldType determines which values a field accepts and how it is edited.
+type FieldType int
+
+const (
+	// TextField accepts any value.
+	TextField FieldType = iota
+	// DateField accepts dates in the DateFormat.
+	DateField
+	// TimeField accepts times of the day in the TimeFormat.
+	TimeField
+	// DurationField accepts durations like 1h30m.
+	DurationField
+	// ChoiceField accepts only one of the field's choices. Instead of
+	// typing, the value is changed by cycling through them.
+	ChoiceField
+	// TagsField holds a whitespace separated list of tags and suggests
+	// completions from the field's choices.
+	TagsField
+)
+
+const (
+	DateFormat = "2006-01-02"
+	TimeFormat = "15:04"
+)
+
+// Validator checks the non-empty value of a field.
+type Validator func(value string) error
+
+// Pattern makes sure that the value matches the given expression.
+func Pattern(re *regexp.Regexp, message string) Validator {
+	return func(value string) error {
+		if !re.MatchString(value) {
+			return fmt.Errorf("%s", message)
+		}
+		return nil
+	}
+}
+
+// Unique makes sure that the value isn't known yet according to exists.
+// The value given as except is allowed nonetheless, e.g. the original value
+// of an existing record.
+func Unique(exists func(string) bool, except string, message string) Validator {
+	return func(value string) error {
+		if value != except && exists(value) {
+			return fmt.Errorf("%s", message)
+		}
+		return nil
+	}
+}
+
+func (f *Field) validate(value string) error {
+	if value == "" {
+		if f.IsRequired {
+			return fmt.Errorf("This field is required.")
+		}
+		return nil
+	}
+	if err := f.validateType(value); err != nil {
+		return err
+	}
+	for _, v := range f.Validators {
+		if err := v(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Field) validateType(value string) error {
+	switch f.Type {
+	case DateField:
+		if _, err := time.ParseInLocation(DateFormat, value, time.Local); err != nil {
+			return fmt.Errorf("Expected a date like %s.", DateFormat)
+		}
+	case TimeField:
+		if _, err := time.ParseInLocation(TimeFormat, value, time.Local); err != nil {
+			return fmt.Errorf("Expected a time like %s.", TimeFormat)
+		}
+	case DurationField:
+		if d, err := time.ParseDuration(value); err != nil || d < 0 {
+			return fmt.Errorf("Expected a duration like 1h30m.")
+		}
+	case ChoiceField:
+		for _, c := range f.Choices {
+			if c == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("Expected one of %s.", strings.Join(f.Choices, ", "))
+	}
+	return nil
+}
+
+// Tags returns the unique, whitespace separated values of the given field.
+func (f *Form) Tags(field string) []string {
+	var result []string
+	seen := make(map[string]struct{})
+	for _, t := range strings.Fields(f.Value(field)) {
+		if _, found := seen[t]; found {
+			continue
+		}
+		seen[t] = struct{}{}
+		result = append(result, t)
+	}
+	return result
+}
+
+// Date parses the value of a DateField.
+func (f *Form) Date(field string) (time.Time, error) {
+	return time.ParseInLocation(DateFormat, f.Value(field), time.Local)
+}
+
+// Time returns the time of a TimeField on the given day.
+func (f *Form) Time(field string, day time.Time) (time.Time, error) {
+	t, err := time.ParseInLocation(TimeFormat, f.Value(field), time.Local)
+	if err != nil {
+		return t, err
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, day.Location()), nil
+}
+
+// Duration parses the value of a DurationField.
+func (f *Form) Duration(field string) (time.Duration, error) {
+	return time.ParseDuration(f.Value(field))
+}
@@ -0,0 +1,116 @@
+package form
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	termbox "github.com/nsf/termbox-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldTypes(t *testing.T) {
+	tests := []struct {
+		field Field
+		valid []string
+		wrong []string
+	}{
+		{Field{Type: DateField}, []string{"", "2018-02-28"}, []string{"2018-02-30", "tomorrow"}},
+		{Field{Type: TimeField}, []string{"09:30", "23:59"}, []string{"24:00", "9"}},
+		{Field{Type: DurationField}, []string{"1h30m", "45m"}, []string{"-1h", "1 hour"}},
+		{Field{Type: ChoiceField, Choices: []string{"a", "b"}}, []string{"a", "b"}, []string{"c"}},
+		{Field{Type: DateField, IsRequired: true}, []string{"2018-01-01"}, []string{""}},
+	}
+	for _, test := range tests {
+		for _, v := range test.valid {
+			require.NoError(t, test.field.validate(v), v)
+		}
+		for _, v := range test.wrong {
+			require.Error(t, test.field.validate(v), v)
+		}
+	}
+}
+
+func TestValidators(t *testing.T) {
+	exists := func(code string) bool {
+		return code == "ABC-1" || code == "ABC-2"
+	}
+	f := NewForm([]Field{{
+		Code: "code",
+		Validators: []Validator{
+			Pattern(regexp.MustCompile(`^[A-Z]+-[0-9]+$`), "invalid code"),
+			Unique(exists, "ABC-1", "code exists"),
+		},
+	}})
+	f.SetValue("code", "abc")
+	require.False(t, f.Validate())
+	require.Equal(t, "invalid code", f.Fields()[0].Error)
+	f.SetValue("code", "ABC-2")
+	require.False(t, f.Validate())
+	require.Equal(t, "code exists", f.Fields()[0].Error)
+	f.SetValue("code", "ABC-1")
+	require.True(t, f.Validate())
+	f.SetValue("code", "ABC-3")
+	require.True(t, f.Validate())
+	// Validators are not run for empty values.
+	f.SetValue("code", "")
+	require.True(t, f.Validate())
+}
+
+func TestTypedValues(t *testing.T) {
+	f := NewForm([]Field{
+		{Code: "tags", Value: " a  b a\tc "},
+		{Code: "date", Value: "2018-03-01"},
+		{Code: "time", Value: "09:15"},
+		{Code: "duration", Value: "1h30m"},
+	})
+	require.Equal(t, []string{"a", "b", "c"}, f.Tags("tags"))
+	day, err := f.Date("date")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2018, 3, 1, 0, 0, 0, 0, time.Local), day)
+	at, err := f.Time("time", day)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2018, 3, 1, 9, 15, 0, 0, time.Local), at)
+	d, err := f.Duration("duration")
+	require.NoError(t, err)
+	require.Equal(t, 90*time.Minute, d)
+}
+
+func TestChoiceField(t *testing.T) {
+	f := NewForm([]Field{{Code: "c", Type: ChoiceField, Choices: []string{"daily", "weekly", "monthly"}}})
+	require.True(t, f.HandleEvent(termbox.Event{Key: termbox.KeyArrowRight}))
+	require.Equal(t, "daily", f.Value("c"))
+	f.HandleEvent(termbox.Event{Key: termbox.KeySpace})
+	require.Equal(t, "weekly", f.Value("c"))
+	f.HandleEvent(termbox.Event{Key: termbox.KeyArrowLeft})
+	f.HandleEvent(termbox.Event{Key: termbox.KeyArrowLeft})
+	require.Equal(t, "monthly", f.Value("c"))
+	f.HandleEvent(termbox.Event{Ch: 'W'})
+	require.Equal(t, "weekly", f.Value("c"))
+	// Other characters don't modify the value.
+	f.HandleEvent(termbox.Event{Ch: 'x'})
+	f.HandleEvent(termbox.Event{Key: termbox.KeyBackspace2})
+	require.Equal(t, "weekly", f.Value("c"))
+}
+
+func TestTagsFieldSuggestions(t *testing.T) {
+	f := NewForm([]Field{{Code: "tags", Type: TagsField, Choices: []string{"review", "meeting", "release"}}})
+	require.Empty(t, f.Suggestion())
+	f.HandleEvent(termbox.Event{Ch: 'r'})
+	require.Equal(t, []string{"release", "review"}, f.Suggestions())
+	require.Equal(t, "elease", f.Suggestion())
+	f.HandleEvent(termbox.Event{Key: termbox.KeyArrowDown})
+	require.Equal(t, "eview", f.Suggestion())
+	f.HandleEvent(termbox.Event{Key: termbox.KeyArrowRight})
+	require.Equal(t, "review ", f.Value("tags"))
+	require.Empty(t, f.Suggestion())
+
+	// Tags that are already used are not suggested again.
+	f.HandleEvent(termbox.Event{Ch: 'r'})
+	require.Equal(t, []string{"release"}, f.Suggestions())
+	f.HandleEvent(termbox.Event{Ch: 'x'})
+	require.Empty(t, f.Suggestions())
+	// Without a suggestion, the arrow keys move the cursor.
+	f.HandleEvent(termbox.Event{Key: termbox.KeyArrowLeft})
+	require.Equal(t, 8, f.Editor("tags").Cursor())
+}
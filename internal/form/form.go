@@ -2,13 +2,17 @@ package form
 
 type Form struct {
 	fields       []Field
+	editors      []*LineEditor
 	focusedField int
+	// suggestion is the index of the selected suggestion of the focused
+	// tags field.
+	suggestion int
 }
 
 func (f *Form) Values() map[string]string {
 	result := make(map[string]string)
-	for _, field := range f.fields {
-		result[field.Code] = field.Value
+	for idx, field := range f.fields {
+		result[field.Code] = f.editors[idx].Value()
 	}
 	return result
 }
@@ -23,6 +27,7 @@ func (f *Form) Next() {
 		idx = 0
 	}
 	f.focusedField = idx
+	f.suggestion = 0
 }
 
 func (f *Form) Previous() {
@@ -35,6 +40,7 @@ func (f *Form) Previous() {
 		idx = num - 1
 	}
 	f.focusedField = idx
+	f.suggestion = 0
 }
 
 func (f *Form) IsFocused(code string) bool {
@@ -59,18 +65,41 @@ func (f *Form) IsValid() bool {
 
 func (f *Form) Fields() []Field {
 	res := make([]Field, 0, len(f.fields))
-	for _, fld := range f.fields {
+	for idx, fld := range f.fields {
+		fld.Value = f.editors[idx].Value()
 		res = append(res, fld)
 	}
 	return res
 }
 
+// Editor returns the editor of the field with the given code.
+func (f *Form) Editor(field string) *LineEditor {
+	for idx, fld := range f.fields {
+		if fld.Code == field {
+			return f.editors[idx]
+		}
+	}
+	return nil
+}
+
+// FocusedEditor returns the editor of the focused field.
+func (f *Form) FocusedEditor() *LineEditor {
+	return f.Editor(f.FocusedField())
+}
+
 type Field struct {
 	Code       string
 	Label      string
 	Value      string
 	IsRequired bool
 	Error      string
+	Type       FieldType
+	// Choices are the values a ChoiceField can take or the suggestions
+	// offered by a TagsField.
+	Choices []string
+	// Validators are run in order after the type of the value has been
+	// checked. The first error is reported.
+	Validators []Validator
 }
 
 func (f *Field) IsValid() bool {
@@ -79,10 +108,12 @@ func (f *Field) IsValid() bool {
 
 func NewForm(fields []Field) *Form {
 	f := Form{
-		fields: make([]Field, 0, len(fields)),
+		fields:  make([]Field, 0, len(fields)),
+		editors: make([]*LineEditor, 0, len(fields)),
 	}
 	for _, fld := range fields {
 		f.fields = append(f.fields, fld)
+		f.editors = append(f.editors, NewLineEditor(fld.Value))
 	}
 	return &f
 }
@@ -90,30 +121,24 @@ func NewForm(fields []Field) *Form {
 func (f *Form) Validate() bool {
 	var invalid bool
 	for idx := range f.fields {
-		if f.fields[idx].IsRequired && f.fields[idx].Value == "" {
-			f.fields[idx].Error = "This field is required."
+		f.fields[idx].Error = ""
+		if err := f.fields[idx].validate(f.editors[idx].Value()); err != nil {
+			f.fields[idx].Error = err.Error()
 			invalid = true
-		} else {
-			f.fields[idx].Error = ""
 		}
 	}
 	return !invalid
 }
 
 func (f *Form) Value(field string) string {
-	for _, fld := range f.fields {
-		if fld.Code == field {
-			return fld.Value
-		}
+	if e := f.Editor(field); e != nil {
+		return e.Value()
 	}
 	return ""
 }
 
 func (f *Form) SetValue(field string, value string) {
-	for idx, fld := range f.fields {
-		if fld.Code == field {
-			f.fields[idx].Value = value
-			// fld.Value = value
-		}
+	if e := f.Editor(field); e != nil {
+		e.SetValue(value)
 	}
 }
@@ -0,0 +1,238 @@
+package form
+
+import (
+	"unicode"
+
+	runewidth "github.com/mattn/go-runewidth"
+	termbox "github.com/nsf/termbox-go"
+)
+
+// LineEditor is a single-line text input. It works on runes instead of
+// bytes and keeps track of the cursor position as well as of the part of
+// the value that is visible inside of a limited width.
+type LineEditor struct {
+	value  []rune
+	cursor int
+	offset int
+	// killed holds the text removed by the last kill operation so that it
+	// can be yanked back in.
+	killed []rune
+}
+
+// NewLineEditor creates an editor with the given value and the cursor at
+// its end.
+func NewLineEditor(value string) *LineEditor {
+	e := &LineEditor{}
+	e.SetValue(value)
+	return e
+}
+
+func (e *LineEditor) Value() string {
+	return string(e.value)
+}
+
+// SetValue replaces the value and moves the cursor to its end.
+func (e *LineEditor) SetValue(value string) {
+	e.value = []rune(value)
+	e.cursor = len(e.value)
+	e.offset = 0
+}
+
+// Cursor returns the position of the cursor in runes.
+func (e *LineEditor) Cursor() int {
+	return e.cursor
+}
+
+// Insert adds the given text at the cursor position. Line breaks and other
+// control characters are replaced by spaces as the value has to fit into a
+// single line.
+func (e *LineEditor) Insert(s string) {
+	runes := make([]rune, 0, len(s))
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			r = ' '
+		}
+		runes = append(runes, r)
+	}
+	value := make([]rune, 0, len(e.value)+len(runes))
+	value = append(value, e.value[:e.cursor]...)
+	value = append(value, runes...)
+	value = append(value, e.value[e.cursor:]...)
+	e.value = value
+	e.cursor += len(runes)
+}
+
+func (e *LineEditor) Left() {
+	if e.cursor > 0 {
+		e.cursor--
+	}
+}
+
+func (e *LineEditor) Right() {
+	if e.cursor < len(e.value) {
+		e.cursor++
+	}
+}
+
+func (e *LineEditor) Home() {
+	e.cursor = 0
+}
+
+func (e *LineEditor) End() {
+	e.cursor = len(e.value)
+}
+
+// WordLeft moves the cursor to the start of the current or previous word.
+func (e *LineEditor) WordLeft() {
+	e.cursor = e.wordStart()
+}
+
+// WordRight moves the cursor behind the end of the current or next word.
+func (e *LineEditor) WordRight() {
+	idx := e.cursor
+	for idx < len(e.value) && unicode.IsSpace(e.value[idx]) {
+		idx++
+	}
+	for idx < len(e.value) && !unicode.IsSpace(e.value[idx]) {
+		idx++
+	}
+	e.cursor = idx
+}
+
+func (e *LineEditor) wordStart() int {
+	idx := e.cursor
+	for idx > 0 && unicode.IsSpace(e.value[idx-1]) {
+		idx--
+	}
+	for idx > 0 && !unicode.IsSpace(e.value[idx-1]) {
+		idx--
+	}
+	return idx
+}
+
+// Backspace removes the rune before the cursor.
+func (e *LineEditor) Backspace() {
+	if e.cursor == 0 {
+		return
+	}
+	e.remove(e.cursor-1, e.cursor)
+}
+
+// Delete removes the rune under the cursor.
+func (e *LineEditor) Delete() {
+	if e.cursor >= len(e.value) {
+		return
+	}
+	e.remove(e.cursor, e.cursor+1)
+}
+
+// DeleteWord removes the word before the cursor.
+func (e *LineEditor) DeleteWord() {
+	e.kill(e.wordStart(), e.cursor)
+}
+
+// DeleteToStart removes everything before the cursor.
+func (e *LineEditor) DeleteToStart() {
+	e.kill(0, e.cursor)
+}
+
+// DeleteToEnd removes everything starting at the cursor.
+func (e *LineEditor) DeleteToEnd() {
+	e.kill(e.cursor, len(e.value))
+}
+
+// Yank inserts the text removed by the last DeleteWord, DeleteToStart or
+// DeleteToEnd. Like in readline, this is a kill buffer that is local to the
+// editor and has nothing to do with the clipboard of the terminal.
+func (e *LineEditor) Yank() {
+	e.Insert(string(e.killed))
+}
+
+func (e *LineEditor) kill(from, to int) {
+	if from == to {
+		return
+	}
+	e.killed = append([]rune{}, e.value[from:to]...)
+	e.remove(from, to)
+}
+
+func (e *LineEditor) remove(from, to int) {
+	e.value = append(e.value[:from], e.value[to:]...)
+	e.cursor = from
+}
+
+// HandleEvent applies the given key event and reports if it was handled.
+// Besides the arrow keys, home, end, backspace and delete, the usual
+// readline bindings are supported: ^a, ^e, ^b, ^f, ^w, ^u, ^k and ^y.
+// Alt+b and alt+f move by words.
+func (e *LineEditor) HandleEvent(evt termbox.Event) bool {
+	if evt.Mod&termbox.ModAlt != 0 {
+		switch evt.Ch {
+		case 'b':
+			e.WordLeft()
+		case 'f':
+			e.WordRight()
+		default:
+			return false
+		}
+		return true
+	}
+	switch evt.Key {
+	case termbox.KeyArrowLeft, termbox.KeyCtrlB:
+		e.Left()
+	case termbox.KeyArrowRight, termbox.KeyCtrlF:
+		e.Right()
+	case termbox.KeyHome, termbox.KeyCtrlA:
+		e.Home()
+	case termbox.KeyEnd, termbox.KeyCtrlE:
+		e.End()
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		e.Backspace()
+	case termbox.KeyDelete, termbox.KeyCtrlD:
+		e.Delete()
+	case termbox.KeyCtrlW:
+		e.DeleteWord()
+	case termbox.KeyCtrlU:
+		e.DeleteToStart()
+	case termbox.KeyCtrlK:
+		e.DeleteToEnd()
+	case termbox.KeyCtrlY:
+		e.Yank()
+	case termbox.KeySpace:
+		e.Insert(" ")
+	default:
+		if evt.Ch == 0 {
+			return false
+		}
+		e.Insert(string(evt.Ch))
+	}
+	return true
+}
+
+// View returns the part of the value that fits into the given number of
+// terminal cells together with the cell the cursor is in. Wide characters
+// occupy two cells. The visible part is scrolled so that the cursor always
+// stays inside of it.
+func (e *LineEditor) View(width int) (string, int) {
+	if width <= 0 {
+		return "", 0
+	}
+	if e.offset > e.cursor {
+		e.offset = e.cursor
+	}
+	// The cursor needs a cell of its own.
+	for e.offset < e.cursor && runewidth.StringWidth(string(e.value[e.offset:e.cursor]))+1 > width {
+		e.offset++
+	}
+	var visible []rune
+	used := 0
+	for _, r := range e.value[e.offset:] {
+		w := runewidth.RuneWidth(r)
+		if used+w > width {
+			break
+		}
+		visible = append(visible, r)
+		used += w
+	}
+	return string(visible), runewidth.StringWidth(string(e.value[e.offset:e.cursor]))
+}
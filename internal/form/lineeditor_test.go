@@ -0,0 +1,94 @@
+package form
+
+import (
+	"testing"
+
+	termbox "github.com/nsf/termbox-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineEditorMultiByte(t *testing.T) {
+	e := NewLineEditor("Grüße")
+	e.Backspace()
+	e.Backspace()
+	require.Equal(t, "Grü", e.Value())
+	e.Left()
+	e.Insert("ö")
+	require.Equal(t, "Gröü", e.Value())
+	require.Equal(t, 3, e.Cursor())
+	e.Delete()
+	require.Equal(t, "Grö", e.Value())
+}
+
+func TestLineEditorWords(t *testing.T) {
+	e := NewLineEditor("fix  the bug")
+	e.WordLeft()
+	require.Equal(t, 9, e.Cursor())
+	e.WordLeft()
+	require.Equal(t, 5, e.Cursor())
+	e.WordRight()
+	require.Equal(t, 8, e.Cursor())
+
+	e.End()
+	e.DeleteWord()
+	require.Equal(t, "fix  the ", e.Value())
+	e.Home()
+	e.Yank()
+	require.Equal(t, "bugfix  the ", e.Value())
+	e.DeleteToEnd()
+	require.Equal(t, "bug", e.Value())
+	e.DeleteToStart()
+	require.Equal(t, "", e.Value())
+	e.Yank()
+	require.Equal(t, "bug", e.Value())
+}
+
+func TestLineEditorInsertStripsControlCharacters(t *testing.T) {
+	e := NewLineEditor("")
+	e.Insert("a\nb\tc")
+	require.Equal(t, "a b c", e.Value())
+}
+
+func TestLineEditorHandleEvent(t *testing.T) {
+	e := NewLineEditor("ac")
+	require.True(t, e.HandleEvent(termbox.Event{Key: termbox.KeyArrowLeft}))
+	require.True(t, e.HandleEvent(termbox.Event{Ch: 'b'}))
+	require.True(t, e.HandleEvent(termbox.Event{Key: termbox.KeySpace}))
+	require.Equal(t, "ab c", e.Value())
+	require.True(t, e.HandleEvent(termbox.Event{Key: termbox.KeyCtrlA}))
+	require.Equal(t, 0, e.Cursor())
+	require.True(t, e.HandleEvent(termbox.Event{Ch: 'f', Mod: termbox.ModAlt}))
+	require.Equal(t, 2, e.Cursor())
+	require.False(t, e.HandleEvent(termbox.Event{Key: termbox.KeyTab}))
+}
+
+func TestLineEditorView(t *testing.T) {
+	e := NewLineEditor("abcdef")
+	text, cursor := e.View(4)
+	require.Equal(t, "def", text)
+	require.Equal(t, 3, cursor)
+
+	e.Home()
+	text, cursor = e.View(4)
+	require.Equal(t, "abcd", text)
+	require.Equal(t, 0, cursor)
+
+	// Wide characters take up two cells.
+	e = NewLineEditor("日本語")
+	text, cursor = e.View(10)
+	require.Equal(t, "日本語", text)
+	require.Equal(t, 6, cursor)
+	text, cursor = e.View(5)
+	require.Equal(t, "本語", text)
+	require.Equal(t, 4, cursor)
+}
+
+func TestFormUsesEditors(t *testing.T) {
+	f := NewForm([]Field{{Code: "a", Value: "x"}, {Code: "b"}})
+	f.FocusedEditor().Insert("y")
+	require.Equal(t, "xy", f.Value("a"))
+	f.SetValue("b", "z")
+	require.Equal(t, "z", f.Editor("b").Value())
+	require.Equal(t, map[string]string{"a": "xy", "b": "z"}, f.Values())
+	require.Equal(t, "xy", f.Fields()[0].Value)
+}
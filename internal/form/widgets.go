@@ -0,0 +1,134 @@
+package form
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	termbox "github.com/nsf/termbox-go"
+)
+
+func (f *Form) focused() (*Field, *LineEditor) {
+	if f.focusedField < 0 || f.focusedField >= len(f.fields) {
+		return nil, nil
+	}
+	return &f.fields[f.focusedField], f.editors[f.focusedField]
+}
+
+// HandleEvent passes the given key event to the widget of the focused field
+// and reports if it was handled. Choice fields cycle through their choices
+// with the arrow keys and space or jump to the first choice starting with
+// the typed character. Tags fields accept the current suggestion with the
+// right arrow and switch between suggestions with up and down. All other
+// keys are handled by the field's LineEditor.
+func (f *Form) HandleEvent(evt termbox.Event) bool {
+	fld, ed := f.focused()
+	if fld == nil {
+		return false
+	}
+	switch fld.Type {
+	case ChoiceField:
+		return f.handleChoice(fld, ed, evt)
+	case TagsField:
+		switch evt.Key {
+		case termbox.KeyArrowUp:
+			f.suggestion--
+			return true
+		case termbox.KeyArrowDown:
+			f.suggestion++
+			return true
+		case termbox.KeyArrowRight:
+			if s := f.Suggestion(); s != "" {
+				ed.Insert(s + " ")
+				f.suggestion = 0
+				return true
+			}
+		}
+	}
+	previous := ed.Value()
+	handled := ed.HandleEvent(evt)
+	if ed.Value() != previous {
+		f.suggestion = 0
+	}
+	return handled
+}
+
+func (f *Form) handleChoice(fld *Field, ed *LineEditor, evt termbox.Event) bool {
+	num := len(fld.Choices)
+	if num == 0 {
+		return false
+	}
+	current := -1
+	for idx, c := range fld.Choices {
+		if c == ed.Value() {
+			current = idx
+		}
+	}
+	switch evt.Key {
+	case termbox.KeyArrowLeft, termbox.KeyArrowUp:
+		if current <= 0 {
+			current = num
+		}
+		ed.SetValue(fld.Choices[current-1])
+	case termbox.KeyArrowRight, termbox.KeyArrowDown, termbox.KeySpace:
+		ed.SetValue(fld.Choices[(current+1)%num])
+	default:
+		if evt.Ch == 0 {
+			return false
+		}
+		for idx := 1; idx <= num; idx++ {
+			c := fld.Choices[(current+idx)%num]
+			if strings.HasPrefix(strings.ToLower(c), strings.ToLower(string(evt.Ch))) {
+				ed.SetValue(c)
+				break
+			}
+		}
+	}
+	return true
+}
+
+// Suggestions returns all choices of the focused tags field that complete
+// the word in front of the cursor. Tags that are already part of the value
+// are not suggested again.
+func (f *Form) Suggestions() []string {
+	fld, ed := f.focused()
+	if fld == nil || fld.Type != TagsField {
+		return nil
+	}
+	if ed.cursor < len(ed.value) && !unicode.IsSpace(ed.value[ed.cursor]) {
+		return nil
+	}
+	prefix := string(ed.value[ed.wordStart():ed.cursor])
+	if prefix == "" || strings.TrimSpace(prefix) != prefix {
+		return nil
+	}
+	used := make(map[string]struct{})
+	for _, t := range strings.Fields(ed.Value()) {
+		used[t] = struct{}{}
+	}
+	var result []string
+	for _, c := range fld.Choices {
+		if _, found := used[c]; found || !strings.HasPrefix(c, prefix) {
+			continue
+		}
+		result = append(result, c)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Suggestion returns the part of the selected suggestion that is still
+// missing in front of the cursor.
+func (f *Form) Suggestion() string {
+	suggestions := f.Suggestions()
+	if len(suggestions) == 0 {
+		return ""
+	}
+	_, ed := f.focused()
+	idx := f.suggestion % len(suggestions)
+	if idx < 0 {
+		idx += len(suggestions)
+	}
+	prefix := ed.value[ed.wordStart():ed.cursor]
+	return string([]rune(suggestions[idx])[len(prefix):])
+}
@@ -0,0 +1,133 @@
+// Package gitint connects clocked with git repositories. Task codes are
+// extracted from branch names like feature/ABC-123-something or from
+// commit messages.
+package gitint
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// hookMarker identifies hooks written by Install.
+const hookMarker = "# Installed by clocked"
+
+// RepoTagPrefix is prepended to the name of the repository to form the tag
+// used for attributing time to it.
+const RepoTagPrefix = "repo:"
+
+var codePattern = regexp.MustCompile(`[A-Z][A-Z0-9]+-[0-9]+`)
+
+// ExtractCode returns the first task code found in s.
+func ExtractCode(s string) string {
+	return codePattern.FindString(s)
+}
+
+// TitleFromBranch derives a title from whatever follows the code inside of
+// the branch name, e.g. "fix login" for feature/ABC-1-fix-login.
+func TitleFromBranch(branch, code string) string {
+	idx := strings.Index(branch, code)
+	if idx < 0 {
+		return ""
+	}
+	rest := strings.Trim(branch[idx+len(code):], "-_/")
+	return strings.TrimSpace(strings.NewReplacer("-", " ", "_", " ").Replace(rest))
+}
+
+// Repository is a git working copy.
+type Repository struct {
+	Path string
+}
+
+func (r Repository) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.Path
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %s", strings.Join(args, " "), strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Name returns the name of the folder the repository is checked out in.
+func (r Repository) Name() (string, error) {
+	top, err := r.git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(top), nil
+}
+
+// CurrentBranch returns the name of the checked out branch. For a detached
+// HEAD the name of the commit is returned instead.
+func (r Repository) CurrentBranch() (string, error) {
+	if branch, err := r.git("symbolic-ref", "--short", "-q", "HEAD"); err == nil {
+		return branch, nil
+	}
+	return r.git("rev-parse", "--abbrev-ref", "HEAD")
+}
+
+// LastCommitMessage returns the message of the HEAD commit.
+func (r Repository) LastCommitMessage() (string, error) {
+	return r.git("log", "-1", "--format=%B")
+}
+
+func (r Repository) hooksFolder() (string, error) {
+	path, err := r.git("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(r.Path, path)
+	}
+	return path, nil
+}
+
+var hooks = map[string]string{
+	// The third argument is 1 for branch checkouts and 0 for file
+	// checkouts.
+	"post-checkout": `[ "$3" = "1" ] || exit 0
+%s git-switch --hook < /dev/tty || true
+`,
+	"post-commit": `%s git-switch --hook --commit < /dev/tty || true
+`,
+}
+
+// Install adds post-checkout and post-commit hooks that call the given
+// clocked binary with the given store. Existing hooks that haven't been
+// written by clocked are left alone and reported as error.
+func (r Repository) Install(clockedPath, storePath string) ([]string, error) {
+	folder, err := r.hooksFolder()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		return nil, err
+	}
+	command := fmt.Sprintf("%s --store %s", shellQuote(clockedPath), shellQuote(storePath))
+	installed := make([]string, 0, len(hooks))
+	for _, name := range []string{"post-checkout", "post-commit"} {
+		path := filepath.Join(folder, name)
+		existing, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return installed, err
+		}
+		if err == nil && !strings.Contains(string(existing), hookMarker) {
+			return installed, fmt.Errorf("%s already exists. Please add a call to `clocked git-switch --hook` yourself", path)
+		}
+		script := fmt.Sprintf("#!/bin/sh\n%s\n%s", hookMarker, fmt.Sprintf(hooks[name], command))
+		if err := ioutil.WriteFile(path, []byte(script), 0755); err != nil {
+			return installed, err
+		}
+		installed = append(installed, path)
+	}
+	return installed, nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
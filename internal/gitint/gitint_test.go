@@ -0,0 +1,56 @@
+package gitint_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zerok/clocked/internal/gitint"
+)
+
+func TestExtractCode(t *testing.T) {
+	require.Equal(t, "ABC-123", gitint.ExtractCode("feature/ABC-123-something"))
+	require.Equal(t, "AB2-1", gitint.ExtractCode("AB2-1: Fix login"))
+	require.Equal(t, "", gitint.ExtractCode("feature/abc-123"))
+	require.Equal(t, "", gitint.ExtractCode("master"))
+}
+
+func TestTitleFromBranch(t *testing.T) {
+	require.Equal(t, "fix login form", gitint.TitleFromBranch("feature/ABC-1-fix-login_form", "ABC-1"))
+	require.Equal(t, "", gitint.TitleFromBranch("ABC-1", "ABC-1"))
+}
+
+func TestInstall(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, err := ioutil.TempDir("", "clocked-git")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, exec.Command("git", "init", dir).Run())
+	repo := gitint.Repository{Path: dir}
+
+	installed, err := repo.Install("/usr/bin/clocked", "/home/me/work store")
+	require.NoError(t, err)
+	require.Len(t, installed, 2)
+	data, err := ioutil.ReadFile(filepath.Join(dir, ".git", "hooks", "post-checkout"))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "'/usr/bin/clocked' --store '/home/me/work store' git-switch --hook")
+
+	// Installing again updates the hooks.
+	_, err = repo.Install("/usr/local/bin/clocked", "/home/me/.clocked")
+	require.NoError(t, err)
+
+	// Foreign hooks are not overwritten.
+	foreign := filepath.Join(dir, ".git", "hooks", "post-commit")
+	require.NoError(t, ioutil.WriteFile(foreign, []byte("#!/bin/sh\necho hi\n"), 0755))
+	_, err = repo.Install("/usr/bin/clocked", "/home/me/.clocked")
+	require.Error(t, err)
+
+	name, err := repo.Name()
+	require.NoError(t, err)
+	require.Equal(t, filepath.Base(dir), name)
+}
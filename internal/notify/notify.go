@@ -0,0 +1,91 @@
+// Package notify delivers desktop notifications.
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+const (
+	UrgencyLow      = 0
+	UrgencyNormal   = 1
+	UrgencyCritical = 2
+)
+
+// Notification is a single message shown to the user.
+type Notification struct {
+	Summary string
+	Body    string
+	Urgency int
+}
+
+// Notifier delivers notifications.
+type Notifier interface {
+	Notify(n Notification) error
+}
+
+// DBusNotifier sends notifications to the notification daemon of the
+// desktop using the freedesktop notifications specification. The D-Bus
+// calls are made with gdbus, which is part of every GLib installation.
+type DBusNotifier struct {
+	// Command is the gdbus binary. It defaults to gdbus.
+	Command string
+	AppName string
+}
+
+func NewDBusNotifier() *DBusNotifier {
+	return &DBusNotifier{
+		Command: "gdbus",
+		AppName: "clocked",
+	}
+}
+
+// Available checks if gdbus can be found.
+func (n *DBusNotifier) Available() bool {
+	_, err := exec.LookPath(n.Command)
+	return err == nil
+}
+
+// quote turns s into a GVariant string literal.
+func quote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	return `"` + s + `"`
+}
+
+func (n *DBusNotifier) Notify(msg Notification) error {
+	cmd := exec.Command(n.Command, "call", "--session",
+		"--dest", "org.freedesktop.Notifications",
+		"--object-path", "/org/freedesktop/Notifications",
+		"--method", "org.freedesktop.Notifications.Notify",
+		quote(n.AppName), "0", quote(""), quote(msg.Summary), quote(msg.Body),
+		"[]", fmt.Sprintf("{'urgency': <byte %d>}", msg.Urgency), "--", "-1")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to send notification: %s: %s", err.Error(), strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Fake records all notifications instead of delivering them.
+type Fake struct {
+	lock          sync.Mutex
+	notifications []Notification
+}
+
+func (f *Fake) Notify(n Notification) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.notifications = append(f.notifications, n)
+	return nil
+}
+
+// Notifications returns all notifications received so far.
+func (f *Fake) Notifications() []Notification {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	result := make([]Notification, len(f.notifications))
+	copy(result, f.notifications)
+	return result
+}
@@ -0,0 +1,59 @@
+package notify_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zerok/clocked/internal/notify"
+)
+
+// fakeGDBus writes every argument into its own line of the file args next
+// to it.
+const fakeGDBus = `#!/bin/sh
+for arg in "$@"; do
+	printf '%s\n' "$arg" >> "$(dirname "$0")/args"
+done
+if [ -e "$(dirname "$0")/fail" ]; then
+	echo "Error: no notification daemon" >&2
+	exit 1
+fi
+`
+
+func TestDBusNotifier(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clocked-notify")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	n := notify.NewDBusNotifier()
+	n.Command = filepath.Join(dir, "gdbus")
+	require.False(t, n.Available())
+	require.NoError(t, ioutil.WriteFile(n.Command, []byte(fakeGDBus), 0700))
+	require.True(t, n.Available())
+
+	require.NoError(t, n.Notify(notify.Notification{Summary: `Say "hi"`, Body: `C:\temp`, Urgency: notify.UrgencyCritical}))
+	data, err := ioutil.ReadFile(filepath.Join(dir, "args"))
+	require.NoError(t, err)
+	args := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Equal(t, []string{
+		"call", "--session",
+		"--dest", "org.freedesktop.Notifications",
+		"--object-path", "/org/freedesktop/Notifications",
+		"--method", "org.freedesktop.Notifications.Notify",
+		`"clocked"`, "0", `""`, `"Say \"hi\""`, `"C:\\temp"`,
+		"[]", "{'urgency': <byte 2>}", "--", "-1",
+	}, args, "the expiry has to follow -- so that it isn't parsed as option")
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "fail"), nil, 0600))
+	require.EqualError(t, n.Notify(notify.Notification{Summary: "x"}), "failed to send notification: exit status 1: Error: no notification daemon")
+}
+
+func TestFake(t *testing.T) {
+	f := &notify.Fake{}
+	require.Empty(t, f.Notifications())
+	require.NoError(t, f.Notify(notify.Notification{Summary: "a"}))
+	require.NoError(t, f.Notify(notify.Notification{Summary: "b"}))
+	require.Equal(t, []notify.Notification{{Summary: "a"}, {Summary: "b"}}, f.Notifications())
+}
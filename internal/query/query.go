@@ -0,0 +1,317 @@
+// Package query implements the filter syntax of the task list. A query
+// consists of whitespace-separated terms. Plain terms are matched fuzzily
+// against a task's label while terms like tag:offline, code:ABC-* or
+// active:today filter by specific properties. Every term can be negated by
+// prefixing it with a dash.
+package query
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/zerok/clocked"
+)
+
+type term struct {
+	key     string
+	value   string
+	negated bool
+}
+
+// Query is a parsed filter expression.
+type Query struct {
+	terms []term
+}
+
+// Context provides information that is not part of the task itself but is
+// required for evaluating some filters.
+type Context struct {
+	Now        time.Time
+	ActiveCode string
+}
+
+var filterKeys = map[string]struct{}{
+	"tag":    struct{}{},
+	"code":   struct{}{},
+	"active": struct{}{},
+}
+
+// Parse splits the given expression into its terms. Terms with an unknown
+// key are treated as text.
+func Parse(s string) Query {
+	q := Query{}
+	for _, f := range strings.Fields(s) {
+		t := term{}
+		if len(f) > 1 && f[0] == '-' {
+			t.negated = true
+			f = f[1:]
+		}
+		if idx := strings.Index(f, ":"); idx > 0 {
+			if _, ok := filterKeys[strings.ToLower(f[:idx])]; ok {
+				t.key = strings.ToLower(f[:idx])
+				f = f[idx+1:]
+			}
+		}
+		t.value = strings.ToLower(f)
+		q.terms = append(q.terms, t)
+	}
+	return q
+}
+
+// Empty returns true if the query doesn't restrict the result in any way.
+func (q Query) Empty() bool {
+	return len(q.terms) == 0
+}
+
+// Ranked reports if the query contains text terms and the matching tasks
+// are therefore ranked by how well they match.
+func (q Query) Ranked() bool {
+	for _, trm := range q.terms {
+		if trm.key == "" && !trm.negated {
+			return true
+		}
+	}
+	return false
+}
+
+// Match checks if the given task satisfies every term of the query. The
+// returned score indicates how well the text terms matched.
+func (q Query) Match(t clocked.Task, ctx Context) (int, bool) {
+	var total int
+	label := t.Label()
+	for _, trm := range q.terms {
+		var score int
+		var ok bool
+		switch trm.key {
+		case "tag":
+			ok = matchTag(t, trm.value)
+		case "code":
+			ok = matchGlob(trm.value, strings.ToLower(t.Code))
+		case "active":
+			ok = matchActive(t, trm.value, ctx)
+		default:
+			score, _, ok = FuzzyMatch(trm.value, label)
+		}
+		if ok == trm.negated {
+			return 0, false
+		}
+		total += score
+	}
+	return total, true
+}
+
+// Highlights returns the rune positions inside of label that are matched
+// by the text terms of the query.
+func (q Query) Highlights(label string) []int {
+	seen := make(map[int]struct{})
+	result := make([]int, 0, 5)
+	for _, trm := range q.terms {
+		if trm.key != "" || trm.negated {
+			continue
+		}
+		_, positions, ok := FuzzyMatch(trm.value, label)
+		if !ok {
+			continue
+		}
+		for _, p := range positions {
+			if _, found := seen[p]; !found {
+				seen[p] = struct{}{}
+				result = append(result, p)
+			}
+		}
+	}
+	sort.Ints(result)
+	return result
+}
+
+func matchTag(t clocked.Task, pattern string) bool {
+	for _, tag := range t.Tags {
+		if matchGlob(pattern, strings.ToLower(tag)) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchGlob(pattern, value string) bool {
+	ok, err := path.Match(pattern, value)
+	if err != nil {
+		return pattern == value
+	}
+	return ok
+}
+
+func matchActive(t clocked.Task, period string, ctx Context) bool {
+	var from time.Time
+	y, m, d := ctx.Now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, ctx.Now.Location())
+	switch period {
+	case "now":
+		return t.Code == ctx.ActiveCode
+	case "today":
+		from = today
+	case "week":
+		from = today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	case "month":
+		from = today.AddDate(0, 0, 1-d)
+	default:
+		return false
+	}
+	last := t.LastUsed()
+	return last != nil && !last.Before(from)
+}
+
+// FuzzyMatch checks if all runes of pattern appear in s in the same order.
+// Consecutive matches and matches at the start of a word result in a higher
+// score. The positions of the matched runes inside of s are returned as
+// well. Runes are compared case-insensitively one by one so that the
+// positions refer to the runes of s itself.
+func FuzzyMatch(pattern, s string) (int, []int, bool) {
+	p := lowerRunes(pattern)
+	if len(p) == 0 {
+		return 0, nil, true
+	}
+	original := []rune(s)
+	candidate := lowerRunes(s)
+	positions := make([]int, 0, len(p))
+	score := 0
+	pIdx := 0
+	prev := -2
+	for idx, c := range candidate {
+		if pIdx >= len(p) {
+			break
+		}
+		if c != p[pIdx] {
+			continue
+		}
+		score++
+		if prev == idx-1 {
+			score += 5
+		}
+		if idx == 0 || isSeparator(original[idx-1]) {
+			score += 3
+		}
+		positions = append(positions, idx)
+		prev = idx
+		pIdx++
+	}
+	if pIdx < len(p) {
+		return 0, nil, false
+	}
+	// Prefer a contiguous match if there is one as it is easier to read.
+	if start := indexRunes(candidate, p); start >= 0 {
+		contiguous := make([]int, len(p))
+		for i := range p {
+			contiguous[i] = start + i
+		}
+		return len(p)*6 + 3, contiguous, true
+	}
+	return score, positions, true
+}
+
+// lowerRunes returns the runes of s in lower case. Unlike strings.ToLower,
+// every rune is mapped to exactly one rune.
+func lowerRunes(s string) []rune {
+	result := []rune(s)
+	for idx, r := range result {
+		result[idx] = unicode.ToLower(r)
+	}
+	return result
+}
+
+// indexRunes returns the position of the first occurrence of sub inside of
+// s or -1.
+func indexRunes(s, sub []rune) int {
+	for start := 0; start+len(sub) <= len(s); start++ {
+		found := true
+		for i := range sub {
+			if s[start+i] != sub[i] {
+				found = false
+				break
+			}
+		}
+		if found {
+			return start
+		}
+	}
+	return -1
+}
+
+func isSeparator(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsPunct(r)
+}
+
+type rankedTask struct {
+	task     clocked.Task
+	score    int
+	lastUsed *time.Time
+}
+
+type byRank []rankedTask
+
+func (r byRank) Len() int {
+	return len(r)
+}
+
+func (r byRank) Swap(i, j int) {
+	r[i], r[j] = r[j], r[i]
+}
+
+func (r byRank) Less(i, j int) bool {
+	if r[i].score != r[j].score {
+		return r[i].score > r[j].score
+	}
+	a, b := r[i].lastUsed, r[j].lastUsed
+	if a != nil && b != nil && !a.Equal(*b) {
+		return a.After(*b)
+	}
+	if (a == nil) != (b == nil) {
+		return a != nil
+	}
+	return r[i].task.Code < r[j].task.Code
+}
+
+// recencyBonus ranks tasks that have been used recently higher.
+func recencyBonus(last *time.Time, now time.Time) int {
+	if last == nil {
+		return 0
+	}
+	age := now.Sub(*last)
+	switch {
+	case age < 24*time.Hour:
+		return 10
+	case age < 7*24*time.Hour:
+		return 5
+	case age < 31*24*time.Hour:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// Filter returns all tasks matching the query ranked by their score and
+// how recently they have been used.
+func Filter(tasks []clocked.Task, q Query, ctx Context) []clocked.Task {
+	ranked := make([]rankedTask, 0, len(tasks))
+	for _, t := range tasks {
+		score, ok := q.Match(t, ctx)
+		if !ok {
+			continue
+		}
+		last := t.LastUsed()
+		ranked = append(ranked, rankedTask{
+			task:     t,
+			score:    score + recencyBonus(last, ctx.Now),
+			lastUsed: last,
+		})
+	}
+	sort.Sort(byRank(ranked))
+	result := make([]clocked.Task, 0, len(ranked))
+	for _, r := range ranked {
+		result = append(result, r.task)
+	}
+	return result
+}
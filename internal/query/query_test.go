@@ -0,0 +1,59 @@
+package query_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/query"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	_, positions, ok := query.FuzzyMatch("abc", "ABC-123 Something")
+	require.True(t, ok)
+	require.Equal(t, []int{0, 1, 2}, positions)
+
+	_, positions, ok = query.FuzzyMatch("a1s", "ABC-123 Something")
+	require.True(t, ok)
+	require.Equal(t, []int{0, 4, 8}, positions)
+
+	_, _, ok = query.FuzzyMatch("sa", "ABC-123 Something")
+	require.False(t, ok, "the order of runes has to be respected")
+
+	// The lower case of İ consists of two runes.
+	_, positions, ok = query.FuzzyMatch("st", "İstanbul")
+	require.True(t, ok)
+	require.Equal(t, []int{1, 2}, positions, "positions refer to the original runes")
+}
+
+func TestFilter(t *testing.T) {
+	now := time.Date(2017, 10, 11, 12, 0, 0, 0, time.UTC)
+	used := clocked.Task{Code: "ABC-2", Title: "Review", Tags: []string{"done"}}
+	used.Start(now.Add(-time.Hour))
+	tasks := []clocked.Task{
+		{Code: "ABC-1", Title: "Review", Tags: []string{"offline"}},
+		used,
+		{Code: "XYZ-1", Title: "Meeting", Tags: []string{"offline"}},
+	}
+	ctx := query.Context{Now: now}
+	codes := func(tasks []clocked.Task) []string {
+		result := make([]string, 0, len(tasks))
+		for _, t := range tasks {
+			result = append(result, t.Code)
+		}
+		return result
+	}
+
+	require.Equal(t, []string{"ABC-2", "ABC-1"}, codes(query.Filter(tasks, query.Parse("rvw"), ctx)), "recently used tasks should be ranked first")
+	require.Equal(t, []string{"ABC-1", "XYZ-1"}, codes(query.Filter(tasks, query.Parse("tag:offline"), ctx)))
+	require.Equal(t, []string{"XYZ-1"}, codes(query.Filter(tasks, query.Parse("tag:offline -code:abc-*"), ctx)))
+	require.Equal(t, []string{"ABC-2"}, codes(query.Filter(tasks, query.Parse("active:today"), ctx)))
+	require.Equal(t, []string{"ABC-1"}, codes(query.Filter(tasks, query.Parse("review -tag:done"), ctx)))
+	require.Equal(t, []string{"XYZ-1"}, codes(query.Filter(tasks, query.Parse("-rvw"), ctx)), "negated text is matched fuzzily as well")
+}
+
+func TestHighlights(t *testing.T) {
+	q := query.Parse("tag:offline abc 12")
+	require.Equal(t, []int{0, 1, 2, 4, 5}, q.Highlights("ABC-123"))
+}
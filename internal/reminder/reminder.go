@@ -0,0 +1,197 @@
+// Package reminder checks the state of the database periodically and sends
+// notifications if the user most likely forgot to clock in or out, or if
+// the day is over.
+package reminder
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/zerok/clocked/internal/config"
+	"github.com/zerok/clocked/internal/database"
+	"github.com/zerok/clocked/internal/notify"
+	"gopkg.in/yaml.v2"
+)
+
+// StateFilename is the name of the file inside the store that remembers
+// which notifications have already been sent.
+const StateFilename = "reminders.yml"
+
+// State remembers which notifications have already been sent so that every
+// situation is only reported once.
+type State struct {
+	// LastIdle is the time the last idle reminder was sent.
+	LastIdle time.Time `yaml:"last_idle"`
+	// ReportedBooking is the key of the running booking that has already
+	// been reported as too long.
+	ReportedBooking string `yaml:"reported_booking,omitempty"`
+	// ReportedDay is the last day the end of day summary was sent for.
+	ReportedDay string `yaml:"reported_day,omitempty"`
+}
+
+func (s State) equal(o State) bool {
+	return s.LastIdle.Equal(o.LastIdle) && s.ReportedBooking == o.ReportedBooking && s.ReportedDay == o.ReportedDay
+}
+
+// LoadState reads the state from the given file. A missing file results in
+// an empty state.
+func LoadState(path string) (State, error) {
+	var s State
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return s, err
+	}
+	err = yaml.Unmarshal(data, &s)
+	return s, err
+}
+
+// Save writes the state to the given file.
+func (s State) Save(path string) error {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return database.WriteFile(path, data)
+}
+
+// Reminder sends the notifications that are due whenever it is checked.
+type Reminder struct {
+	cfg      *config.Config
+	notifier notify.Notifier
+	state    State
+	// StatePath is the file the state is kept in between checks. Without
+	// it, the state is only kept in memory, e.g. for a single process
+	// checking periodically.
+	StatePath string
+}
+
+func New(cfg *config.Config, notifier notify.Notifier) *Reminder {
+	return &Reminder{
+		cfg:      cfg,
+		notifier: notifier,
+	}
+}
+
+// Check sends all notifications that are due at the given time.
+func (r *Reminder) Check(db database.Database, now time.Time) error {
+	if r.StatePath != "" {
+		state, err := LoadState(r.StatePath)
+		if err != nil {
+			return err
+		}
+		r.state = state
+	}
+	before := r.state
+	err := r.check(db, now)
+	if r.StatePath != "" && !r.state.equal(before) {
+		if saveErr := r.state.Save(r.StatePath); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}
+	return err
+}
+
+func (r *Reminder) check(db database.Database, now time.Time) error {
+	if err := r.checkIdle(db, now); err != nil {
+		return err
+	}
+	if err := r.checkLongBooking(db, now); err != nil {
+		return err
+	}
+	return r.checkEndOfDay(db, now)
+}
+
+func (r *Reminder) checkIdle(db database.Database, now time.Time) error {
+	rc := r.cfg.Reminders
+	if rc.IdleAfter <= 0 || db.ActiveCode() != "" || !r.cfg.IsWorkingDay(now) {
+		return nil
+	}
+	start, end, ok, err := r.cfg.WorkingHours(now)
+	if err != nil || !ok {
+		return err
+	}
+	if now.Before(start) || !now.Before(end) {
+		return nil
+	}
+	idleSince := start
+	for _, b := range db.GenerateDailySummary(now).Bookings {
+		if b.Stop != nil && b.Stop.After(idleSince) {
+			idleSince = *b.Stop
+		}
+	}
+	if r.state.LastIdle.After(idleSince) {
+		idleSince = r.state.LastIdle
+	}
+	if now.Sub(idleSince) < rc.IdleAfter {
+		return nil
+	}
+	r.state.LastIdle = now
+	return r.notifier.Notify(notify.Notification{
+		Summary: "Nothing clocked in",
+		Body:    fmt.Sprintf("You haven't been clocked into a task for %s.", database.FormatHours(now.Sub(idleSince))),
+		Urgency: notify.UrgencyNormal,
+	})
+}
+
+func (r *Reminder) checkLongBooking(db database.Database, now time.Time) error {
+	rc := r.cfg.Reminders
+	if rc.LongBooking <= 0 {
+		return nil
+	}
+	task, ok := db.ActiveTask()
+	if !ok || len(task.Bookings) == 0 {
+		return nil
+	}
+	b := task.Bookings[len(task.Bookings)-1]
+	start := b.StartTime()
+	if b.Stop != "" || start == nil || now.Sub(*start) < rc.LongBooking {
+		return nil
+	}
+	key := b.Key(task.Code)
+	if key == r.state.ReportedBooking {
+		return nil
+	}
+	r.state.ReportedBooking = key
+	return r.notifier.Notify(notify.Notification{
+		Summary: fmt.Sprintf("%s is running for %s", task.Code, database.FormatHours(now.Sub(*start))),
+		Body:    "Did you forget to clock out?",
+		Urgency: notify.UrgencyCritical,
+	})
+}
+
+func (r *Reminder) checkEndOfDay(db database.Database, now time.Time) error {
+	rc := r.cfg.Reminders
+	day := now.Format(database.DayFormat)
+	if rc.EndOfDay == "" || r.state.ReportedDay == day || !r.cfg.IsWorkingDay(now) {
+		return nil
+	}
+	at, err := config.AtClock(now, rc.EndOfDay)
+	if err != nil {
+		return err
+	}
+	if now.Before(at) {
+		return nil
+	}
+	r.state.ReportedDay = day
+	summary := db.GenerateDailySummary(now)
+	body := fmt.Sprintf("You have booked %s on %d tasks today.", database.FormatHours(summary.Total), len(summary.Totals))
+	if target := r.cfg.TargetFor(now); target > 0 {
+		body = fmt.Sprintf("You have booked %s of %s on %d tasks today.", database.FormatHours(summary.Total), database.FormatHours(target), len(summary.Totals))
+	}
+	if code := db.ActiveCode(); code != "" {
+		body += fmt.Sprintf(" %s is still running.", code)
+	}
+	if r.cfg.JIRAURL != "" {
+		body += " Don't forget to sync your bookings to JIRA."
+	}
+	return r.notifier.Notify(notify.Notification{
+		Summary: "End of day",
+		Body:    body,
+		Urgency: notify.UrgencyNormal,
+	})
+}
@@ -0,0 +1,110 @@
+package reminder_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/config"
+	"github.com/zerok/clocked/internal/database"
+	"github.com/zerok/clocked/internal/notify"
+	"github.com/zerok/clocked/internal/reminder"
+)
+
+// 2017-10-09 is a Monday.
+func at(hour, minute int) time.Time {
+	return time.Date(2017, 10, 9, hour, minute, 0, 0, time.Local)
+}
+
+func newConfig() *config.Config {
+	return &config.Config{
+		JIRAURL: "https://jira.example.com",
+		Reminders: config.Reminders{
+			IdleAfter:   30 * time.Minute,
+			LongBooking: 2 * time.Hour,
+			WorkStart:   "09:00",
+			WorkEnd:     "17:00",
+			EndOfDay:    "17:30",
+		},
+	}
+}
+
+func TestIdleReminder(t *testing.T) {
+	db := database.NewInMemory()
+	require.NoError(t, db.AddTask(clocked.Task{Code: "A", Bookings: []clocked.Booking{
+		{Start: at(9, 0).Format(time.RFC3339), Stop: at(10, 0).Format(time.RFC3339)},
+	}}))
+	fake := &notify.Fake{}
+	r := reminder.New(newConfig(), fake)
+
+	require.NoError(t, r.Check(db, at(8, 0)))
+	require.NoError(t, r.Check(db, at(10, 20)))
+	require.Len(t, fake.Notifications(), 0, "no reminders outside of working hours or before the idle time passed")
+
+	require.NoError(t, r.Check(db, at(10, 30)))
+	require.Len(t, fake.Notifications(), 1)
+	require.Equal(t, "Nothing clocked in", fake.Notifications()[0].Summary)
+
+	require.NoError(t, r.Check(db, at(10, 45)))
+	require.Len(t, fake.Notifications(), 1, "the reminder should only be repeated after another idle period")
+	require.NoError(t, r.Check(db, at(11, 0)))
+	require.Len(t, fake.Notifications(), 2)
+
+	// Weekends are no working days.
+	fake = &notify.Fake{}
+	r = reminder.New(newConfig(), fake)
+	require.NoError(t, r.Check(db, at(11, 0).AddDate(0, 0, -1)))
+	require.Len(t, fake.Notifications(), 0)
+}
+
+func TestLongBookingAndEndOfDay(t *testing.T) {
+	db := database.NewInMemory()
+	require.NoError(t, db.AddTask(clocked.Task{Code: "A"}))
+	require.NoError(t, db.ClockInto("A"))
+	require.NoError(t, db.UpdateBooking("A", 0, clocked.Booking{Start: at(14, 0).Format(time.RFC3339)}))
+	fake := &notify.Fake{}
+	r := reminder.New(newConfig(), fake)
+
+	require.NoError(t, r.Check(db, at(15, 0)))
+	require.Len(t, fake.Notifications(), 0)
+	require.NoError(t, r.Check(db, at(16, 30)))
+	require.Len(t, fake.Notifications(), 1)
+	require.Equal(t, "A is running for 2h30m", fake.Notifications()[0].Summary)
+	require.NoError(t, r.Check(db, at(17, 0)))
+	require.Len(t, fake.Notifications(), 1, "every booking should only be reported once")
+
+	require.NoError(t, r.Check(db, at(17, 30)))
+	require.Len(t, fake.Notifications(), 2)
+	n := fake.Notifications()[1]
+	require.Equal(t, "End of day", n.Summary)
+	require.Contains(t, n.Body, "A is still running")
+	require.Contains(t, n.Body, "JIRA")
+	require.NoError(t, r.Check(db, at(18, 0)))
+	require.Len(t, fake.Notifications(), 2)
+}
+
+func TestStatePath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clocked-reminder")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db := database.NewInMemory()
+	fake := &notify.Fake{}
+	check := func(now time.Time) {
+		r := reminder.New(newConfig(), fake)
+		r.StatePath = filepath.Join(dir, reminder.StateFilename)
+		require.NoError(t, r.Check(db, now))
+	}
+
+	check(at(10, 0))
+	require.Len(t, fake.Notifications(), 1)
+	check(at(10, 15))
+	require.Len(t, fake.Notifications(), 1, "the idle reminder is remembered")
+	check(at(17, 30))
+	check(at(18, 0))
+	require.Len(t, fake.Notifications(), 2, "the end of day summary is only sent once")
+	require.Equal(t, "End of day", fake.Notifications()[1].Summary)
+}
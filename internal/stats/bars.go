@@ -0,0 +1,58 @@
+package stats
+
+import "time"
+
+// Block characters filling a cell by eighths, either from the left or from
+// the bottom.
+var (
+	horizontalBlocks = []rune(" ▏▎▍▌▋▊▉█")
+	verticalBlocks   = []rune(" ▁▂▃▄▅▆▇█")
+)
+
+// eighths scales value relative to max onto the given number of cells and
+// returns the number of eighths of a cell to fill.
+func eighths(value, max time.Duration, cells int) int {
+	if value <= 0 || max <= 0 || cells <= 0 {
+		return 0
+	}
+	if value > max {
+		value = max
+	}
+	n := int(int64(value) * int64(cells*8) / int64(max))
+	if n == 0 {
+		// Values above zero are always visible.
+		n = 1
+	}
+	return n
+}
+
+// HorizontalBar renders value relative to max as a bar of the given width.
+func HorizontalBar(value, max time.Duration, width int) string {
+	n := eighths(value, max, width)
+	result := make([]rune, 0, width)
+	for ; n >= 8; n -= 8 {
+		result = append(result, horizontalBlocks[8])
+	}
+	if n > 0 {
+		result = append(result, horizontalBlocks[n])
+	}
+	return string(result)
+}
+
+// VerticalBar renders value relative to max as a bar of the given height.
+// The cells are returned from top to bottom.
+func VerticalBar(value, max time.Duration, height int) []rune {
+	n := eighths(value, max, height)
+	result := make([]rune, height)
+	for row := 0; row < height; row++ {
+		fill := n - (height-1-row)*8
+		if fill < 0 {
+			fill = 0
+		}
+		if fill > 8 {
+			fill = 8
+		}
+		result[row] = verticalBlocks[fill]
+	}
+	return result
+}
@@ -0,0 +1,193 @@
+// Package stats calculates statistics about the time booked within a
+// period like the time per day, per tag and per task, the usual start and
+// end of the working day and the longest focus sessions.
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/zerok/clocked/internal/database"
+)
+
+// Untagged is used as tag name for the time booked on tasks without tags.
+const Untagged = "(untagged)"
+
+// SessionGap is the longest break between two bookings of the same task that
+// still counts as a single focus session.
+const SessionGap = 5 * time.Minute
+
+// Day is the time booked on a single day.
+type Day struct {
+	Date  time.Time
+	Total time.Duration
+}
+
+// Share is the time booked on a tag or task.
+type Share struct {
+	Name  string
+	Total time.Duration
+}
+
+// Session is a period of uninterrupted work on a single task.
+type Session struct {
+	Code  string
+	Start time.Time
+	Stop  time.Time
+}
+
+// Duration returns the length of the session.
+func (s Session) Duration() time.Duration {
+	return s.Stop.Sub(s.Start)
+}
+
+// Stats are the statistics of a period.
+type Stats struct {
+	From  time.Time
+	To    time.Time
+	Total time.Duration
+	// Days contains every day of the period, including those without any
+	// bookings.
+	Days  []Day
+	Tags  []Share
+	Tasks []Share
+	// Repositories contains the time booked in git repositories.
+	Repositories []Share
+	// AverageStart and AverageEnd are the average times of the day (as
+	// offset from midnight) of the first and the last booking of all days
+	// with bookings.
+	AverageStart time.Duration
+	AverageEnd   time.Duration
+	// WorkDays is the number of days with bookings.
+	WorkDays int
+	// Sessions are sorted by their duration, the longest first.
+	Sessions []Session
+}
+
+// MaxDay returns the longest total of all days.
+func (s *Stats) MaxDay() time.Duration {
+	var max time.Duration
+	for _, d := range s.Days {
+		if d.Total > max {
+			max = d.Total
+		}
+	}
+	return max
+}
+
+// Calculate generates the summary of [from, to) and calculates its
+// statistics. Only finished bookings are taken into account.
+func Calculate(db database.Database, from, to time.Time) Stats {
+	summary := db.GenerateSummary(from, to)
+	s := Stats{From: from, To: to, Total: summary.Total}
+	for day := database.StartOfDay(from); day.Before(to); day = day.AddDate(0, 0, 1) {
+		s.Days = append(s.Days, Day{Date: day, Total: summary.DailyTotals[day.Format(database.DayFormat)]})
+	}
+
+	tags := make(map[string]time.Duration)
+	for code, total := range summary.Totals {
+		s.Tasks = append(s.Tasks, Share{Name: code, Total: total})
+		t, found := db.TaskByCode(code)
+		if !found || len(t.Tags) == 0 {
+			tags[Untagged] += total
+			continue
+		}
+		seen := make(map[string]struct{})
+		for _, tag := range t.Tags {
+			if _, found := seen[tag]; found {
+				continue
+			}
+			seen[tag] = struct{}{}
+			tags[tag] += total
+		}
+	}
+	for tag, total := range tags {
+		s.Tags = append(s.Tags, Share{Name: tag, Total: total})
+	}
+	for repo, total := range summary.RepositoryTotals {
+		s.Repositories = append(s.Repositories, Share{Name: repo, Total: total})
+	}
+	sort.Sort(byTotal(s.Tasks))
+	sort.Sort(byTotal(s.Tags))
+	sort.Sort(byTotal(s.Repositories))
+
+	var bookings []database.TaskBooking
+	for _, b := range summary.Bookings {
+		if b.Start != nil && b.Stop != nil {
+			bookings = append(bookings, b)
+		}
+	}
+	s.AverageStart, s.AverageEnd, s.WorkDays = averageDay(bookings, from.Location())
+	s.Sessions = sessions(bookings, from.Location())
+	return s
+}
+
+// averageDay calculates the average start and end of all days with
+// bookings. bookings have to be sorted by their start.
+func averageDay(bookings []database.TaskBooking, loc *time.Location) (time.Duration, time.Duration, int) {
+	type span struct {
+		start, end time.Duration
+	}
+	var order []string
+	days := make(map[string]*span)
+	for _, b := range bookings {
+		start := b.Start.In(loc)
+		day := database.StartOfDay(start)
+		key := day.Format(database.DayFormat)
+		end := b.Stop.In(loc).Sub(day)
+		sp, found := days[key]
+		if !found {
+			days[key] = &span{start: start.Sub(day), end: end}
+			order = append(order, key)
+			continue
+		}
+		if end > sp.end {
+			sp.end = end
+		}
+	}
+	if len(order) == 0 {
+		return 0, 0, 0
+	}
+	var start, end time.Duration
+	for _, key := range order {
+		start += days[key].start
+		end += days[key].end
+	}
+	n := time.Duration(len(order))
+	return start / n, end / n, len(order)
+}
+
+// sessions joins bookings of the same task that are at most SessionGap apart
+// and returns the results sorted by their duration. bookings have to be
+// sorted by their start.
+func sessions(bookings []database.TaskBooking, loc *time.Location) []Session {
+	var result []Session
+	for _, b := range bookings {
+		if n := len(result); n > 0 && result[n-1].Code == b.Code && b.Start.Sub(result[n-1].Stop) <= SessionGap {
+			if b.Stop.After(result[n-1].Stop) {
+				result[n-1].Stop = b.Stop.In(loc)
+			}
+			continue
+		}
+		result = append(result, Session{Code: b.Code, Start: b.Start.In(loc), Stop: b.Stop.In(loc)})
+	}
+	sort.Stable(byDuration(result))
+	return result
+}
+
+type byTotal []Share
+
+func (s byTotal) Len() int      { return len(s) }
+func (s byTotal) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byTotal) Less(i, j int) bool {
+	if s[i].Total == s[j].Total {
+		return s[i].Name < s[j].Name
+	}
+	return s[i].Total > s[j].Total
+}
+
+type byDuration []Session
+
+func (s byDuration) Len() int           { return len(s) }
+func (s byDuration) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byDuration) Less(i, j int) bool { return s[i].Duration() > s[j].Duration() }
@@ -0,0 +1,72 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/database"
+)
+
+func at(day, h, m int) time.Time {
+	return time.Date(2018, 3, day, h, m, 0, 0, time.Local)
+}
+
+func booking(from, to time.Time) clocked.Booking {
+	b := clocked.Booking{}
+	b.SetStart(from)
+	b.SetStop(to)
+	return b
+}
+
+func repoBooking(from, to time.Time, repo string) clocked.Booking {
+	b := booking(from, to)
+	b.Repository = repo
+	return b
+}
+
+func TestCalculate(t *testing.T) {
+	db := database.NewInMemory()
+	db.AddTask(clocked.Task{Code: "a", Tags: []string{"review", "review"}, Bookings: []clocked.Booking{
+		booking(at(1, 8, 0), at(1, 10, 0)),
+		repoBooking(at(1, 10, 3), at(1, 11, 0), "web"),
+		repoBooking(at(2, 10, 0), at(2, 12, 0), "api"),
+	}})
+	db.AddTask(clocked.Task{Code: "b", Bookings: []clocked.Booking{
+		repoBooking(at(1, 13, 0), at(1, 18, 0), "web"),
+	}})
+	b := clocked.Booking{}
+	b.SetStart(at(2, 13, 0))
+	db.AddTask(clocked.Task{Code: "c", Tags: []string{"review"}, Bookings: []clocked.Booking{b}})
+
+	s := Calculate(db, at(1, 0, 0), at(4, 0, 0))
+	require.Len(t, s.Days, 3)
+	require.Equal(t, 7*time.Hour+57*time.Minute, s.Days[0].Total)
+	require.Equal(t, 2*time.Hour, s.Days[1].Total)
+	require.Equal(t, time.Duration(0), s.Days[2].Total)
+	require.Equal(t, 7*time.Hour+57*time.Minute, s.MaxDay())
+
+	require.Equal(t, []Share{{"b", 5 * time.Hour}, {"a", 4*time.Hour + 57*time.Minute}}, s.Tasks)
+	require.Equal(t, []Share{{Untagged, 5 * time.Hour}, {"review", 4*time.Hour + 57*time.Minute}}, s.Tags)
+	require.Equal(t, []Share{{"web", 5*time.Hour + 57*time.Minute}, {"api", 2 * time.Hour}}, s.Repositories, "the time of a task is split by repository")
+
+	require.Equal(t, 2, s.WorkDays)
+	require.Equal(t, 9*time.Hour, s.AverageStart)
+	require.Equal(t, 15*time.Hour, s.AverageEnd)
+
+	require.Len(t, s.Sessions, 3)
+	require.Equal(t, Session{Code: "b", Start: at(1, 13, 0), Stop: at(1, 18, 0)}, s.Sessions[0])
+	require.Equal(t, 3*time.Hour, s.Sessions[1].Duration(), "bookings with short breaks are joined")
+}
+
+func TestBars(t *testing.T) {
+	require.Equal(t, "", HorizontalBar(0, time.Hour, 4))
+	require.Equal(t, "████", HorizontalBar(time.Hour, time.Hour, 4))
+	require.Equal(t, "██▌", HorizontalBar(5*time.Minute, 8*time.Minute, 4))
+	require.Equal(t, "▏", HorizontalBar(time.Second, time.Hour, 4), "values above zero are visible")
+	require.Equal(t, "████", HorizontalBar(2*time.Hour, time.Hour, 4))
+
+	require.Equal(t, []rune("  ▄█"), VerticalBar(3*time.Hour, 8*time.Hour, 4))
+	require.Equal(t, []rune("    "), VerticalBar(0, 8*time.Hour, 4))
+}
@@ -0,0 +1,202 @@
+// Package status renders the active task for status bars like tmux, waybar
+// or i3blocks. As status bars poll very often, Read only loads what is
+// necessary for that from a folder-based store.
+package status
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/database"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	OutputPlain    = "plain"
+	OutputWaybar   = "waybar"
+	OutputI3Blocks = "i3blocks"
+	OutputTmux     = "tmux"
+)
+
+// DefaultFormat is used if no other template is specified.
+const DefaultFormat = "{{if .Active}}{{.Code}} {{hours .Elapsed}}{{else}}idle{{end}} ({{hours .Today}})"
+
+// Status is what is available inside of a format template.
+type Status struct {
+	Code  string
+	Title string
+	Tags  []string
+	// Since is the start of the running booking.
+	Since *time.Time
+	// Elapsed is the duration of the running booking.
+	Elapsed time.Duration
+	// Today is the time booked today including the running booking.
+	Today time.Duration
+}
+
+func (s Status) Active() bool {
+	return s.Code != ""
+}
+
+// Compute builds the status at the given time.
+func Compute(tasks []clocked.Task, activeCode string, now time.Time) Status {
+	s := Status{}
+	dayStart := database.StartOfDay(now)
+	for _, t := range tasks {
+		for idx := range t.Bookings {
+			b := &t.Bookings[idx]
+			start := b.StartTime()
+			if start == nil {
+				continue
+			}
+			stop := b.StopTime()
+			if stop == nil {
+				if t.Code != activeCode {
+					continue
+				}
+				stop = &now
+				if s.Since == nil || start.After(*s.Since) {
+					s.Since = start
+				}
+			}
+			from := *start
+			if from.Before(dayStart) {
+				from = dayStart
+			}
+			if stop.After(from) {
+				s.Today += stop.Sub(from)
+			}
+		}
+		if t.Code == activeCode {
+			s.Code = t.Code
+			s.Title = t.Title
+			s.Tags = t.Tags
+		}
+	}
+	if s.Since != nil {
+		s.Elapsed = now.Sub(*s.Since)
+	}
+	return s
+}
+
+// FromDatabase computes the status using an already loaded database.
+func FromDatabase(db database.Database, now time.Time) Status {
+	tasks, _ := db.AllTasks()
+	return Compute(tasks, db.ActiveCode(), now)
+}
+
+// Read computes the status from the folder-based store at the given path.
+// Only the active task and tasks changed today are loaded as all other
+// tasks cannot contain bookings of today.
+func Read(path string, now time.Time) (Status, error) {
+	activeCode := ""
+	data, err := ioutil.ReadFile(filepath.Join(path, database.ActiveCodeFilename))
+	if err != nil && !os.IsNotExist(err) {
+		return Status{}, err
+	}
+	activeCode = strings.TrimSpace(string(data))
+	files, err := filepath.Glob(filepath.Join(path, database.TasksFolder, "*.yml"))
+	if err != nil {
+		return Status{}, err
+	}
+	dayStart := database.StartOfDay(now)
+	tasks := make([]clocked.Task, 0, 5)
+	for _, f := range files {
+		code := strings.TrimSuffix(filepath.Base(f), ".yml")
+		if code != activeCode {
+			info, err := os.Stat(f)
+			if err != nil || info.ModTime().Before(dayStart) {
+				continue
+			}
+		}
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return Status{}, err
+		}
+		var t clocked.Task
+		if err := yaml.Unmarshal(data, &t); err != nil {
+			return Status{}, fmt.Errorf("failed to decode %s: %s", f, err.Error())
+		}
+		t.Code = code
+		tasks = append(tasks, t)
+	}
+	return Compute(tasks, activeCode, now), nil
+}
+
+var funcs = template.FuncMap{
+	"hours": database.FormatHours,
+	"join":  strings.Join,
+}
+
+type waybarOutput struct {
+	Text    string `json:"text"`
+	Alt     string `json:"alt"`
+	Tooltip string `json:"tooltip"`
+	Class   string `json:"class"`
+}
+
+type i3blocksOutput struct {
+	FullText  string `json:"full_text"`
+	ShortText string `json:"short_text"`
+	Color     string `json:"color,omitempty"`
+}
+
+// Render formats the status using the given template and output mode.
+func Render(s Status, format string, output string) (string, error) {
+	if format == "" {
+		format = DefaultFormat
+	}
+	tmpl, err := template.New("status").Funcs(funcs).Parse(format)
+	if err != nil {
+		return "", fmt.Errorf("invalid format: %s", err.Error())
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, s); err != nil {
+		return "", err
+	}
+	text := buf.String()
+	state := "idle"
+	if s.Active() {
+		state = "active"
+	}
+	var result interface{}
+	switch output {
+	case "", OutputPlain:
+		return text, nil
+	case OutputTmux:
+		text = strings.Replace(text, "#", "##", -1)
+		if s.Active() {
+			return "#[fg=green]" + text + "#[default]", nil
+		}
+		return text, nil
+	case OutputWaybar:
+		tooltip := fmt.Sprintf("Today: %s", database.FormatHours(s.Today))
+		if s.Active() {
+			tooltip = fmt.Sprintf("%s %s\n%s", s.Code, s.Title, tooltip)
+		}
+		result = waybarOutput{Text: text, Alt: state, Tooltip: tooltip, Class: state}
+	case OutputI3Blocks:
+		short := s.Code
+		color := "#00FF00"
+		if !s.Active() {
+			short = state
+			color = ""
+		}
+		result = i3blocksOutput{FullText: text, ShortText: short, Color: color}
+	default:
+		return "", fmt.Errorf("unsupported output %s", output)
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,87 @@
+package status_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/status"
+)
+
+func booking(start, stop string) clocked.Booking {
+	return clocked.Booking{Start: start, Stop: stop}
+}
+
+func TestCompute(t *testing.T) {
+	now := time.Date(2017, 10, 10, 12, 0, 0, 0, time.UTC)
+	tasks := []clocked.Task{
+		{Code: "A", Title: "Task A", Bookings: []clocked.Booking{
+			booking("2017-10-09T23:00:00Z", "2017-10-10T01:00:00Z"),
+			booking("2017-10-10T11:00:00Z", ""),
+		}},
+		{Code: "B", Bookings: []clocked.Booking{
+			booking("2017-10-10T08:00:00Z", "2017-10-10T09:30:00Z"),
+			// Open bookings of inactive tasks are ignored.
+			booking("2017-10-10T10:00:00Z", ""),
+		}},
+	}
+	s := status.Compute(tasks, "A", now)
+	require.True(t, s.Active())
+	require.Equal(t, "Task A", s.Title)
+	require.Equal(t, time.Hour, s.Elapsed)
+	require.Equal(t, 3*time.Hour+30*time.Minute, s.Today)
+
+	s = status.Compute(tasks, "", now)
+	require.False(t, s.Active())
+	require.Equal(t, 2*time.Hour+30*time.Minute, s.Today)
+}
+
+func TestRender(t *testing.T) {
+	s := status.Status{Code: "A#1", Title: "Task", Elapsed: 90 * time.Minute, Today: 3 * time.Hour}
+	out, err := status.Render(s, "", status.OutputPlain)
+	require.NoError(t, err)
+	require.Equal(t, "A#1 1h30m (3h00m)", out)
+
+	out, err = status.Render(s, "", status.OutputTmux)
+	require.NoError(t, err)
+	require.Equal(t, "#[fg=green]A##1 1h30m (3h00m)#[default]", out)
+
+	out, err = status.Render(s, "{{.Title}}", status.OutputWaybar)
+	require.NoError(t, err)
+	require.Equal(t, `{"text":"Task","alt":"active","tooltip":"A#1 Task\nToday: 3h00m","class":"active"}`, out)
+
+	out, err = status.Render(status.Status{}, "", status.OutputI3Blocks)
+	require.NoError(t, err)
+	require.Equal(t, `{"full_text":"idle (0h00m)","short_text":"idle"}`, out)
+
+	_, err = status.Render(s, "{{.Unknown", status.OutputPlain)
+	require.Error(t, err)
+	_, err = status.Render(s, "", "unknown")
+	require.Error(t, err)
+}
+
+func TestRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clocked-status")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "tasks"), 0700))
+	now := time.Now()
+	start := now.Add(-time.Minute).Format(time.RFC3339)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "activeCode"), []byte("A\n"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "tasks", "A.yml"), []byte("title: Task A\nbookings:\n- start: "+start+"\n  stop: \"\"\n"), 0600))
+	old := filepath.Join(dir, "tasks", "B.yml")
+	require.NoError(t, ioutil.WriteFile(old, []byte("title: [broken"), 0600))
+	// Files that haven't been changed today are not even parsed.
+	yesterday := now.AddDate(0, 0, -1)
+	require.NoError(t, os.Chtimes(old, yesterday, yesterday))
+
+	s, err := status.Read(dir, now)
+	require.NoError(t, err)
+	require.Equal(t, "A", s.Code)
+	require.Equal(t, "Task A", s.Title)
+	require.NotNil(t, s.Since)
+}
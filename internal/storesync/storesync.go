@@ -0,0 +1,366 @@
+// Package storesync merges two clocked stores, e.g. the one on a laptop and
+// the one on a desktop machine. Tasks are matched by their ID (or by their
+// code if they don't have one yet), bookings by their ID. If both stores
+// changed the same task or booking, the change with the newer timestamp
+// wins. Deleted tasks are tracked using tombstones so that deletions reach
+// the other store as well.
+package storesync
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/satori/go.uuid"
+	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/database"
+)
+
+// State is everything that is synchronized between two stores.
+type State struct {
+	Tasks []clocked.Task
+	// Deleted contains the tasks that have been deleted.
+	Deleted database.Tombstones
+	// ActiveCode is the code of the task with the running booking. It is
+	// derived from the bookings when stores are merged.
+	ActiveCode string
+}
+
+// Report summarizes what changed during a merge.
+type Report struct {
+	TasksAdded      int
+	TasksUpdated    int
+	TasksRemoved    int
+	BookingsAdded   int
+	BookingsUpdated int
+	BookingsClosed  int
+	ActiveCode      string
+}
+
+func (r Report) String() string {
+	return fmt.Sprintf("%d tasks added, %d tasks updated, %d tasks removed, %d bookings added, %d bookings updated, %d bookings closed", r.TasksAdded, r.TasksUpdated, r.TasksRemoved, r.BookingsAdded, r.BookingsUpdated, r.BookingsClosed)
+}
+
+// Merge combines the local and the remote state. The report describes the
+// changes relative to the local state.
+func Merge(local, remote State) (State, Report) {
+	var report Report
+	result := State{Deleted: mergeTombstones(local.Deleted, remote.Deleted)}
+	matches := matchTasks(local.Tasks, remote.Tasks)
+	used := make(map[string]struct{})
+	for lIdx, lt := range local.Tasks {
+		merged := lt.Copy()
+		rIdx, found := matches[lIdx]
+		if found {
+			rt := remote.Tasks[rIdx]
+			if rt.UpdatedTime().After(lt.UpdatedTime()) {
+				if rt.Code != lt.Code || rt.Title != lt.Title || !sameTags(rt.Tags, lt.Tags) {
+					report.TasksUpdated++
+				}
+				merged.Code = rt.Code
+				merged.Title = rt.Title
+				merged.Tags = rt.Tags
+				merged.Updated = rt.Updated
+			}
+			if merged.ID == "" {
+				merged.ID = rt.ID
+			}
+			merged.Bookings = mergeBookings(lt.Bookings, rt.Bookings, &report)
+		}
+		if deleted(merged, result.Deleted) {
+			report.TasksRemoved++
+			continue
+		}
+		result.Tasks = append(result.Tasks, merged)
+	}
+	remoteMatched := make(map[int]struct{})
+	for _, rIdx := range matches {
+		remoteMatched[rIdx] = struct{}{}
+	}
+	for rIdx, rt := range remote.Tasks {
+		if _, found := remoteMatched[rIdx]; found || deleted(rt, result.Deleted) {
+			continue
+		}
+		report.TasksAdded++
+		report.BookingsAdded += len(rt.Bookings)
+		result.Tasks = append(result.Tasks, rt.Copy())
+	}
+
+	for idx := range result.Tasks {
+		t := &result.Tasks[idx]
+		// Tasks created before IDs were introduced get one now so that
+		// they can be renamed without being duplicated afterwards.
+		if t.ID == "" {
+			t.ID = uuid.NewV4().String()
+		}
+		t.Code = uniqueCode(t.Code, used)
+		used[t.Code] = struct{}{}
+		// A task that exists is no longer deleted, e.g. because it has
+		// been changed after it was deleted on the other machine.
+		delete(result.Deleted, t.Key())
+		delete(result.Deleted, t.Code)
+	}
+
+	resolveActiveCode(&result, &report)
+	report.ActiveCode = result.ActiveCode
+	return result, report
+}
+
+// matchTasks returns the index of the matching remote task for each local
+// task that has one. Tasks are matched by their ID first. Tasks without
+// match are matched by their code if at least one of them has no ID yet or
+// if both have been created independently with the same code.
+func matchTasks(local, remote []clocked.Task) map[int]int {
+	result := make(map[int]int)
+	byID := make(map[string]int)
+	byCode := make(map[string]int)
+	for idx, t := range remote {
+		if t.ID != "" {
+			byID[t.ID] = idx
+		}
+		byCode[t.Code] = idx
+	}
+	matched := make(map[int]struct{})
+	for lIdx, t := range local {
+		if t.ID == "" {
+			continue
+		}
+		if rIdx, found := byID[t.ID]; found {
+			result[lIdx] = rIdx
+			matched[rIdx] = struct{}{}
+		}
+	}
+	localIDs := make(map[string]struct{})
+	for _, t := range local {
+		if t.ID != "" {
+			localIDs[t.ID] = struct{}{}
+		}
+	}
+	for lIdx, t := range local {
+		if _, found := result[lIdx]; found {
+			continue
+		}
+		rIdx, found := byCode[t.Code]
+		if !found {
+			continue
+		}
+		if _, taken := matched[rIdx]; taken {
+			continue
+		}
+		// The remote task is a different one that is known locally
+		// under another code.
+		if _, known := localIDs[remote[rIdx].ID]; known {
+			continue
+		}
+		result[lIdx] = rIdx
+		matched[rIdx] = struct{}{}
+	}
+	return result
+}
+
+// mergeTombstones returns all tombstones of both stores. If a task has been
+// deleted in both stores, the later deletion is kept.
+func mergeTombstones(local, remote database.Tombstones) database.Tombstones {
+	result := local.Copy()
+	for key, tm := range remote {
+		if existing, found := result[key]; !found || tm.After(existing) {
+			result[key] = tm
+		}
+	}
+	return result
+}
+
+// deleted reports if the task has been deleted after its last change.
+func deleted(t clocked.Task, tombstones database.Tombstones) bool {
+	tm, found := tombstones[t.Key()]
+	if !found {
+		return false
+	}
+	return !lastChange(t).After(tm)
+}
+
+// lastChange returns the time the task or one of its bookings has been
+// changed the last time.
+func lastChange(t clocked.Task) time.Time {
+	result := t.UpdatedTime()
+	for idx := range t.Bookings {
+		if tm := t.Bookings[idx].UpdatedTime(); tm.After(result) {
+			result = tm
+		}
+	}
+	return result
+}
+
+// uniqueCode appends a number to code if another task already uses it,
+// e.g. if a task has been renamed on one machine while another task with
+// the same code has been created on the other one.
+func uniqueCode(code string, used map[string]struct{}) string {
+	if _, found := used[code]; !found {
+		return code
+	}
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s-%d", code, n)
+		if _, found := used[candidate]; !found {
+			return candidate
+		}
+	}
+}
+
+func sameTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+type byStart []clocked.Booking
+
+func (l byStart) Len() int {
+	return len(l)
+}
+
+func (l byStart) Less(i, j int) bool {
+	a, b := l[i].StartTime(), l[j].StartTime()
+	if a == nil || b == nil {
+		return b != nil
+	}
+	return a.Before(*b)
+}
+
+func (l byStart) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+}
+
+// mergeBookings merges the bookings of a task. As the bookings of a single
+// task are compared, bookings without ID are identified by their start
+// alone so that they still match if the task has been renamed.
+func mergeBookings(local, remote []clocked.Booking, report *Report) []clocked.Booking {
+	result := make([]clocked.Booking, 0, len(local)+len(remote))
+	remoteBookings := make(map[string]clocked.Booking)
+	for _, b := range remote {
+		remoteBookings[b.Key("")] = b
+	}
+	seen := make(map[string]struct{})
+	for _, lb := range local {
+		key := lb.Key("")
+		seen[key] = struct{}{}
+		rb, found := remoteBookings[key]
+		if found && rb.UpdatedTime().After(lb.UpdatedTime()) {
+			if rb.Start != lb.Start || rb.Stop != lb.Stop {
+				report.BookingsUpdated++
+			}
+			result = append(result, rb)
+			continue
+		}
+		result = append(result, lb)
+	}
+	for _, rb := range remote {
+		if _, found := seen[rb.Key("")]; found {
+			continue
+		}
+		report.BookingsAdded++
+		result = append(result, rb)
+	}
+	sort.Stable(byStart(result))
+	return result
+}
+
+// resolveActiveCode makes sure that at most one booking is still running:
+// the latest one that is the last booking of its task. Every other open
+// booking is stopped when the next booking of its task or the running one
+// started, whatever comes first.
+func resolveActiveCode(s *State, report *Report) {
+	var active *clocked.Booking
+	var activeStart *time.Time
+	s.ActiveCode = ""
+	for tIdx := range s.Tasks {
+		t := &s.Tasks[tIdx]
+		if len(t.Bookings) == 0 {
+			continue
+		}
+		b := &t.Bookings[len(t.Bookings)-1]
+		start := b.StartTime()
+		if b.Stop != "" || start == nil {
+			continue
+		}
+		if active == nil || start.After(*activeStart) {
+			active = b
+			activeStart = start
+			s.ActiveCode = t.Code
+		}
+	}
+	for tIdx := range s.Tasks {
+		t := &s.Tasks[tIdx]
+		for bIdx := range t.Bookings {
+			b := &t.Bookings[bIdx]
+			if b.Stop != "" || b == active {
+				continue
+			}
+			start := b.StartTime()
+			var stop time.Time
+			if bIdx+1 < len(t.Bookings) {
+				if next := t.Bookings[bIdx+1].StartTime(); next != nil {
+					stop = *next
+				}
+			}
+			if activeStart != nil && (stop.IsZero() || activeStart.Before(stop)) {
+				stop = *activeStart
+			}
+			if start != nil && (stop.IsZero() || stop.Before(*start)) {
+				stop = *start
+			}
+			b.SetStop(stop)
+			b.Touch(time.Now())
+			report.BookingsClosed++
+		}
+	}
+}
+
+func loadFolder(path string, log *logrus.Logger) (*database.FolderBasedDatabase, State, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, State{}, err
+	}
+	db, err := database.NewDatabase(path, log)
+	if err != nil {
+		return nil, State{}, err
+	}
+	if err := db.LoadState(); err != nil {
+		return nil, State{}, err
+	}
+	folder := db.(*database.FolderBasedDatabase)
+	tasks, _ := folder.AllTasks()
+	return folder, State{
+		Tasks:      tasks,
+		Deleted:    folder.Tombstones(),
+		ActiveCode: folder.ActiveCode(),
+	}, nil
+}
+
+// SyncFolders merges the stores inside the given folders and writes the
+// result back into both of them. The remote folder is created if it doesn't
+// exist yet.
+func SyncFolders(localPath, remotePath string, log *logrus.Logger) (Report, error) {
+	localDB, local, err := loadFolder(localPath, log)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to load %s: %s", localPath, err.Error())
+	}
+	remoteDB, remote, err := loadFolder(remotePath, log)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to load %s: %s", remotePath, err.Error())
+	}
+	merged, report := Merge(local, remote)
+	if err := localDB.ReplaceState(fmt.Sprintf("Sync with %s", remotePath), merged.Tasks, merged.ActiveCode, merged.Deleted); err != nil {
+		return report, err
+	}
+	if err := remoteDB.ReplaceState(fmt.Sprintf("Sync with %s", localPath), merged.Tasks, merged.ActiveCode, merged.Deleted); err != nil {
+		return report, err
+	}
+	return report, nil
+}
@@ -0,0 +1,148 @@
+package storesync_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/database"
+	"github.com/zerok/clocked/internal/storesync"
+)
+
+func TestMerge(t *testing.T) {
+	start := time.Date(2017, 10, 9, 8, 0, 0, 0, time.UTC)
+	shared := clocked.Booking{ID: "1", Start: start.Format(time.RFC3339)}
+	stopped := shared
+	stopped.SetStop(start.Add(time.Hour))
+	stopped.Touch(start.Add(time.Hour))
+
+	local := storesync.State{
+		Tasks: []clocked.Task{
+			{ID: "ta", Code: "a", Title: "old", Updated: start.Format(time.RFC3339), Bookings: []clocked.Booking{shared}},
+			{ID: "tb", Code: "b", Bookings: []clocked.Booking{{ID: "2", Start: start.Add(3 * time.Hour).Format(time.RFC3339)}}},
+		},
+	}
+	remote := storesync.State{
+		Tasks: []clocked.Task{
+			{ID: "ta", Code: "a", Title: "new", Updated: start.Add(time.Minute).Format(time.RFC3339), Bookings: []clocked.Booking{stopped}},
+			{ID: "tc", Code: "c", Bookings: []clocked.Booking{{ID: "3", Start: start.Add(4 * time.Hour).Format(time.RFC3339)}}},
+		},
+	}
+
+	merged, report := storesync.Merge(local, remote)
+	require.Len(t, merged.Tasks, 3)
+	require.Equal(t, "new", merged.Tasks[0].Title, "the newer title should win")
+	require.Equal(t, stopped.Stop, merged.Tasks[0].Bookings[0].Stop, "the newer version of the booking should win")
+	require.Equal(t, "c", merged.ActiveCode, "the booking started last should stay active")
+	require.Equal(t, start.Add(4*time.Hour).Format(time.RFC3339), merged.Tasks[1].Bookings[0].Stop, "b should have been clocked out when c was clocked into")
+	require.Equal(t, "", local.Tasks[1].Bookings[0].Stop, "the local state must not be modified")
+	require.Equal(t, 1, report.TasksAdded)
+	require.Equal(t, 1, report.TasksUpdated)
+	require.Equal(t, 1, report.BookingsUpdated)
+	require.Equal(t, 1, report.BookingsClosed)
+}
+
+func TestMergeRenamedTask(t *testing.T) {
+	start := time.Date(2017, 10, 9, 8, 0, 0, 0, time.UTC)
+	booking := clocked.Booking{Start: start.Format(time.RFC3339), Stop: start.Add(time.Hour).Format(time.RFC3339)}
+	local := storesync.State{Tasks: []clocked.Task{
+		{ID: "ta", Code: "b", Updated: start.Add(time.Hour).Format(time.RFC3339), Bookings: []clocked.Booking{booking}},
+	}}
+	remote := storesync.State{Tasks: []clocked.Task{
+		{ID: "ta", Code: "a", Updated: start.Format(time.RFC3339), Bookings: []clocked.Booking{booking}},
+		{ID: "tb", Code: "b"},
+	}}
+	merged, report := storesync.Merge(local, remote)
+	require.Len(t, merged.Tasks, 2)
+	require.Equal(t, "b", merged.Tasks[0].Code, "the task is matched by its ID")
+	require.Len(t, merged.Tasks[0].Bookings, 1, "the bookings must not be duplicated")
+	require.Equal(t, "b-2", merged.Tasks[1].Code, "codes have to stay unique")
+	require.Equal(t, 1, report.TasksAdded)
+	require.Equal(t, 0, report.BookingsAdded)
+
+	// Tasks without ID are matched by their code and get an ID.
+	merged, _ = storesync.Merge(
+		storesync.State{Tasks: []clocked.Task{{Code: "a"}}},
+		storesync.State{Tasks: []clocked.Task{{Code: "a"}}},
+	)
+	require.Len(t, merged.Tasks, 1)
+	require.NotEmpty(t, merged.Tasks[0].ID)
+}
+
+func TestMergeDeletedTask(t *testing.T) {
+	start := time.Date(2017, 10, 9, 8, 0, 0, 0, time.UTC)
+	local := storesync.State{
+		Tasks:   []clocked.Task{{ID: "tb", Code: "b", Updated: start.Add(2 * time.Hour).Format(time.RFC3339)}},
+		Deleted: database.Tombstones{"ta": start.Add(time.Hour), "tc": start.Add(time.Hour)},
+	}
+	remote := storesync.State{Tasks: []clocked.Task{
+		{ID: "ta", Code: "a", Updated: start.Format(time.RFC3339)},
+		{ID: "tc", Code: "c", Updated: start.Add(2 * time.Hour).Format(time.RFC3339)},
+	}, Deleted: database.Tombstones{"tb": start.Add(time.Hour)}}
+	merged, report := storesync.Merge(local, remote)
+	require.Len(t, merged.Tasks, 2, "a has been deleted, b and c have been changed after their deletion")
+	require.Equal(t, "b", merged.Tasks[0].Code)
+	require.Equal(t, "c", merged.Tasks[1].Code)
+	require.Equal(t, database.Tombstones{"ta": start.Add(time.Hour)}, merged.Deleted)
+	require.Equal(t, 0, report.TasksRemoved)
+	require.Equal(t, 1, report.TasksAdded)
+
+	merged, report = storesync.Merge(remote, local)
+	require.Len(t, merged.Tasks, 2)
+	require.Equal(t, 1, report.TasksRemoved, "a is removed from the remote store")
+}
+
+func TestSyncFolders(t *testing.T) {
+	root, err := ioutil.TempDir("", "clocked")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+	log := logrus.New()
+	log.Out = ioutil.Discard
+	laptopPath := filepath.Join(root, "laptop")
+	desktopPath := filepath.Join(root, "desktop")
+
+	laptop, err := database.NewDatabase(laptopPath, log)
+	require.NoError(t, err)
+	require.NoError(t, laptop.AddTask(clocked.Task{Code: "a"}))
+	require.NoError(t, laptop.ClockInto("a"))
+	require.NoError(t, laptop.ClockOutOf("a"))
+
+	_, err = storesync.SyncFolders(laptopPath, desktopPath, log)
+	require.NoError(t, err, "syncing into a new folder should work")
+
+	desktop, err := database.NewDatabase(desktopPath, log)
+	require.NoError(t, err)
+	require.NoError(t, desktop.LoadState())
+	task, found := desktop.TaskByCode("a")
+	require.True(t, found, "the task should have been copied to the desktop")
+	require.Len(t, task.Bookings, 1)
+	require.NoError(t, desktop.AddTask(clocked.Task{Code: "b"}))
+	require.NoError(t, desktop.ClockInto("b"))
+
+	report, err := storesync.SyncFolders(laptopPath, desktopPath, log)
+	require.NoError(t, err)
+	require.Equal(t, 1, report.TasksAdded)
+	require.NoError(t, laptop.LoadState())
+	require.Equal(t, "b", laptop.ActiveCode(), "the clock-in on the desktop should be synced")
+	task, _ = laptop.TaskByCode("a")
+	require.Len(t, task.Bookings, 1, "bookings must not be duplicated")
+
+	// Deleting a task (here by undoing its creation) is synced as well.
+	require.NoError(t, desktop.LoadState())
+	undoer := desktop.(database.Undoer)
+	for _, found = desktop.TaskByCode("b"); found; _, found = desktop.TaskByCode("b") {
+		_, err = undoer.Undo()
+		require.NoError(t, err)
+	}
+	report, err = storesync.SyncFolders(laptopPath, desktopPath, log)
+	require.NoError(t, err)
+	require.Equal(t, 1, report.TasksRemoved)
+	require.NoError(t, laptop.LoadState())
+	_, found = laptop.TaskByCode("b")
+	require.False(t, found, "the deletion should be synced")
+}
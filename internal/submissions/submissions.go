@@ -0,0 +1,83 @@
+// Package submissions remembers which days have been submitted to JIRA so
+// that days that still have to be synchronized can be spotted.
+package submissions
+
+import (
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/zerok/clocked/internal/database"
+	"gopkg.in/yaml.v2"
+)
+
+// Filename is the name of the file inside the store the submissions are
+// kept in.
+const Filename = "submissions.yml"
+
+// Possible values of Submission.Status.
+const (
+	StatusOK     = "ok"
+	StatusFailed = "failed"
+)
+
+// Submission is the result of synchronizing a single day.
+type Submission struct {
+	Status    string        `yaml:"status"`
+	Submitted time.Time     `yaml:"submitted"`
+	Total     time.Duration `yaml:"total"`
+}
+
+// Log maps days (formatted using database.DayFormat) to their latest
+// submission.
+type Log map[string]Submission
+
+type logFile struct {
+	Days Log `yaml:"days"`
+}
+
+// Load reads the log from the given file. A missing file results in an
+// empty log.
+func Load(path string) (Log, error) {
+	var f logFile
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Log{}, nil
+		}
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if f.Days == nil {
+		f.Days = Log{}
+	}
+	return f.Days, nil
+}
+
+// Save writes the log to the given file.
+func (l Log) Save(path string) error {
+	data, err := yaml.Marshal(logFile{Days: l})
+	if err != nil {
+		return err
+	}
+	return database.WriteFile(path, data)
+}
+
+// Record stores the submission of the given day.
+func (l Log) Record(day time.Time, s Submission) {
+	l[day.Format(database.DayFormat)] = s
+}
+
+// For returns the latest submission of the given day.
+func (l Log) For(day time.Time) (Submission, bool) {
+	s, ok := l[day.Format(database.DayFormat)]
+	return s, ok
+}
+
+// Outdated reports if the day was submitted successfully but the time
+// booked on it has changed since.
+func (s Submission) Outdated(total time.Duration) bool {
+	return s.Status == StatusOK && s.Total != total
+}
@@ -0,0 +1,39 @@
+package submissions
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clocked-submissions")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, Filename)
+
+	log, err := Load(path)
+	require.NoError(t, err)
+	require.Empty(t, log)
+
+	day := time.Date(2018, 3, 1, 15, 0, 0, 0, time.Local)
+	log.Record(day, Submission{Status: StatusOK, Submitted: day, Total: 8 * time.Hour})
+	require.NoError(t, log.Save(path))
+
+	log, err = Load(path)
+	require.NoError(t, err)
+	s, ok := log.For(time.Date(2018, 3, 1, 0, 0, 0, 0, time.Local))
+	require.True(t, ok)
+	require.Equal(t, StatusOK, s.Status)
+	require.True(t, day.Equal(s.Submitted))
+	require.False(t, s.Outdated(8*time.Hour))
+	require.True(t, s.Outdated(7*time.Hour))
+
+	_, ok = log.For(day.AddDate(0, 0, 1))
+	require.False(t, ok)
+	require.False(t, Submission{Status: StatusFailed}.Outdated(time.Hour))
+}
@@ -0,0 +1,191 @@
+// Package tags provides statistics about the tags used by tasks and
+// operations that change a tag across all tasks at once.
+package tags
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/database"
+	"gopkg.in/yaml.v2"
+)
+
+// Filename is the name of the file inside the store that holds the colors
+// assigned to tags.
+const Filename = "tags.yml"
+
+// Info describes a single tag.
+type Info struct {
+	Name string
+	// Tasks is the number of tasks with this tag.
+	Tasks int
+	// Total is the time booked on these tasks within the requested
+	// period.
+	Total time.Duration
+}
+
+// Collect returns all tags sorted by name together with the time booked on
+// their tasks between from and to.
+func Collect(db database.Database, from, to time.Time) ([]Info, error) {
+	tasks, err := db.AllTasks()
+	if err != nil {
+		return nil, err
+	}
+	totals := db.GenerateSummary(from, to).Totals
+	infos := make(map[string]*Info)
+	for _, t := range tasks {
+		for _, tag := range unique(t.Tags) {
+			info, found := infos[tag]
+			if !found {
+				info = &Info{Name: tag}
+				infos[tag] = info
+			}
+			info.Tasks++
+			info.Total += totals[t.Code]
+		}
+	}
+	result := make([]Info, 0, len(infos))
+	for _, info := range infos {
+		result = append(result, *info)
+	}
+	sort.Sort(byName(result))
+	return result, nil
+}
+
+type byName []Info
+
+func (l byName) Len() int {
+	return len(l)
+}
+
+func (l byName) Less(i, j int) bool {
+	return l[i].Name < l[j].Name
+}
+
+func (l byName) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+}
+
+// unique removes empty and duplicate tags while keeping their order.
+func unique(tags []string) []string {
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]struct{})
+	for _, tag := range tags {
+		if _, found := seen[tag]; found || tag == "" {
+			continue
+		}
+		seen[tag] = struct{}{}
+		result = append(result, tag)
+	}
+	return result
+}
+
+// Merge replaces the given tags with target on all tasks. Renaming a tag is
+// a merge with a single source. The number of changed tasks is returned.
+// All tasks are updated as a single operation so that either all or none
+// of them are changed.
+func Merge(db database.Database, sources []string, target string) (int, error) {
+	if target == "" || strings.IndexFunc(target, unicode.IsSpace) != -1 {
+		return 0, fmt.Errorf("invalid tag %q", target)
+	}
+	replaced := make(map[string]struct{})
+	for _, s := range sources {
+		replaced[s] = struct{}{}
+	}
+	tasks, err := db.AllTasks()
+	if err != nil {
+		return 0, err
+	}
+	var changed []clocked.Task
+	for _, t := range tasks {
+		tags := make([]string, 0, len(t.Tags))
+		modified := false
+		for _, tag := range t.Tags {
+			if _, found := replaced[tag]; found {
+				modified = modified || tag != target
+				tag = target
+			}
+			tags = append(tags, tag)
+		}
+		if !modified {
+			continue
+		}
+		t.Tags = unique(tags)
+		changed = append(changed, t)
+	}
+	if len(changed) == 0 {
+		return 0, nil
+	}
+	description := fmt.Sprintf("Rename tag %s to %s", strings.Join(sources, ", "), target)
+	if len(sources) > 1 {
+		description = fmt.Sprintf("Merge tags %s into %s", strings.Join(sources, ", "), target)
+	}
+	batch, ok := db.(database.BatchUpdater)
+	if !ok {
+		return 0, fmt.Errorf("the store can't update several tasks at once")
+	}
+	if err := batch.UpdateTasks(description, changed); err != nil {
+		return 0, err
+	}
+	return len(changed), nil
+}
+
+// Colors maps tags to the color they should be rendered in. Colors are
+// either names like "red" or numbers of the 256 color palette.
+type Colors map[string]string
+
+type colorsFile struct {
+	Colors Colors `yaml:"colors"`
+}
+
+// LoadColors reads the colors from the given file. A missing file results
+// in no colors being assigned.
+func LoadColors(path string) (Colors, error) {
+	var f colorsFile
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Colors{}, nil
+		}
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if f.Colors == nil {
+		f.Colors = Colors{}
+	}
+	return f.Colors, nil
+}
+
+// Save writes the colors to the given file.
+func (c Colors) Save(path string) error {
+	data, err := yaml.Marshal(colorsFile{Colors: c})
+	if err != nil {
+		return err
+	}
+	return database.WriteFile(path, data)
+}
+
+// Merge moves the colors of the given tags to target. target keeps its own
+// color if it already has one.
+func (c Colors) Merge(sources []string, target string) {
+	for _, s := range sources {
+		color, found := c[s]
+		if !found {
+			continue
+		}
+		if _, exists := c[target]; !exists {
+			c[target] = color
+		}
+		if s != target {
+			delete(c, s)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package tags
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/database"
+)
+
+func newTestDatabase(t *testing.T) (database.Database, func()) {
+	dir, err := ioutil.TempDir("", "clocked-tags")
+	require.NoError(t, err)
+	log := logrus.New()
+	log.Out = ioutil.Discard
+	db, err := database.NewDatabase(dir, log)
+	require.NoError(t, err)
+	require.NoError(t, db.LoadState())
+	return db, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func booking(start time.Time, d time.Duration) clocked.Booking {
+	var b clocked.Booking
+	b.SetStart(start)
+	b.SetStop(start.Add(d))
+	return b
+}
+
+func TestCollect(t *testing.T) {
+	db := database.NewInMemory()
+	day := time.Date(2018, 3, 1, 9, 0, 0, 0, time.Local)
+	require.NoError(t, db.AddTask(clocked.Task{Code: "a", Tags: []string{"review", "x", "review"}, Bookings: []clocked.Booking{booking(day, time.Hour)}}))
+	require.NoError(t, db.AddTask(clocked.Task{Code: "b", Tags: []string{"x", ""}, Bookings: []clocked.Booking{booking(day.AddDate(0, 0, -1), 2*time.Hour)}}))
+	require.NoError(t, db.AddTask(clocked.Task{Code: "c"}))
+
+	infos, err := Collect(db, database.StartOfDay(day), database.StartOfDay(day).AddDate(0, 0, 1))
+	require.NoError(t, err)
+	require.Equal(t, []Info{
+		{Name: "review", Tasks: 1, Total: time.Hour},
+		{Name: "x", Tasks: 2, Total: time.Hour},
+	}, infos)
+}
+
+func TestMerge(t *testing.T) {
+	db, cleanup := newTestDatabase(t)
+	defer cleanup()
+	require.NoError(t, db.AddTask(clocked.Task{Code: "a", Tags: []string{"rev", "x"}}))
+	require.NoError(t, db.AddTask(clocked.Task{Code: "b", Tags: []string{"x", "review", "reviews"}}))
+	require.NoError(t, db.AddTask(clocked.Task{Code: "c", Tags: []string{"y"}}))
+
+	_, err := Merge(db, []string{"rev"}, "re view")
+	require.Error(t, err)
+
+	n, err := Merge(db, []string{"rev", "reviews"}, "review")
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	a, _ := db.TaskByCode("a")
+	b, _ := db.TaskByCode("b")
+	require.Equal(t, []string{"review", "x"}, a.Tags)
+	require.Equal(t, []string{"x", "review"}, b.Tags)
+
+	// The whole merge is undone at once.
+	desc, err := db.(database.Undoer).Undo()
+	require.NoError(t, err)
+	require.Equal(t, "Merge tags rev, reviews into review", desc)
+	a, _ = db.TaskByCode("a")
+	require.Equal(t, []string{"rev", "x"}, a.Tags)
+
+	n, err = Merge(db, []string{"unknown"}, "z")
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+}
+
+func TestColors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clocked-tags")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, Filename)
+
+	colors, err := LoadColors(path)
+	require.NoError(t, err)
+	require.Empty(t, colors)
+
+	colors["rev"] = "red"
+	colors["meeting"] = "33"
+	colors.Merge([]string{"rev"}, "review")
+	require.Equal(t, Colors{"review": "red", "meeting": "33"}, colors)
+	colors.Merge([]string{"review"}, "meeting")
+	require.Equal(t, Colors{"meeting": "33"}, colors)
+
+	require.NoError(t, colors.Save(path))
+	loaded, err := LoadColors(path)
+	require.NoError(t, err)
+	require.Equal(t, colors, loaded)
+}
@@ -0,0 +1,106 @@
+package team
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/zerok/clocked/internal/database"
+)
+
+// Client talks to a team server on behalf of a single user.
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// EntriesFromSummary turns the bookings of a summary into entries per day
+// and task. The tags of each task are looked up in the database.
+func EntriesFromSummary(db database.Database, summary database.Summary) []Entry {
+	index := make(map[string]int)
+	entries := make([]Entry, 0, len(summary.Totals))
+	for _, b := range summary.Bookings {
+		if b.Start == nil || b.Stop == nil {
+			continue
+		}
+		day := b.Start.Local().Format(database.DayFormat)
+		key := day + "/" + b.Code
+		idx, found := index[key]
+		if !found {
+			e := Entry{Day: day, Code: b.Code}
+			if t, ok := db.TaskByCode(b.Code); ok {
+				e.Tags = t.Tags
+			}
+			entries = append(entries, e)
+			idx = len(entries) - 1
+			index[key] = idx
+		}
+		entries[idx].Seconds += int64(b.Duration().Seconds())
+	}
+	return entries
+}
+
+func (c *Client) do(req *http.Request, expected int, result interface{}) error {
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != expected {
+		var e errorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&e); err == nil && e.Error != "" {
+			return fmt.Errorf("team server returned %d: %s", resp.StatusCode, e.Error)
+		}
+		return fmt.Errorf("team server returned %d", resp.StatusCode)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// Push replaces all of the user's entries within [from, to) on the server.
+func (c *Client) Push(from, to time.Time, entries []Entry) error {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(PushRequest{
+		From:    from.Format(database.DayFormat),
+		To:      to.Format(database.DayFormat),
+		Entries: entries,
+	}); err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+"/api/push", &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.do(req, http.StatusNoContent, nil)
+}
+
+// Report fetches the aggregated report for [from, to).
+func (c *Client) Report(from, to time.Time, group string) ([]Row, error) {
+	q := url.Values{}
+	q.Set("from", from.Format(database.DayFormat))
+	q.Set("to", to.Format(database.DayFormat))
+	q.Set("group", group)
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/api/report?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var rows []Row
+	return rows, c.do(req, http.StatusOK, &rows)
+}
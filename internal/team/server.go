@@ -0,0 +1,176 @@
+// Package team implements a small server that collects the summaries of
+// every member of a team and offers aggregated reports per task, tag and
+// person.
+package team
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/zerok/clocked/internal/database"
+	"gopkg.in/yaml.v2"
+)
+
+// User is a member of the team. Every member authenticates with their own
+// token.
+type User struct {
+	Name  string `yaml:"name"`
+	Token string `yaml:"token"`
+}
+
+// LoadUsers reads the list of users from a YAML file.
+func LoadUsers(path string) ([]User, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var users []User
+	if err := yaml.Unmarshal(data, &users); err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		if u.Name == "" || u.Token == "" {
+			return nil, fmt.Errorf("every user requires a name and a token")
+		}
+	}
+	return users, nil
+}
+
+// PushRequest replaces all entries of the pushing user within [From, To).
+type PushRequest struct {
+	From    string  `json:"from"`
+	To      string  `json:"to"`
+	Entries []Entry `json:"entries"`
+}
+
+// userKey is the context key of the authenticated user.
+type userKey struct{}
+
+// userFromContext returns the name of the user that authenticated the
+// request.
+func userFromContext(ctx context.Context) string {
+	user, _ := ctx.Value(userKey{}).(string)
+	return user
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server is a http.Handler serving the team API.
+type Server struct {
+	store *Store
+	users []User
+	mux   *http.ServeMux
+}
+
+func NewServer(store *Store, users []User) *Server {
+	s := &Server{
+		store: store,
+		users: users,
+		mux:   http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/api/push", s.handlePush)
+	s.mux.HandleFunc("/api/report", s.handleReport)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.authenticate(r)
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "invalid or missing token"})
+		return
+	}
+	s.mux.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+}
+
+func (s *Server) authenticate(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return "", false
+	}
+	token := []byte(strings.TrimPrefix(header, "Bearer "))
+	for _, u := range s.users {
+		if subtle.ConstantTimeCompare(token, []byte(u.Token)) == 1 {
+			return u.Name, true
+		}
+	}
+	return "", false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func validRange(from, to string) error {
+	f, err := time.Parse(database.DayFormat, from)
+	if err != nil {
+		return fmt.Errorf("invalid from date: %s", err.Error())
+	}
+	t, err := time.Parse(database.DayFormat, to)
+	if err != nil {
+		return fmt.Errorf("invalid to date: %s", err.Error())
+	}
+	if !t.After(f) {
+		return fmt.Errorf("to has to be after from")
+	}
+	return nil
+}
+
+func (s *Server) handlePush(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "only POST is allowed"})
+		return
+	}
+	var req PushRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	if err := validRange(req.From, req.To); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	for _, e := range req.Entries {
+		if _, err := time.Parse(database.DayFormat, e.Day); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid day of entry %s: %s", e.Code, err.Error())})
+			return
+		}
+	}
+	if err := s.store.Replace(userFromContext(r.Context()), req.From, req.To, req.Entries); err != nil {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "only GET is allowed"})
+		return
+	}
+	q := r.URL.Query()
+	from, to := q.Get("from"), q.Get("to")
+	if err := validRange(from, to); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	group := q.Get("group")
+	switch group {
+	case "":
+		group = GroupByTask
+	case GroupByTask, GroupByTag, GroupByPerson:
+	default:
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("unsupported grouping %s", group)})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.store.Aggregate(from, to, group))
+}
@@ -0,0 +1,161 @@
+package team
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/zerok/clocked/internal/database"
+)
+
+// Entry is the time a single person spent on a task on a given day.
+type Entry struct {
+	Day     string   `json:"day"`
+	Code    string   `json:"code"`
+	Tags    []string `json:"tags,omitempty"`
+	Seconds int64    `json:"seconds"`
+}
+
+// Store keeps the entries pushed by all members of the team. If a path is
+// set, the data is persisted there as JSON after every change.
+type Store struct {
+	path    string
+	lock    sync.RWMutex
+	entries map[string][]Entry
+}
+
+// NewStore loads the store from the given path. An empty path results in a
+// store that only lives in memory.
+func NewStore(path string) (*Store, error) {
+	s := &Store{
+		path:    path,
+		entries: make(map[string][]Entry),
+	}
+	if path == "" {
+		return s, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.entries); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) save(entries map[string][]Entry) error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	return database.WriteFile(s.path, data)
+}
+
+// Replace removes all entries of the given user within [from, to) and adds
+// the new ones. Pushing the same range again therefore doesn't count time
+// twice. The entries only change in memory once they have been saved.
+func (s *Store) Replace(user, from, to string, entries []Entry) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	kept := make([]Entry, 0, len(s.entries[user])+len(entries))
+	for _, e := range s.entries[user] {
+		if e.Day >= from && e.Day < to {
+			continue
+		}
+		kept = append(kept, e)
+	}
+	for _, e := range entries {
+		if e.Day >= from && e.Day < to {
+			kept = append(kept, e)
+		}
+	}
+	updated := make(map[string][]Entry, len(s.entries)+1)
+	for u, e := range s.entries {
+		updated[u] = e
+	}
+	updated[user] = kept
+	if err := s.save(updated); err != nil {
+		return err
+	}
+	s.entries = updated
+	return nil
+}
+
+const (
+	GroupByTask   = "task"
+	GroupByTag    = "tag"
+	GroupByPerson = "person"
+)
+
+// Row is a single line of an aggregated report.
+type Row struct {
+	Key     string           `json:"key"`
+	Seconds int64            `json:"seconds"`
+	Days    map[string]int64 `json:"days"`
+}
+
+type byKey []Row
+
+func (r byKey) Len() int {
+	return len(r)
+}
+
+func (r byKey) Less(i, j int) bool {
+	return r[i].Key < r[j].Key
+}
+
+func (r byKey) Swap(i, j int) {
+	r[i], r[j] = r[j], r[i]
+}
+
+// Aggregate sums up all entries within [from, to) grouped by task, tag or
+// person. Entries without tags are grouped under an empty tag.
+func (s *Store) Aggregate(from, to, group string) []Row {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	rows := make(map[string]*Row)
+	add := func(key string, e Entry) {
+		row, ok := rows[key]
+		if !ok {
+			row = &Row{Key: key, Days: make(map[string]int64)}
+			rows[key] = row
+		}
+		row.Seconds += e.Seconds
+		row.Days[e.Day] += e.Seconds
+	}
+	for user, entries := range s.entries {
+		for _, e := range entries {
+			if e.Day < from || e.Day >= to {
+				continue
+			}
+			switch group {
+			case GroupByPerson:
+				add(user, e)
+			case GroupByTag:
+				if len(e.Tags) == 0 {
+					add("", e)
+				}
+				for _, tag := range e.Tags {
+					add(tag, e)
+				}
+			default:
+				add(e.Code, e)
+			}
+		}
+	}
+	result := make([]Row, 0, len(rows))
+	for _, r := range rows {
+		result = append(result, *r)
+	}
+	sort.Sort(byKey(result))
+	return result
+}
@@ -0,0 +1,118 @@
+package team_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zerok/clocked/internal/team"
+)
+
+func TestPushAndReport(t *testing.T) {
+	store, err := team.NewStore("")
+	require.NoError(t, err)
+	srv := httptest.NewServer(team.NewServer(store, []team.User{
+		{Name: "alice", Token: "a"},
+		{Name: "bob", Token: "b"},
+	}))
+	defer srv.Close()
+
+	from := time.Date(2017, 10, 9, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+	alice := team.NewClient(srv.URL, "a")
+	bob := team.NewClient(srv.URL, "b")
+	require.NoError(t, alice.Push(from, to, []team.Entry{
+		{Day: "2017-10-09", Code: "ABC-1", Tags: []string{"project-a"}, Seconds: 3600},
+		{Day: "2017-10-10", Code: "ABC-2", Tags: []string{"project-a"}, Seconds: 1800},
+	}))
+	// Pushing again replaces the previous entries.
+	require.NoError(t, alice.Push(from, to, []team.Entry{
+		{Day: "2017-10-09", Code: "ABC-1", Tags: []string{"project-a"}, Seconds: 3600},
+	}))
+	require.NoError(t, bob.Push(from, to, []team.Entry{
+		{Day: "2017-10-09", Code: "ABC-1", Tags: []string{"project-a", "review"}, Seconds: 600},
+	}))
+	require.Error(t, team.NewClient(srv.URL, "unknown").Push(from, to, nil), "unknown tokens should be rejected")
+
+	rows, err := alice.Report(from, to, team.GroupByTask)
+	require.NoError(t, err)
+	require.Equal(t, []team.Row{{Key: "ABC-1", Seconds: 4200, Days: map[string]int64{"2017-10-09": 4200}}}, rows)
+
+	rows, err = alice.Report(from, to, team.GroupByPerson)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, "alice", rows[0].Key)
+	require.Equal(t, int64(3600), rows[0].Seconds)
+
+	rows, err = alice.Report(from, to, team.GroupByTag)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, "review", rows[1].Key)
+	require.Equal(t, int64(600), rows[1].Seconds)
+
+	_, err = alice.Report(from, to, "unknown")
+	require.Error(t, err)
+}
+
+func TestPushIgnoresUserHeader(t *testing.T) {
+	store, err := team.NewStore("")
+	require.NoError(t, err)
+	srv := httptest.NewServer(team.NewServer(store, []team.User{{Name: "bob", Token: "b"}}))
+	defer srv.Close()
+
+	body := `{"from":"2017-10-09","to":"2017-10-16","entries":[{"day":"2017-10-09","code":"ABC-1","seconds":60}]}`
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/push", strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer b")
+	req.Header.Set("X-Clocked-User", "alice")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	from := time.Date(2017, 10, 9, 0, 0, 0, 0, time.UTC)
+	rows, err := team.NewClient(srv.URL, "b").Report(from, from.AddDate(0, 0, 7), team.GroupByPerson)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, "bob", rows[0].Key)
+}
+
+func TestPushRejectsInvalidDays(t *testing.T) {
+	store, err := team.NewStore("")
+	require.NoError(t, err)
+	srv := httptest.NewServer(team.NewServer(store, []team.User{{Name: "bob", Token: "b"}}))
+	defer srv.Close()
+
+	for _, day := range []string{"", "2017-10-9", "09.10.2017", "2017-13-01"} {
+		body := `{"from":"2017-10-09","to":"2017-10-16","entries":[{"day":"` + day + `","code":"ABC-1","seconds":60}]}`
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/push", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer b")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode, "day %q should be rejected", day)
+	}
+}
+
+func TestReplaceKeepsDataIfSavingFails(t *testing.T) {
+	folder, err := ioutil.TempDir("", "clocked-team")
+	require.NoError(t, err)
+	defer os.RemoveAll(folder)
+	path := filepath.Join(folder, "team.json")
+	store, err := team.NewStore(path)
+	require.NoError(t, err)
+	entries := []team.Entry{{Day: "2017-10-09", Code: "ABC-1", Seconds: 60}}
+	require.NoError(t, store.Replace("bob", "2017-10-09", "2017-10-16", entries))
+
+	// A directory in place of the temporary file makes saving fail.
+	require.NoError(t, os.Mkdir(path+".tmp", 0700))
+	require.Error(t, store.Replace("bob", "2017-10-09", "2017-10-16", nil))
+	require.Equal(t, []team.Row{{Key: "ABC-1", Seconds: 60, Days: map[string]int64{"2017-10-09": 60}}}, store.Aggregate("2017-10-09", "2017-10-16", team.GroupByTask))
+}
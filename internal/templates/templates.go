@@ -0,0 +1,155 @@
+// Package templates turns the task templates defined in the configuration
+// into actual tasks and takes care of generating recurring tasks.
+package templates
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/zerok/clocked"
+	"github.com/zerok/clocked/internal/config"
+	"github.com/zerok/clocked/internal/database"
+	"gopkg.in/yaml.v2"
+)
+
+// Filename is the name of the file inside the store that remembers which
+// recurring tasks have been generated.
+const Filename = "recurring.yml"
+
+// Expand replaces the placeholders inside of pattern with values derived
+// from t. Supported placeholders are {isoweek}, {isoyear}, {year},
+// {month}, {day} and {date}. {isoweek} is only the number of the week, so
+// it has to be combined with {isoyear} to tell the weeks of different years
+// apart.
+func Expand(pattern string, t time.Time) string {
+	isoYear, isoWeek := t.ISOWeek()
+	r := strings.NewReplacer(
+		"{isoweek}", fmt.Sprintf("%02d", isoWeek),
+		"{isoyear}", fmt.Sprintf("%d", isoYear),
+		"{year}", t.Format("2006"),
+		"{month}", t.Format("01"),
+		"{day}", t.Format("02"),
+		"{date}", t.Format("2006-01-02"),
+	)
+	return r.Replace(pattern)
+}
+
+// NewTask creates a task based on the given template.
+func NewTask(tmpl config.TaskTemplate, t time.Time) clocked.Task {
+	tags := make([]string, len(tmpl.Tags))
+	copy(tags, tmpl.Tags)
+	return clocked.Task{
+		Code:  Expand(tmpl.Code, t),
+		Title: Expand(tmpl.Title, t),
+		Tags:  tags,
+	}
+}
+
+// periodStart returns the start of the period the given schedule defines
+// for t. If nothing should be generated for t, false is returned.
+func periodStart(schedule string, t time.Time) (time.Time, bool, error) {
+	switch schedule {
+	case "daily":
+		return database.StartOfDay(t), true, nil
+	case "weekdays":
+		if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+			return t, false, nil
+		}
+		return database.StartOfDay(t), true, nil
+	case "weekly":
+		return database.StartOfWeek(t), true, nil
+	case "monthly":
+		return database.StartOfMonth(t), true, nil
+	default:
+		return t, false, fmt.Errorf("unsupported schedule %s", schedule)
+	}
+}
+
+// History maps each recurring task to the first day (formatted using
+// database.DayFormat) of the latest period a task has been generated for.
+type History map[string]string
+
+type historyFile struct {
+	Periods History `yaml:"periods"`
+}
+
+// LoadHistory reads the history from the given file. A missing file results
+// in an empty history.
+func LoadHistory(path string) (History, error) {
+	var f historyFile
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return History{}, nil
+		}
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if f.Periods == nil {
+		f.Periods = History{}
+	}
+	return f.Periods, nil
+}
+
+// Save writes the history to the given file.
+func (h History) Save(path string) error {
+	data, err := yaml.Marshal(historyFile{Periods: h})
+	if err != nil {
+		return err
+	}
+	return database.WriteFile(path, data)
+}
+
+func historyKey(r config.RecurringTask) string {
+	return fmt.Sprintf("%s/%s", r.Template, r.Schedule)
+}
+
+// Generate creates all the recurring tasks configured in cfg that are due
+// at the given time and haven't been generated for the current period yet.
+// Generated periods are recorded in history so that tasks that have been
+// deleted or renamed afterwards aren't created again. The created tasks are
+// returned and changed reports if history has to be saved. Failing
+// recurring tasks don't keep the others from being created; all errors are
+// returned at once.
+func Generate(db database.Database, cfg *config.Config, history History, now time.Time) (created []clocked.Task, changed bool, err error) {
+	var errs []string
+	for _, r := range cfg.Recurring {
+		tmpl, found := cfg.Template(r.Template)
+		if !found {
+			errs = append(errs, fmt.Sprintf("recurring task references unknown template %s", r.Template))
+			continue
+		}
+		start, due, err := periodStart(r.Schedule, now)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if !due {
+			continue
+		}
+		key := historyKey(r)
+		period := start.Format(database.DayFormat)
+		if history[key] >= period {
+			continue
+		}
+		task := NewTask(tmpl, start)
+		if _, exists := db.TaskByCode(task.Code); !exists {
+			if err := db.AddTask(task); err != nil {
+				errs = append(errs, fmt.Sprintf("failed to create %s: %s", task.Code, err.Error()))
+				continue
+			}
+			created = append(created, task)
+		}
+		history[key] = period
+		changed = true
+	}
+	if len(errs) > 0 {
+		return created, changed, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return created, changed, nil
+}
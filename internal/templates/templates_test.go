@@ -0,0 +1,82 @@
+package templates_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zerok/clocked/internal/config"
+	"github.com/zerok/clocked/internal/database"
+	"github.com/zerok/clocked/internal/templates"
+)
+
+func TestExpand(t *testing.T) {
+	tm := time.Date(2018, 1, 3, 12, 0, 0, 0, time.UTC)
+	require.Equal(t, "MEET-01", templates.Expand("MEET-{isoweek}", tm))
+	require.Equal(t, "2018-01-03 (2018)", templates.Expand("{date} ({isoyear})", tm))
+	require.Equal(t, "MEET-2018-W01", templates.Expand("MEET-{isoyear}-W{isoweek}", tm))
+	// 2018-12-31 already belongs to the first week of 2019.
+	require.Equal(t, "MEET-2019-W01", templates.Expand("MEET-{isoyear}-W{isoweek}", time.Date(2018, 12, 31, 12, 0, 0, 0, time.UTC)))
+}
+
+func TestGenerate(t *testing.T) {
+	cfg := &config.Config{
+		Templates: []config.TaskTemplate{
+			{Name: "meeting", Code: "MEET-{isoyear}-W{isoweek}", Title: "Meetings of {date}", Tags: []string{"offline"}},
+		},
+		Recurring: []config.RecurringTask{
+			{Template: "meeting", Schedule: "weekly"},
+		},
+	}
+	db := database.NewInMemory()
+	history := templates.History{}
+	// 2018-01-03 is a Wednesday, so the task should reference the Monday.
+	now := time.Date(2018, 1, 3, 12, 0, 0, 0, time.UTC)
+
+	created, changed, err := templates.Generate(db, cfg, history, now)
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Len(t, created, 1, "the meeting task should have been created")
+	task, found := db.TaskByCode("MEET-2018-W01")
+	require.True(t, found, "the task should be in the database")
+	require.Equal(t, "Meetings of 2018-01-01", task.Title)
+	require.True(t, task.HasTag("offline"))
+
+	// The task is only created once per week, even if it has been
+	// deleted or renamed in the meantime.
+	fresh := database.NewInMemory()
+	created, changed, err = templates.Generate(fresh, cfg, history, now.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	require.False(t, changed)
+	require.Len(t, created, 0, "the task should only be created once per week")
+
+	created, _, err = templates.Generate(fresh, cfg, history, now.AddDate(0, 0, 7))
+	require.NoError(t, err)
+	require.Len(t, created, 1, "the task of the next week should be created")
+
+	// An unknown template doesn't keep the other tasks from being
+	// created.
+	cfg.Recurring = append([]config.RecurringTask{{Template: "unknown", Schedule: "daily"}}, cfg.Recurring...)
+	created, _, err = templates.Generate(fresh, cfg, history, now.AddDate(0, 0, 14))
+	require.Error(t, err, "unknown templates should be reported")
+	require.Len(t, created, 1)
+}
+
+func TestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clocked")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, templates.Filename)
+
+	history, err := templates.LoadHistory(path)
+	require.NoError(t, err)
+	require.Empty(t, history)
+	history["meeting/weekly"] = "2018-01-01"
+	require.NoError(t, history.Save(path))
+	loaded, err := templates.LoadHistory(path)
+	require.NoError(t, err)
+	require.Equal(t, history, loaded)
+}
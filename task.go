@@ -4,13 +4,44 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/satori/go.uuid"
 )
 
 type Task struct {
-	Code     string    `yaml:"code"`
-	Title    string    `yaml:"title"`
-	Tags     []string  `yaml:"tags"`
-	Bookings []Booking `yaml:"bookings"`
+	// ID identifies the task across stores even if its code changes.
+	ID       string    `yaml:"id,omitempty" json:"id,omitempty"`
+	Code     string    `yaml:"code" json:"code"`
+	Title    string    `yaml:"title" json:"title"`
+	Tags     []string  `yaml:"tags" json:"tags"`
+	Bookings []Booking `yaml:"bookings" json:"bookings"`
+	// Updated is the time the code, title or tags of the task were last
+	// changed.
+	Updated string `yaml:"updated,omitempty" json:"updated,omitempty"`
+}
+
+// Key identifies a task across stores. Tasks created before IDs were
+// introduced are identified by their code.
+func (t *Task) Key() string {
+	if t.ID != "" {
+		return t.ID
+	}
+	return t.Code
+}
+
+// Copy returns a copy of the task that doesn't share its tags and bookings
+// with the original.
+func (t Task) Copy() Task {
+	c := t
+	if t.Tags != nil {
+		c.Tags = make([]string, len(t.Tags))
+		copy(c.Tags, t.Tags)
+	}
+	if t.Bookings != nil {
+		c.Bookings = make([]Booking, len(t.Bookings))
+		copy(c.Bookings, t.Bookings)
+	}
+	return c
 }
 
 func (t *Task) HasTag(tag string) bool {
@@ -23,8 +54,11 @@ func (t *Task) HasTag(tag string) bool {
 }
 
 func (t *Task) Start(tm time.Time) error {
-	b := Booking{}
+	b := Booking{
+		ID: uuid.NewV4().String(),
+	}
 	b.SetStart(tm)
+	b.Touch(tm)
 	t.Bookings = append(t.Bookings, b)
 	return nil
 }
@@ -32,16 +66,74 @@ func (t *Task) Start(tm time.Time) error {
 func (t *Task) Stop(tm time.Time) error {
 	b := &t.Bookings[len(t.Bookings)-1]
 	b.SetStop(tm)
+	b.Touch(tm)
 	return nil
 }
 
+// Touch marks the task as updated at the given time.
+func (t *Task) Touch(tm time.Time) {
+	t.Updated = tm.Format(time.RFC3339)
+}
+
+// UpdatedTime returns the time of the last change. Tasks that have never
+// been changed since these changes are tracked return the zero time.
+func (t *Task) UpdatedTime() time.Time {
+	tm, _ := time.Parse(time.RFC3339, t.Updated)
+	return tm
+}
+
+// LastUsed returns the start of the most recent booking of this task.
+func (t *Task) LastUsed() *time.Time {
+	var last *time.Time
+	for idx := range t.Bookings {
+		start := t.Bookings[idx].StartTime()
+		if start == nil {
+			continue
+		}
+		if last == nil || start.After(*last) {
+			last = start
+		}
+	}
+	return last
+}
+
 func (t Task) Label() string {
 	return fmt.Sprintf("%s %s", t.Code, t.Title)
 }
 
 type Booking struct {
-	Start string `yaml:"start"`
-	Stop  string `yaml:"stop"`
+	ID      string `yaml:"id,omitempty" json:"id,omitempty"`
+	Start   string `yaml:"start" json:"start"`
+	Stop    string `yaml:"stop" json:"stop"`
+	Updated string `yaml:"updated,omitempty" json:"updated,omitempty"`
+	// Repository is the name of the git repository the booking has been
+	// worked on in, if it has been started by `clocked git-switch`.
+	Repository string `yaml:"repository,omitempty" json:"repository,omitempty"`
+}
+
+// Key identifies a booking across stores. Bookings created before IDs were
+// introduced are identified by their task code and start time.
+func (b *Booking) Key(code string) string {
+	if b.ID != "" {
+		return b.ID
+	}
+	return fmt.Sprintf("%s@%s", code, b.Start)
+}
+
+// Touch marks the booking as updated at the given time.
+func (b *Booking) Touch(tm time.Time) {
+	b.Updated = tm.Format(time.RFC3339)
+}
+
+// UpdatedTime returns the time of the last change of this booking. If that
+// is unknown, the stop or start time is used instead.
+func (b *Booking) UpdatedTime() time.Time {
+	for _, s := range []string{b.Updated, b.Stop, b.Start} {
+		if tm, err := time.Parse(time.RFC3339, s); err == nil {
+			return tm
+		}
+	}
+	return time.Time{}
 }
 
 func (b *Booking) SetStart(t time.Time) {
@@ -81,3 +173,38 @@ func (l ByCode) Less(i int, j int) bool {
 func (l ByCode) Swap(i int, j int) {
 	l[i], l[j] = l[j], l[i]
 }
+
+type ByTitle []Task
+
+func (l ByTitle) Len() int {
+	return len(l)
+}
+
+func (l ByTitle) Less(i int, j int) bool {
+	return strings.Compare(strings.ToLower(l[i].Title), strings.ToLower(l[j].Title)) < 0
+}
+
+func (l ByTitle) Swap(i int, j int) {
+	l[i], l[j] = l[j], l[i]
+}
+
+// ByLastUsed sorts the most recently used tasks first. Tasks that have never
+// been used end up last.
+type ByLastUsed []Task
+
+func (l ByLastUsed) Len() int {
+	return len(l)
+}
+
+func (l ByLastUsed) Less(i int, j int) bool {
+	a := l[i].LastUsed()
+	b := l[j].LastUsed()
+	if a == nil || b == nil {
+		return a != nil && b == nil
+	}
+	return a.After(*b)
+}
+
+func (l ByLastUsed) Swap(i int, j int) {
+	l[i], l[j] = l[j], l[i]
+}